@@ -0,0 +1,592 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: crawl.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartCrawlRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is generated if empty
+	Id       string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Seeds    []string `protobuf:"bytes,2,rep,name=seeds,proto3" json:"seeds,omitempty"`
+	Sitemaps []string `protobuf:"bytes,3,rep,name=sitemaps,proto3" json:"sitemaps,omitempty"`
+	Workers  int32    `protobuf:"varint,4,opt,name=workers,proto3" json:"workers,omitempty"`
+	// obey_robots defaults to the service's -obeyRobots flag
+	ObeyRobots    *bool `protobuf:"varint,5,opt,name=obey_robots,json=obeyRobots,proto3,oneof" json:"obey_robots,omitempty"`
+	Favicons      bool  `protobuf:"varint,6,opt,name=favicons,proto3" json:"favicons,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCrawlRequest) Reset() {
+	*x = StartCrawlRequest{}
+	mi := &file_crawl_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCrawlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCrawlRequest) ProtoMessage() {}
+
+func (x *StartCrawlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCrawlRequest.ProtoReflect.Descriptor instead.
+func (*StartCrawlRequest) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartCrawlRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *StartCrawlRequest) GetSeeds() []string {
+	if x != nil {
+		return x.Seeds
+	}
+	return nil
+}
+
+func (x *StartCrawlRequest) GetSitemaps() []string {
+	if x != nil {
+		return x.Sitemaps
+	}
+	return nil
+}
+
+func (x *StartCrawlRequest) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *StartCrawlRequest) GetObeyRobots() bool {
+	if x != nil && x.ObeyRobots != nil {
+		return *x.ObeyRobots
+	}
+	return false
+}
+
+func (x *StartCrawlRequest) GetFavicons() bool {
+	if x != nil {
+		return x.Favicons
+	}
+	return false
+}
+
+type StartCrawlResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCrawlResponse) Reset() {
+	*x = StartCrawlResponse{}
+	mi := &file_crawl_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCrawlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCrawlResponse) ProtoMessage() {}
+
+func (x *StartCrawlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCrawlResponse.ProtoReflect.Descriptor instead.
+func (*StartCrawlResponse) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StartCrawlResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_crawl_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CrawlStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// one of running, cancelling, finished or stored
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Started       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started,proto3" json:"started,omitempty"`
+	Finished      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=finished,proto3" json:"finished,omitempty"`
+	Queued        int64                  `protobuf:"varint,5,opt,name=queued,proto3" json:"queued,omitempty"`
+	Visited       int64                  `protobuf:"varint,6,opt,name=visited,proto3" json:"visited,omitempty"`
+	Images        int64                  `protobuf:"varint,7,opt,name=images,proto3" json:"images,omitempty"`
+	ActiveWorkers int64                  `protobuf:"varint,8,opt,name=active_workers,json=activeWorkers,proto3" json:"active_workers,omitempty"`
+	Paused        bool                   `protobuf:"varint,9,opt,name=paused,proto3" json:"paused,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrawlStatus) Reset() {
+	*x = CrawlStatus{}
+	mi := &file_crawl_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrawlStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrawlStatus) ProtoMessage() {}
+
+func (x *CrawlStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrawlStatus.ProtoReflect.Descriptor instead.
+func (*CrawlStatus) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CrawlStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CrawlStatus) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *CrawlStatus) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+func (x *CrawlStatus) GetFinished() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Finished
+	}
+	return nil
+}
+
+func (x *CrawlStatus) GetQueued() int64 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetVisited() int64 {
+	if x != nil {
+		return x.Visited
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetImages() int64 {
+	if x != nil {
+		return x.Images
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetActiveWorkers() int64 {
+	if x != nil {
+		return x.ActiveWorkers
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+type StreamImagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Follow        bool                   `protobuf:"varint,2,opt,name=follow,proto3" json:"follow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamImagesRequest) Reset() {
+	*x = StreamImagesRequest{}
+	mi := &file_crawl_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamImagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamImagesRequest) ProtoMessage() {}
+
+func (x *StreamImagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamImagesRequest.ProtoReflect.Descriptor instead.
+func (*StreamImagesRequest) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamImagesRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *StreamImagesRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_crawl_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Image) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type CancelCrawlRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelCrawlRequest) Reset() {
+	*x = CancelCrawlRequest{}
+	mi := &file_crawl_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelCrawlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCrawlRequest) ProtoMessage() {}
+
+func (x *CancelCrawlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCrawlRequest.ProtoReflect.Descriptor instead.
+func (*CancelCrawlRequest) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CancelCrawlRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CancelCrawlResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelCrawlResponse) Reset() {
+	*x = CancelCrawlResponse{}
+	mi := &file_crawl_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelCrawlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelCrawlResponse) ProtoMessage() {}
+
+func (x *CancelCrawlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawl_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelCrawlResponse.ProtoReflect.Descriptor instead.
+func (*CancelCrawlResponse) Descriptor() ([]byte, []int) {
+	return file_crawl_proto_rawDescGZIP(), []int{7}
+}
+
+var File_crawl_proto protoreflect.FileDescriptor
+
+const file_crawl_proto_rawDesc = "" +
+	"\n" +
+	"\vcrawl.proto\x12\rimgcrawler.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc1\x01\n" +
+	"\x11StartCrawlRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05seeds\x18\x02 \x03(\tR\x05seeds\x12\x1a\n" +
+	"\bsitemaps\x18\x03 \x03(\tR\bsitemaps\x12\x18\n" +
+	"\aworkers\x18\x04 \x01(\x05R\aworkers\x12$\n" +
+	"\vobey_robots\x18\x05 \x01(\bH\x00R\n" +
+	"obeyRobots\x88\x01\x01\x12\x1a\n" +
+	"\bfavicons\x18\x06 \x01(\bR\bfaviconsB\x0e\n" +
+	"\f_obey_robots\"$\n" +
+	"\x12StartCrawlResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\"\n" +
+	"\x10GetStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xaa\x02\n" +
+	"\vCrawlStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x124\n" +
+	"\astarted\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\astarted\x126\n" +
+	"\bfinished\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bfinished\x12\x16\n" +
+	"\x06queued\x18\x05 \x01(\x03R\x06queued\x12\x18\n" +
+	"\avisited\x18\x06 \x01(\x03R\avisited\x12\x16\n" +
+	"\x06images\x18\a \x01(\x03R\x06images\x12%\n" +
+	"\x0eactive_workers\x18\b \x01(\x03R\ractiveWorkers\x12\x16\n" +
+	"\x06paused\x18\t \x01(\bR\x06paused\"=\n" +
+	"\x13StreamImagesRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06follow\x18\x02 \x01(\bR\x06follow\"\x19\n" +
+	"\x05Image\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\"$\n" +
+	"\x12CancelCrawlRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x15\n" +
+	"\x13CancelCrawlResponse2\xcd\x02\n" +
+	"\fCrawlService\x12Q\n" +
+	"\n" +
+	"StartCrawl\x12 .imgcrawler.v1.StartCrawlRequest\x1a!.imgcrawler.v1.StartCrawlResponse\x12H\n" +
+	"\tGetStatus\x12\x1f.imgcrawler.v1.GetStatusRequest\x1a\x1a.imgcrawler.v1.CrawlStatus\x12J\n" +
+	"\fStreamImages\x12\".imgcrawler.v1.StreamImagesRequest\x1a\x14.imgcrawler.v1.Image0\x01\x12T\n" +
+	"\vCancelCrawl\x12!.imgcrawler.v1.CancelCrawlRequest\x1a\".imgcrawler.v1.CancelCrawlResponseB,Z*github.com/daveagill/go-imgcrawler/api;apib\x06proto3"
+
+var (
+	file_crawl_proto_rawDescOnce sync.Once
+	file_crawl_proto_rawDescData []byte
+)
+
+func file_crawl_proto_rawDescGZIP() []byte {
+	file_crawl_proto_rawDescOnce.Do(func() {
+		file_crawl_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_crawl_proto_rawDesc), len(file_crawl_proto_rawDesc)))
+	})
+	return file_crawl_proto_rawDescData
+}
+
+var file_crawl_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_crawl_proto_goTypes = []any{
+	(*StartCrawlRequest)(nil),     // 0: imgcrawler.v1.StartCrawlRequest
+	(*StartCrawlResponse)(nil),    // 1: imgcrawler.v1.StartCrawlResponse
+	(*GetStatusRequest)(nil),      // 2: imgcrawler.v1.GetStatusRequest
+	(*CrawlStatus)(nil),           // 3: imgcrawler.v1.CrawlStatus
+	(*StreamImagesRequest)(nil),   // 4: imgcrawler.v1.StreamImagesRequest
+	(*Image)(nil),                 // 5: imgcrawler.v1.Image
+	(*CancelCrawlRequest)(nil),    // 6: imgcrawler.v1.CancelCrawlRequest
+	(*CancelCrawlResponse)(nil),   // 7: imgcrawler.v1.CancelCrawlResponse
+	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+}
+var file_crawl_proto_depIdxs = []int32{
+	8, // 0: imgcrawler.v1.CrawlStatus.started:type_name -> google.protobuf.Timestamp
+	8, // 1: imgcrawler.v1.CrawlStatus.finished:type_name -> google.protobuf.Timestamp
+	0, // 2: imgcrawler.v1.CrawlService.StartCrawl:input_type -> imgcrawler.v1.StartCrawlRequest
+	2, // 3: imgcrawler.v1.CrawlService.GetStatus:input_type -> imgcrawler.v1.GetStatusRequest
+	4, // 4: imgcrawler.v1.CrawlService.StreamImages:input_type -> imgcrawler.v1.StreamImagesRequest
+	6, // 5: imgcrawler.v1.CrawlService.CancelCrawl:input_type -> imgcrawler.v1.CancelCrawlRequest
+	1, // 6: imgcrawler.v1.CrawlService.StartCrawl:output_type -> imgcrawler.v1.StartCrawlResponse
+	3, // 7: imgcrawler.v1.CrawlService.GetStatus:output_type -> imgcrawler.v1.CrawlStatus
+	5, // 8: imgcrawler.v1.CrawlService.StreamImages:output_type -> imgcrawler.v1.Image
+	7, // 9: imgcrawler.v1.CrawlService.CancelCrawl:output_type -> imgcrawler.v1.CancelCrawlResponse
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_crawl_proto_init() }
+func file_crawl_proto_init() {
+	if File_crawl_proto != nil {
+		return
+	}
+	file_crawl_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_crawl_proto_rawDesc), len(file_crawl_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_crawl_proto_goTypes,
+		DependencyIndexes: file_crawl_proto_depIdxs,
+		MessageInfos:      file_crawl_proto_msgTypes,
+	}.Build()
+	File_crawl_proto = out.File
+	file_crawl_proto_goTypes = nil
+	file_crawl_proto_depIdxs = nil
+}
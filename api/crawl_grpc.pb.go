@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: crawl.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CrawlService_StartCrawl_FullMethodName   = "/imgcrawler.v1.CrawlService/StartCrawl"
+	CrawlService_GetStatus_FullMethodName    = "/imgcrawler.v1.CrawlService/GetStatus"
+	CrawlService_StreamImages_FullMethodName = "/imgcrawler.v1.CrawlService/StreamImages"
+	CrawlService_CancelCrawl_FullMethodName  = "/imgcrawler.v1.CrawlService/CancelCrawl"
+)
+
+// CrawlServiceClient is the client API for CrawlService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CrawlService orchestrates crawl jobs, sharing the job manager used by
+// crawlsvc's REST API
+type CrawlServiceClient interface {
+	// StartCrawl seeds and starts a new job
+	StartCrawl(ctx context.Context, in *StartCrawlRequest, opts ...grpc.CallOption) (*StartCrawlResponse, error)
+	// GetStatus reports a job's progress
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*CrawlStatus, error)
+	// StreamImages streams every image URL found by a job in the order found,
+	// and with follow set keeps streaming newly found images until the job
+	// finishes
+	StreamImages(ctx context.Context, in *StreamImagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Image], error)
+	// CancelCrawl stops a running job
+	CancelCrawl(ctx context.Context, in *CancelCrawlRequest, opts ...grpc.CallOption) (*CancelCrawlResponse, error)
+}
+
+type crawlServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCrawlServiceClient(cc grpc.ClientConnInterface) CrawlServiceClient {
+	return &crawlServiceClient{cc}
+}
+
+func (c *crawlServiceClient) StartCrawl(ctx context.Context, in *StartCrawlRequest, opts ...grpc.CallOption) (*StartCrawlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartCrawlResponse)
+	err := c.cc.Invoke(ctx, CrawlService_StartCrawl_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*CrawlStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CrawlStatus)
+	err := c.cc.Invoke(ctx, CrawlService_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlServiceClient) StreamImages(ctx context.Context, in *StreamImagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Image], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CrawlService_ServiceDesc.Streams[0], CrawlService_StreamImages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamImagesRequest, Image]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrawlService_StreamImagesClient = grpc.ServerStreamingClient[Image]
+
+func (c *crawlServiceClient) CancelCrawl(ctx context.Context, in *CancelCrawlRequest, opts ...grpc.CallOption) (*CancelCrawlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelCrawlResponse)
+	err := c.cc.Invoke(ctx, CrawlService_CancelCrawl_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CrawlServiceServer is the server API for CrawlService service.
+// All implementations must embed UnimplementedCrawlServiceServer
+// for forward compatibility.
+//
+// CrawlService orchestrates crawl jobs, sharing the job manager used by
+// crawlsvc's REST API
+type CrawlServiceServer interface {
+	// StartCrawl seeds and starts a new job
+	StartCrawl(context.Context, *StartCrawlRequest) (*StartCrawlResponse, error)
+	// GetStatus reports a job's progress
+	GetStatus(context.Context, *GetStatusRequest) (*CrawlStatus, error)
+	// StreamImages streams every image URL found by a job in the order found,
+	// and with follow set keeps streaming newly found images until the job
+	// finishes
+	StreamImages(*StreamImagesRequest, grpc.ServerStreamingServer[Image]) error
+	// CancelCrawl stops a running job
+	CancelCrawl(context.Context, *CancelCrawlRequest) (*CancelCrawlResponse, error)
+	mustEmbedUnimplementedCrawlServiceServer()
+}
+
+// UnimplementedCrawlServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCrawlServiceServer struct{}
+
+func (UnimplementedCrawlServiceServer) StartCrawl(context.Context, *StartCrawlRequest) (*StartCrawlResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StartCrawl not implemented")
+}
+func (UnimplementedCrawlServiceServer) GetStatus(context.Context, *GetStatusRequest) (*CrawlStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedCrawlServiceServer) StreamImages(*StreamImagesRequest, grpc.ServerStreamingServer[Image]) error {
+	return status.Error(codes.Unimplemented, "method StreamImages not implemented")
+}
+func (UnimplementedCrawlServiceServer) CancelCrawl(context.Context, *CancelCrawlRequest) (*CancelCrawlResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CancelCrawl not implemented")
+}
+func (UnimplementedCrawlServiceServer) mustEmbedUnimplementedCrawlServiceServer() {}
+func (UnimplementedCrawlServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeCrawlServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CrawlServiceServer will
+// result in compilation errors.
+type UnsafeCrawlServiceServer interface {
+	mustEmbedUnimplementedCrawlServiceServer()
+}
+
+func RegisterCrawlServiceServer(s grpc.ServiceRegistrar, srv CrawlServiceServer) {
+	// If the following call panics, it indicates UnimplementedCrawlServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CrawlService_ServiceDesc, srv)
+}
+
+func _CrawlService_StartCrawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartCrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlServiceServer).StartCrawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrawlService_StartCrawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlServiceServer).StartCrawl(ctx, req.(*StartCrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrawlService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrawlService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrawlService_StreamImages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamImagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrawlServiceServer).StreamImages(m, &grpc.GenericServerStream[StreamImagesRequest, Image]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrawlService_StreamImagesServer = grpc.ServerStreamingServer[Image]
+
+func _CrawlService_CancelCrawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelCrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlServiceServer).CancelCrawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrawlService_CancelCrawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlServiceServer).CancelCrawl(ctx, req.(*CancelCrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CrawlService_ServiceDesc is the grpc.ServiceDesc for CrawlService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CrawlService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "imgcrawler.v1.CrawlService",
+	HandlerType: (*CrawlServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartCrawl",
+			Handler:    _CrawlService_StartCrawl_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _CrawlService_GetStatus_Handler,
+		},
+		{
+			MethodName: "CancelCrawl",
+			Handler:    _CrawlService_CancelCrawl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamImages",
+			Handler:       _CrawlService_StreamImages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawl.proto",
+}
@@ -0,0 +1,5 @@
+// Package api is the gRPC service definition for orchestrating crawls, the
+// Go code is generated from crawl.proto
+package api
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative crawl.proto
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// maxPageSize caps the count a client may ask for when paging results
+const maxPageSize = 10000
+
+// maxSpecBytes caps the size of the job specs posted
+const maxSpecBytes = 1 << 20
+
+// apiServer exposes the job manager over HTTP:
+//
+//	POST   /crawls              start a job from a jobSpec
+//	GET    /crawls              every job, stored or running
+//	GET    /crawls/{id}         job status and progress
+//	GET    /crawls/{id}/images  paginated image URLs, ?cursor=&count=
+//	GET    /crawls/{id}/failed  the pages that failed, ?class=4xx, 5xx or error
+//	DELETE /crawls/{id}         cancel a running job
+type apiServer struct {
+	jobs   *jobManager
+	logger *slog.Logger
+}
+
+func (api *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /crawls", api.startCrawl)
+	mux.HandleFunc("GET /crawls", api.listCrawls)
+	mux.HandleFunc("GET /crawls/{id}", api.getCrawl)
+	mux.HandleFunc("GET /crawls/{id}/images", api.getImages)
+	mux.HandleFunc("GET /crawls/{id}/failed", api.getFailed)
+	mux.HandleFunc("DELETE /crawls/{id}", api.cancelCrawl)
+	return mux
+}
+
+func (api *apiServer) startCrawl(w http.ResponseWriter, r *http.Request) {
+	spec := jobSpec{}
+	body := http.MaxBytesReader(w, r.Body, maxSpecBytes)
+	if err := json.NewDecoder(body).Decode(&spec); err != nil {
+		tooLarge := &http.MaxBytesError{}
+		if errors.As(err, &tooLarge) {
+			api.error(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		api.error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := api.jobs.start(spec)
+	if errors.Is(err, errJobRunning) || errors.Is(err, errJobExists) {
+		api.error(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		api.error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Location", "/crawls/"+id)
+	api.respond(w, http.StatusCreated, map[string]string{"id": id})
+}
+
+func (api *apiServer) listCrawls(w http.ResponseWriter, r *http.Request) {
+	jobs, err := api.jobs.list()
+	if err != nil {
+		api.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	api.respond(w, http.StatusOK, struct {
+		Jobs []jobListing `json:"jobs"`
+	}{jobs})
+}
+
+func (api *apiServer) getCrawl(w http.ResponseWriter, r *http.Request) {
+	status, err := api.jobs.status(r.PathValue("id"))
+	if err != nil {
+		api.jobError(w, err)
+		return
+	}
+
+	api.respond(w, http.StatusOK, status)
+}
+
+func (api *apiServer) getImages(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := api.jobs.status(id); err != nil {
+		api.jobError(w, err)
+		return
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		cursor = "0"
+	}
+	count := crawler.DefaultScanCount
+	if s := r.URL.Query().Get("count"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			api.error(w, http.StatusBadRequest, errors.New("count must be between 1 and 10000"))
+			return
+		}
+		count = n
+	}
+
+	images, next, err := crawler.NewJob(api.jobs.pool, id).ScanImages(cursor, count)
+	if err != nil {
+		api.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	if images == nil {
+		images = []string{}
+	}
+
+	// a next cursor of "0" means the last page has been reached
+	resp := struct {
+		Images []string `json:"images"`
+		Next   string   `json:"next,omitempty"`
+	}{Images: images}
+	if next != "0" {
+		resp.Next = next
+	}
+	api.respond(w, http.StatusOK, resp)
+}
+
+func (api *apiServer) getFailed(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := api.jobs.status(id); err != nil {
+		api.jobError(w, err)
+		return
+	}
+
+	class := r.URL.Query().Get("class")
+	switch class {
+	case "", crawler.FailedClientError, crawler.FailedServerError, crawler.FailedFetch:
+	default:
+		api.error(w, http.StatusBadRequest, errors.New("class must be 4xx, 5xx or error"))
+		return
+	}
+
+	failed, err := crawler.NewJob(api.jobs.pool, id).FailedPages(class)
+	if err != nil {
+		api.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	api.respond(w, http.StatusOK, struct {
+		Failed []crawler.FailedPage `json:"failed"`
+	}{failed})
+}
+
+func (api *apiServer) cancelCrawl(w http.ResponseWriter, r *http.Request) {
+	if err := api.jobs.cancel(r.PathValue("id")); err != nil {
+		api.jobError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (api *apiServer) jobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnknownJob) {
+		api.error(w, http.StatusNotFound, err)
+		return
+	}
+	api.error(w, http.StatusInternalServerError, err)
+}
+
+func (api *apiServer) error(w http.ResponseWriter, code int, err error) {
+	if code >= 500 {
+		api.logger.Error("api request failed", "err", err)
+	}
+	api.respond(w, code, map[string]string{"error": err.Error()})
+}
+
+func (api *apiServer) respond(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// serveAPI runs the HTTP API until ctx is cancelled
+func serveAPI(ctx context.Context, addr string, jobs *jobManager, logger *slog.Logger) error {
+	api := &apiServer{jobs: jobs, logger: logger}
+	srv := &http.Server{Addr: addr, Handler: api.handler()}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	logger.Info("serving crawl API", "addr", addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// newTestJobs creates a job manager backed by miniredis
+func newTestJobs(t *testing.T) *jobManager {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	pool := crawler.NewPool("tcp", mr.Addr())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := newJobManager(ctx, pool, logger, func(c *crawler.Crawler) {
+		c.TerminationGrace = 10 * time.Millisecond
+	})
+	t.Cleanup(func() {
+		cancel()
+		jobs.wait()
+	})
+	return jobs
+}
+
+// newTestAPI serves the API over a job manager backed by miniredis
+func newTestAPI(t *testing.T) (*httptest.Server, *jobManager) {
+	t.Helper()
+	jobs := newTestJobs(t)
+	srv := httptest.NewServer((&apiServer{jobs: jobs, logger: jobs.logger}).handler())
+	t.Cleanup(srv.Close)
+	return srv, jobs
+}
+
+// call makes an API request, decoding the JSON response into v if given
+func call(t *testing.T, method string, url string, body string, v interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitForState polls a job until it's in the given state
+func waitForState(t *testing.T, srv *httptest.Server, id string, state string) jobStatus {
+	t.Helper()
+	status := jobStatus{}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		call(t, "GET", srv.URL+"/crawls/"+id, "", &status)
+		if status.State == state {
+			return status
+		}
+	}
+	t.Fatalf("job %s is %s, want %s", id, status.State, state)
+	return status
+}
+
+func TestAPIStartAndInspect(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png"><img src="/b.png">`))
+	}))
+	defer site.Close()
+	srv, _ := newTestAPI(t)
+
+	started := map[string]string{}
+	if code := call(t, "POST", srv.URL+"/crawls", `{"id": "site", "seeds": ["`+site.URL+`"]}`, &started); code != http.StatusCreated || started["id"] != "site" {
+		t.Fatalf("POST /crawls = %d %v, want 201 and the job's ID", code, started)
+	}
+
+	status := waitForState(t, srv, "site", jobFinished)
+	if status.Visited != 1 || status.Images != 2 || status.Started == nil || status.Finished == nil {
+		t.Errorf("status = %+v, want 1 page and 2 images visited, with its times", status)
+	}
+
+	// it's been run, so isn't again
+	if code := call(t, "POST", srv.URL+"/crawls", `{"id": "site", "seeds": ["`+site.URL+`"]}`, nil); code != http.StatusConflict {
+		t.Errorf("POST /crawls again = %d, want 409", code)
+	}
+
+	// a generated ID is returned
+	if code := call(t, "POST", srv.URL+"/crawls", `{"seeds": ["`+site.URL+`"]}`, &started); code != http.StatusCreated || started["id"] == "site" || crawler.ValidateJobID(started["id"]) != nil {
+		t.Errorf("POST /crawls without an ID = %d %v, want a new ID", code, started)
+	}
+}
+
+func TestAPIStartErrors(t *testing.T) {
+	srv, _ := newTestAPI(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"malformed", `{"seeds": [`, http.StatusBadRequest},
+		{"no seeds", `{"id": "a"}`, http.StatusBadRequest},
+		{"bad ID", `{"id": "a b", "seeds": ["https://example.com"]}`, http.StatusBadRequest},
+		{"too large", `{"seeds": ["https://example.com/` + strings.Repeat("a", maxSpecBytes) + `"]}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if code := call(t, "POST", srv.URL+"/crawls", tt.body, nil); code != tt.want {
+			t.Errorf("%s: POST /crawls = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	if code := call(t, "GET", srv.URL+"/crawls/nope", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET unknown job = %d, want 404", code)
+	}
+}
+
+func TestAPIListsStoredJobsWithoutRunningThem(t *testing.T) {
+	srv, jobs := newTestAPI(t)
+
+	// a job left by an earlier run
+	stored := crawler.NewJob(jobs.pool, "earlier")
+	stored.Seed("https://example.com/")
+
+	list := struct{ Jobs []jobListing }{}
+	if code := call(t, "GET", srv.URL+"/crawls", "", &list); code != http.StatusOK {
+		t.Fatalf("GET /crawls = %d", code)
+	}
+	if len(list.Jobs) != 1 || list.Jobs[0].ID != "earlier" || list.Jobs[0].State != jobStored {
+		t.Errorf("jobs = %+v, want the stored job", list.Jobs)
+	}
+
+	if code := call(t, "POST", srv.URL+"/crawls", `{"id": "earlier", "seeds": ["https://example.com/"]}`, nil); code != http.StatusConflict {
+		t.Errorf("POST /crawls for a stored job = %d, want 409", code)
+	}
+	status := jobStatus{}
+	if call(t, "GET", srv.URL+"/crawls/earlier", "", &status); status.State != jobStored || status.Queued != 1 || status.Visited != 0 {
+		t.Errorf("stored job = %+v, want it left queued", status)
+	}
+	if running := jobs.running(); len(running) != 0 {
+		t.Errorf("running %d jobs, want none", len(running))
+	}
+}
+
+func TestAPIDelete(t *testing.T) {
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/next">next</a>`))
+	}))
+	defer site.Close()
+	srv, jobs := newTestAPI(t)
+
+	call(t, "POST", srv.URL+"/crawls", `{"id": "slow", "seeds": ["`+site.URL+`"]}`, nil)
+	waitForState(t, srv, "slow", jobRunning)
+
+	if code := call(t, "DELETE", srv.URL+"/crawls/slow", "", nil); code != http.StatusAccepted {
+		t.Errorf("DELETE /crawls/slow = %d, want 202", code)
+	}
+	waitForState(t, srv, "slow", jobCancelling)
+	close(release)
+	waitForState(t, srv, "slow", jobFinished)
+
+	if code := call(t, "DELETE", srv.URL+"/crawls/slow", "", nil); code != http.StatusNotFound {
+		t.Errorf("DELETE a finished job = %d, want 404", code)
+	}
+	if code := call(t, "DELETE", srv.URL+"/crawls/nope", "", nil); code != http.StatusNotFound {
+		t.Errorf("DELETE an unknown job = %d, want 404", code)
+	}
+
+	// the page in hand was finished, its link isn't followed
+	conn := jobs.pool.Get()
+	defer conn.Close()
+	if n, _ := redis.Int(conn.Do("ZCARD", crawler.NewJob(jobs.pool, "slow").KeyCrawlQ)); n != 1 {
+		t.Errorf("queue holds %d pages, want the link left queued", n)
+	}
+}
+
+func TestJobManagerForgetsFinishedJobs(t *testing.T) {
+	_, jobs := newTestAPI(t)
+	job := &runningJob{state: jobFinished}
+	jobs.jobs["done"] = job
+
+	jobs.forget("done", &runningJob{})
+	if _, ok := jobs.local("done"); !ok {
+		t.Error("forgot a job replaced since")
+	}
+	jobs.forget("done", job)
+	if _, ok := jobs.local("done"); ok {
+		t.Error("finished job still held")
+	}
+}
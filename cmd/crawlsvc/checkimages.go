@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// checkImageFormats are the writers for each supported check-images
+// -format, each called once per image checked
+var checkImageFormats = map[string]func(w io.Writer) (write func(crawler.ImageCheck) error, flush func() error){
+	"text":   checkImagesText,
+	"csv":    checkImagesCSV,
+	"ndjson": checkImagesNDJSON,
+}
+
+// checkImagesCmd requests every image a crawl found, reporting those that
+// are broken: answering other than 2xx, or with something other than an
+// image. It exits 1 if any are.
+func checkImagesCmd(args []string) {
+	var (
+		concurrency int
+		all         bool
+		format      string
+		output      string
+	)
+
+	fs := flag.NewFlagSet("crawlsvc check-images", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.IntVar(&concurrency, "concurrency", 8, "How many images to check at once")
+	fs.BoolVar(&all, "all", false, "Report every image checked, not just the broken ones")
+	fs.StringVar(&format, "format", "text", "The report format: text, csv or ndjson")
+	fs.StringVar(&output, "output", "-", "Where to write the report, - for stdout")
+	fs.Parse(args)
+
+	newWriter, ok := checkImageFormats[format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid -format %q\n", format)
+		os.Exit(2)
+	}
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	c := redisOpts.crawler(pool)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	checked, broken := 0, 0
+	err := writeOutput(output, func(w io.Writer) error {
+		write, flush := newWriter(w)
+		var writeErr error
+		err := c.CheckImages(ctx, concurrency, func(ic crawler.ImageCheck) {
+			checked++
+			if ic.Broken() {
+				broken++
+			}
+			if (all || ic.Broken()) && writeErr == nil {
+				writeErr = write(ic)
+			}
+		})
+		if writeErr != nil {
+			return writeErr
+		}
+		if err != nil {
+			return err
+		}
+		return flush()
+	})
+	exitOnError(err)
+
+	fmt.Fprintf(os.Stderr, "%d of %d images broken\n", broken, checked)
+	if broken > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkImagesText writes each image's problem, or status, URL and the
+// pages it's on, one per line
+func checkImagesText(w io.Writer) (func(crawler.ImageCheck) error, func() error) {
+	write := func(ic crawler.ImageCheck) error {
+		result := ic.Problem
+		if !ic.Broken() {
+			result = "OK"
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\n", ic.URL, result)
+		for _, page := range ic.Pages {
+			if err == nil {
+				_, err = fmt.Fprintln(w, "  on", page)
+			}
+		}
+		return err
+	}
+	return write, func() error { return nil }
+}
+
+// checkImagesCSV writes a header row followed by one row per image, its
+// pages separated by spaces
+func checkImagesCSV(w io.Writer) (func(crawler.ImageCheck) error, func() error) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "status", "contentType", "problem", "pages"})
+	write := func(ic crawler.ImageCheck) error {
+		return cw.Write([]string{ic.URL, optionalInt(int64(ic.Status)), ic.ContentType, ic.Problem, strings.Join(ic.Pages, " ")})
+	}
+	flush := func() error {
+		cw.Flush()
+		return cw.Error()
+	}
+	return write, flush
+}
+
+// checkImagesNDJSON writes one JSON image check per line
+func checkImagesNDJSON(w io.Writer) (func(crawler.ImageCheck) error, func() error) {
+	enc := json.NewEncoder(w)
+	write := func(ic crawler.ImageCheck) error {
+		return enc.Encode(ic)
+	}
+	return write, func() error { return nil }
+}
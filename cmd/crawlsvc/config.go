@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configSkipped are the flags that make no sense in a config file
+var configSkipped = map[string]bool{"config": true, "writeConfig": true}
+
+// configSecrets are the flags holding credentials, which config files may
+// set but writeConfig never fills in
+var configSecrets = map[string]bool{"redisPassword": true, "basicAuth": true, "bearerToken": true}
+
+// repeatedFlag is a flag that may be repeated, given as a list in a config
+// file
+type repeatedFlag interface {
+	values() []string
+}
+
+func (h headerFlag) values() []string {
+	var vs []string
+	for name, values := range h {
+		for _, v := range values {
+			vs = append(vs, name+": "+v)
+		}
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func (h hostHeaderFlag) values() []string {
+	var vs []string
+	for host, header := range h {
+		for _, v := range headerFlag(header).values() {
+			vs = append(vs, host+"="+v)
+		}
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func (u *urlListFlag) values() []string {
+	return *u
+}
+
+func (q *queryRuleFlag) values() []string {
+	var vs []string
+	for _, rule := range *q {
+		vs = append(vs, rule.String())
+	}
+	return vs
+}
+
+func (w *windowFlag) values() []string {
+	var vs []string
+	for _, window := range *w {
+		vs = append(vs, window.String())
+	}
+	return vs
+}
+
+// isTOML reports whether a config file is TOML, rather than YAML, by its
+// extension
+func isTOML(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".toml")
+}
+
+// applyConfig sets the flags named in a YAML or TOML config file, all but
+// those already set on the command line, which override it
+func applyConfig(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	options := map[string]any{}
+	if isTOML(path) {
+		err = toml.Unmarshal(data, &options)
+	} else {
+		err = yaml.Unmarshal(data, &options)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || configSkipped[name] {
+			return fmt.Errorf("%s: unknown option %q", path, name)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := setFromConfig(fs, f, options[name]); err != nil {
+			return fmt.Errorf("%s: option %q: %w", path, name, err)
+		}
+	}
+	return nil
+}
+
+// setFromConfig sets a flag to a config file value, setting a repeated flag
+// once per item of a list, and joining a list with commas otherwise
+func setFromConfig(fs *flag.FlagSet, f *flag.Flag, value any) error {
+	switch v := value.(type) {
+	case string, bool, int, int64, uint64, float64:
+		if err := fs.Set(f.Name, fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("invalid value %v: %w", v, err)
+		}
+		return nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case string, bool, int, int64, uint64, float64:
+				items[i] = fmt.Sprint(item)
+			default:
+				return fmt.Errorf("want a list of values, not %T", item)
+			}
+		}
+		if _, ok := f.Value.(repeatedFlag); !ok {
+			return fs.Set(f.Name, strings.Join(items, ","))
+		}
+		for _, item := range items {
+			if err := fs.Set(f.Name, item); err != nil {
+				return fmt.Errorf("invalid value %q: %w", item, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("want a value or a list, not %T", value)
+	}
+}
+
+// writeConfig writes every flag as a commented config file, YAML or TOML by
+// its extension, with the flags that are set filled in and the rest
+// commented out at their defaults. Credentials are always left commented
+// out and empty, and the file is only readable by its owner regardless.
+func writeConfig(fs *flag.FlagSet, path string) error {
+	separator := ": "
+	if isTOML(path) {
+		separator = " = "
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	b := bytes.Buffer{}
+	fmt.Fprintf(&b, "# crawlsvc config, use with: crawlsvc -config %s\n", filepath.Base(path))
+	b.WriteString("# Flags given on the command line override the options set here.\n")
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if configSkipped[f.Name] {
+			return
+		}
+
+		b.WriteString("\n")
+		for _, line := range wrapWords(f.Usage, 76) {
+			b.WriteString("# " + line + "\n")
+		}
+
+		value := configValue(f, explicit[f.Name])
+		if configSecrets[f.Name] {
+			value = `""`
+		}
+		if !explicit[f.Name] || configSecrets[f.Name] {
+			b.WriteString("# ")
+		}
+		b.WriteString(f.Name + separator + value + "\n")
+	})
+
+	return os.WriteFile(path, b.Bytes(), 0o600)
+}
+
+// configValue formats a flag's value, or its default if it isn't set, as a
+// value valid in both YAML and TOML
+func configValue(f *flag.Flag, set bool) string {
+	if r, ok := f.Value.(repeatedFlag); ok {
+		quoted := []string{}
+		if set {
+			for _, v := range r.values() {
+				quoted = append(quoted, strconv.Quote(v))
+			}
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return strconv.Quote(f.Value.String())
+	}
+	switch v := getter.Get().(type) {
+	case string:
+		return strconv.Quote(v)
+	case time.Duration:
+		return strconv.Quote(v.String())
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// wrapWords wraps text into lines of at most width characters, breaking
+// only between words
+func wrapWords(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// initCmd writes a commented config file listing every crawl option
+func initCmd(args []string) {
+	var force bool
+
+	fs := flag.NewFlagSet("crawlsvc init", flag.ExitOnError)
+	fs.BoolVar(&force, "force", false, "Overwrite the config file if it already exists")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crawlsvc init [-force] [crawl.yaml|crawl.toml]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	path := "crawl.yaml"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	if _, err := os.Stat(path); err == nil && !force {
+		exitOnError(fmt.Errorf("%s already exists, use -force to overwrite it", path))
+	}
+
+	exitOnError(crawlCmd([]string{"-writeConfig", path}))
+	fmt.Println("Wrote", path)
+}
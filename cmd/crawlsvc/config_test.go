@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configFlags is a flag set with a flag of each kind config files set
+func configFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.String("name", "", "")
+	fs.String("hosts", "", "")
+	fs.Bool("verbose", false, "")
+	fs.Int("workers", 1, "")
+	fs.Int64("maxBytes", 0, "")
+	fs.Float64("rate", 0, "")
+	fs.Duration("timeout", time.Minute, "")
+	fs.Var(headerFlag{}, "header", "")
+	fs.Var(hostHeaderFlag{}, "hostHeader", "")
+	fs.Var(&urlListFlag{}, "url", "")
+	fs.Var(&windowFlag{}, "window", "")
+	fs.Bool("config", false, "")
+	fs.String("redisPassword", "", "")
+	fs.String("bearerToken", "", "")
+	return fs
+}
+
+// writeTestConfig writes a config file named name in a temporary directory
+func writeTestConfig(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApplyConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		config string
+		flag   string
+		want   string
+	}{
+		{"string", "c.yaml", `name: example`, "name", `"example"`},
+		{"bool", "c.yaml", `verbose: true`, "verbose", "true"},
+		{"int", "c.yaml", `workers: 8`, "workers", "8"},
+		{"int64", "c.toml", `maxBytes = 10485760`, "maxBytes", "10485760"},
+		{"float", "c.yaml", `rate: 2.5`, "rate", "2.5"},
+		{"duration", "c.yaml", `timeout: 90s`, "timeout", `"1m30s"`},
+		{"duration in TOML", "c.toml", `timeout = "2h"`, "timeout", `"2h0m0s"`},
+		{"list joined with commas", "c.yaml", "hosts:\n  - a.example\n  - b.example", "hosts", `"a.example,b.example"`},
+		{"repeated", "c.yaml", "url:\n  - https://a.example/\n  - https://b.example/", "url", `["https://a.example/", "https://b.example/"]`},
+		{"repeated once", "c.yaml", `url: https://a.example/`, "url", `["https://a.example/"]`},
+		{"repeated in TOML", "c.toml", `window = ["01:00-05:00", "22:00-23:30"]`, "window", `["01:00-05:00", "22:00-23:30"]`},
+		{"header map", "c.yaml", "header:\n  - 'X-B: 2'\n  - 'X-A: 1'", "header", `["X-A: 1", "X-B: 2"]`},
+		{"host header map", "c.yaml", "hostHeader:\n  - 'b.example=X-Key: 2'\n  - 'a.example=X-Key: 1'", "hostHeader", `["a.example=X-Key: 1", "b.example=X-Key: 2"]`},
+	}
+	for _, tt := range tests {
+		fs := configFlags()
+		fs.Parse(nil)
+		if err := applyConfig(fs, writeTestConfig(t, tt.file, tt.config)); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := configValue(fs.Lookup(tt.flag), true); got != tt.want {
+			t.Errorf("%s: %s = %s, want %s", tt.name, tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestApplyConfigCommandLineOverrides(t *testing.T) {
+	fs := configFlags()
+	fs.Parse([]string{"-workers", "2"})
+	if err := applyConfig(fs, writeTestConfig(t, "c.yaml", "workers: 8\nname: example")); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.Lookup("workers").Value.String(); got != "2" {
+		t.Errorf("workers = %s, want 2 from the command line", got)
+	}
+	if got := fs.Lookup("name").Value.String(); got != "example" {
+		t.Errorf("name = %s, want example from the config", got)
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		config string
+		want   string
+	}{
+		{"malformed duration", "c.yaml", `timeout: soon`, `option "timeout": invalid value soon`},
+		{"malformed int", "c.toml", `workers = "many"`, `option "workers": invalid value many`},
+		{"malformed repeated value", "c.yaml", "window:\n  - 01:00-05:00\n  - noon", `option "window": invalid value "noon"`},
+		{"malformed header", "c.yaml", "header:\n  - no colon", `option "header": invalid value "no colon"`},
+		{"map value", "c.yaml", "name:\n  first: a", `want a value or a list, not map[string]interface {}`},
+		{"list of maps", "c.yaml", "url:\n  - a: b", `want a list of values, not map[string]interface {}`},
+		{"unknown option", "c.yaml", `nope: 1`, `unknown option "nope"`},
+		{"skipped option", "c.yaml", `config: true`, `unknown option "config"`},
+		{"malformed YAML", "c.yaml", `workers: [8`, "c.yaml"},
+		{"malformed TOML", "c.toml", `workers = `, "c.toml"},
+	}
+	for _, tt := range tests {
+		fs := configFlags()
+		fs.Parse(nil)
+		err := applyConfig(fs, writeTestConfig(t, tt.file, tt.config))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: applyConfig = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestWriteConfigRoundTrips(t *testing.T) {
+	for _, file := range []string{"c.yaml", "c.toml"} {
+		fs := configFlags()
+		fs.Parse([]string{"-name", "example", "-timeout", "90s", "-url", "https://a.example/", "-url", "https://b.example/", "-header", "X-A: 1"})
+		path := filepath.Join(t.TempDir(), file)
+		if err := writeConfig(fs, path); err != nil {
+			t.Fatal(err)
+		}
+
+		again := configFlags()
+		again.Parse(nil)
+		if err := applyConfig(again, path); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for _, name := range []string{"name", "timeout", "url", "header", "workers"} {
+			if got, want := configValue(again.Lookup(name), true), configValue(fs.Lookup(name), true); got != want {
+				t.Errorf("%s: %s = %s, want %s", file, name, got, want)
+			}
+		}
+	}
+}
+
+func TestWriteConfigLeavesOutSecrets(t *testing.T) {
+	fs := configFlags()
+	fs.Parse([]string{"-redisPassword", "hunter2", "-bearerToken", "t0ken", "-name", "example"})
+	path := filepath.Join(t.TempDir(), "c.yaml")
+	if err := writeConfig(fs, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, secret := range []string{"hunter2", "t0ken"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("config file holds %q", secret)
+		}
+	}
+	if !strings.Contains(string(data), "\n# redisPassword: \"\"\n") {
+		t.Errorf("config file = %s, want redisPassword commented out and empty", data)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("config file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// they can still be set by config files
+	again := configFlags()
+	again.Parse(nil)
+	if err := applyConfig(again, writeTestConfig(t, "c.yaml", `redisPassword: hunter2`)); err != nil || again.Lookup("redisPassword").Value.String() != "hunter2" {
+		t.Errorf("applyConfig(redisPassword) = %v, want it set", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+func TestCrawlFlagsApply(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	seedOpts := addSeedFlags(fs)
+	scopeOpts := addScopeFlags(fs)
+	fetchOpts := addFetchFlags(fs)
+	extractOpts := addExtractFlags(fs)
+	stateOpts := addStateFlags(fs)
+	err := fs.Parse([]string{
+		"-url", "https://a.example/,https://b.example/",
+		"-maxDepth", "2", "-obeyRobots=false", "-stripTracking", "-queryRule", "sort",
+		"-hostConnections", "4", "-dnsCache", "10", "-bearerToken", "t0ken", "-hostHeader", "b.example=Authorization: mine",
+		"-downloadSigned", "dl", "-maxDownloadBytes", "1024",
+		"-queueCodec", "msgpack", "-visitedBloom", "1000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopeOpts.validate()
+	fetchOpts.validate(seedOpts.urls)
+	extractOpts.validate()
+	stateOpts.validate()
+
+	c := crawler.New(nil)
+	scopeOpts.apply(c)
+	fetchOpts.apply(c)
+	extractOpts.apply(c)
+	stateOpts.apply(c)
+
+	if c.MaxDepth != 2 || c.Politeness != (crawler.Politeness{}) || c.WindowLocation != time.Local {
+		t.Errorf("scope = depth %d, politeness %+v, window zone %v", c.MaxDepth, c.Politeness, c.WindowLocation)
+	}
+	if len(c.QueryRules) != 1+len(crawler.DefaultQueryRules) || c.QueryRules[0].Param != "sort" {
+		t.Errorf("query rules = %v, want sort before the tracking defaults", c.QueryRules)
+	}
+	if c.MaxHostConnections != 4 || c.DNSCache == nil || c.DNSCache.Size != 10 || c.Cookies != nil {
+		t.Errorf("fetch = %d host connections, DNS cache %+v, cookies %v", c.MaxHostConnections, c.DNSCache, c.Cookies)
+	}
+	if got := c.HostHeaders["a.example"].Get("Authorization"); got != "Bearer t0ken" {
+		t.Errorf("a.example Authorization = %q, want the bearer token", got)
+	}
+	if got := c.HostHeaders["b.example"].Get("Authorization"); got != "mine" {
+		t.Errorf("b.example Authorization = %q, want the header given for it kept", got)
+	}
+	if c.DownloadDir != "dl" || !c.DownloadSigned || c.MaxDownloadBytes != 1024 {
+		t.Errorf("downloads = %q, signed %v, %d bytes", c.DownloadDir, c.DownloadSigned, c.MaxDownloadBytes)
+	}
+	if _, ok := c.Codec.(crawler.MsgpackCodec); !ok || c.VisitedBloom == nil || c.VisitedBloom.Capacity != 1000 {
+		t.Errorf("state = codec %T, bloom %+v", c.Codec, c.VisitedBloom)
+	}
+}
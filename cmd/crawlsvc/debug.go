@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// debugSnapshot is what the process is up to, served at /debug/crawls
+type debugSnapshot struct {
+	Goroutines int          `json:"goroutines"`
+	Crawls     []debugCrawl `json:"crawls"`
+}
+
+// debugCrawl is a crawl's progress and the pages this process's workers
+// are crawling for it
+type debugCrawl struct {
+	Job            string      `json:"job,omitempty"`
+	Queued         int         `json:"queued"`
+	Visited        int         `json:"visited"`
+	Images         int         `json:"images"`
+	ActiveWorkers  int         `json:"activeWorkers"`
+	StaleWorkers   int         `json:"staleWorkers"`
+	Paused         bool        `json:"paused"`
+	PagesPerMinute float64     `json:"pagesPerMinute"`
+	ErrorRate      float64     `json:"errorRate"`
+	InFlight       []debugPage `json:"inFlight"`
+	Error          string      `json:"error,omitempty"` // if the status couldn't be read
+}
+
+// debugPage is a page in flight, with how long it's taken so far
+type debugPage struct {
+	crawler.PageInFlight
+	Elapsed string `json:"elapsed"`
+}
+
+// snapshotCrawls snapshots the crawls, reading each one's status from Redis
+func snapshotCrawls(crawlers []*crawler.Crawler) debugSnapshot {
+	snap := debugSnapshot{Goroutines: runtime.NumGoroutine(), Crawls: []debugCrawl{}}
+	now := time.Now()
+	for _, c := range crawlers {
+		crawl := debugCrawl{Job: c.JobID, InFlight: []debugPage{}}
+		for _, p := range c.InFlight() {
+			crawl.InFlight = append(crawl.InFlight, debugPage{PageInFlight: p, Elapsed: now.Sub(p.Started).Round(time.Millisecond).String()})
+		}
+
+		status, err := c.Status()
+		if err != nil {
+			crawl.Error = err.Error()
+			snap.Crawls = append(snap.Crawls, crawl)
+			continue
+		}
+		crawl.Queued = status.Queued
+		crawl.Visited = status.Visited
+		crawl.Images = status.Images
+		crawl.ActiveWorkers = status.ActiveWorkers
+		crawl.Paused = status.Paused
+		crawl.PagesPerMinute = status.PagesPerMinute
+		crawl.ErrorRate = status.ErrorRate
+		for _, hb := range status.Workers {
+			if hb.Stale {
+				crawl.StaleWorkers++
+			}
+		}
+		snap.Crawls = append(snap.Crawls, crawl)
+	}
+	return snap
+}
+
+// serveDebug serves diagnostics of the crawls in hand on addr: pprof
+// profiles at /debug/pprof/, expvar at /debug/vars, and a snapshot of the
+// goroutines and crawls, with the pages each is crawling, at /debug/crawls.
+// It's for operators, so should be kept off public addresses.
+func serveDebug(addr string, crawlers func() []*crawler.Crawler, logger *slog.Logger) {
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	expvar.Publish("crawls", expvar.Func(func() any { return snapshotCrawls(crawlers()).Crawls }))
+	logger.Error("debug server stopped", "err", http.ListenAndServe(addr, debugHandler(crawlers)))
+}
+
+// debugHandler routes the debug listener. The command line, which may hold
+// -redisPassword, isn't served, neither by pprof nor as expvar's cmdline.
+func debugHandler(crawlers func() []*crawler.Crawler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/vars", serveVars)
+	mux.HandleFunc("/debug/crawls", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(snapshotCrawls(crawlers()))
+	})
+	return mux
+}
+
+// serveVars serves the expvars as expvar.Handler does, but for cmdline
+func serveVars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprint(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		if !first {
+			fmt.Fprint(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprint(w, "\n}\n")
+}
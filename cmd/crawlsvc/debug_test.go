@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+func TestDebugHidesCommandLine(t *testing.T) {
+	args := os.Args
+	os.Args = []string{"crawlsvc", "-redisPassword", "hunter2", "crawl", "https://example.com/"}
+	defer func() { os.Args = args }()
+
+	srv := httptest.NewServer(debugHandler(func() []*crawler.Crawler { return nil }))
+	defer srv.Close()
+
+	for _, path := range []string{"/debug/vars", "/debug/pprof/cmdline", "/debug/pprof/"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Contains(string(body), "hunter2") {
+			t.Errorf("%s serves the Redis password", path)
+		}
+		if path == "/debug/pprof/cmdline" && resp.StatusCode == http.StatusOK {
+			t.Errorf("%s served, want it not found", path)
+		}
+		if path == "/debug/vars" {
+			vars := map[string]json.RawMessage{}
+			if err := json.Unmarshal(body, &vars); err != nil {
+				t.Fatalf("%s isn't JSON: %v", path, err)
+			}
+			if _, ok := vars["memstats"]; !ok {
+				t.Errorf("%s = %s, want the other vars still served", path, body)
+			}
+		}
+	}
+}
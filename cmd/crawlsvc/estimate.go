@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// estimateCmd crawls a sample of a site and extrapolates the full crawl
+func estimateCmd(args []string) {
+	var (
+		url      string
+		budget   int
+		workersN int
+	)
+
+	fs := flag.NewFlagSet("crawlsvc estimate", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	logOpts := addLogFlags(fs)
+	fs.StringVar(&url, "url", "", "Required. The seed URL to crawl from")
+	fs.IntVar(&budget, "pages", 100, "How many pages to sample")
+	fs.IntVar(&workersN, "workers", 1, "The number of concurrent workers, the runtime estimate assumes the same")
+	fs.Parse(args)
+
+	if url == "" {
+		fmt.Fprintln(os.Stderr, "-url parameter is required")
+		os.Exit(2)
+	}
+
+	logger := logOpts.logger()
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	// the sample is crawled under a throwaway job
+	id := "estimate-" + crawler.NewJobID()
+	defer crawler.DeleteJob(pool, id)
+
+	c := crawler.NewJob(pool, id)
+	c.Logger = logger
+	c.Seed(url)
+
+	est, err := c.Estimate(shutdownContext(logger, c.DrainTimeout), budget, workersN)
+	exitOnError(err)
+
+	fmt.Printf("Sampled: %d pages, %d images, %s in %s\n",
+		est.SampledPages, est.SampledImages, formatBytes(est.SampledBytes), est.SampleDuration.Round(time.Millisecond))
+
+	switch {
+	case est.Complete:
+		fmt.Println("The sample crawled the whole site")
+	case est.Pages == 0:
+		fmt.Printf("Estimated: at least %d pages, sample more pages for an estimate\n", est.Discovered)
+		return
+	}
+
+	fmt.Printf("Estimated: %d pages, %d images, %s, %s with %d workers\n",
+		est.Pages, est.Images, formatBytes(est.Bytes), est.Duration.Round(time.Second), est.Workers)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
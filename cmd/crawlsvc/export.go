@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// exportRecord is the exported form of an image found during the crawl
+type exportRecord struct {
+	URL         string     `json:"url"`
+	SourcePage  string     `json:"sourcePage"` // the first page it was found on
+	SourcePages []string   `json:"sourcePages"`
+	FoundAt     *time.Time `json:"foundAt"`
+	ContentType string     `json:"contentType"`
+	Bytes       int64      `json:"bytes,omitempty"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+}
+
+func newExportRecord(r crawler.ImageRecord) exportRecord {
+	rec := exportRecord{
+		URL:         r.URL,
+		SourcePage:  r.PageURL,
+		SourcePages: r.Pages,
+		ContentType: r.ContentType,
+		Bytes:       r.Bytes,
+		Width:       r.Width,
+		Height:      r.Height,
+	}
+	if rec.SourcePages == nil {
+		rec.SourcePages = []string{}
+	}
+	if !r.FoundAt.IsZero() {
+		rec.FoundAt = &r.FoundAt
+	}
+	return rec
+}
+
+// exportIterator walks the image records to export, splitting each into one
+// record per page it was found on when perPage is set
+type exportIterator struct {
+	it      *crawler.RecordIterator
+	perPage bool
+	pending []crawler.ImageRecord
+}
+
+func newExportIterator(c *crawler.Crawler, perPage bool) *exportIterator {
+	return &exportIterator{it: c.RecordIterator(), perPage: perPage}
+}
+
+func (e *exportIterator) Next() bool {
+	if len(e.pending) > 1 {
+		e.pending = e.pending[1:]
+		return true
+	}
+	if !e.it.Next() {
+		e.pending = nil
+		return false
+	}
+
+	e.pending = []crawler.ImageRecord{e.it.Record()}
+	if e.perPage {
+		e.pending = e.pending[0].Occurrences()
+	}
+	return true
+}
+
+func (e *exportIterator) Record() crawler.ImageRecord {
+	return e.pending[0]
+}
+
+func (e *exportIterator) Err() error {
+	return e.it.Err()
+}
+
+// exportFormats are the writers for each supported -format, perPage writes
+// an image once for every page it was found on rather than once overall
+var exportFormats = map[string]func(w io.Writer, c *crawler.Crawler, perPage bool) error{
+	"text":   exportText,
+	"json":   exportJSON,
+	"ndjson": exportNDJSON,
+	"csv":    exportCSV,
+	"html":   exportHTML,
+}
+
+// exportResults writes the crawl results to output, "-" being stdout
+func exportResults(c *crawler.Crawler, format string, output string, perPage bool) error {
+	export, ok := exportFormats[format]
+	if !ok {
+		return fmt.Errorf("invalid -format %q", format)
+	}
+	return writeOutput(output, func(w io.Writer) error { return export(w, c, perPage) })
+}
+
+// writeOutput writes to output, "-" being stdout, buffered
+func writeOutput(output string, write func(w io.Writer) error) error {
+	if output == "-" {
+		buf := bufio.NewWriter(os.Stdout)
+		if err := write(buf); err != nil {
+			return err
+		}
+		return buf.Flush()
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(f)
+	err = write(buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// exportText writes a readable report summarizing the crawl, the hosts
+// crawled and any assets censused, icons collected, data URI images or
+// media found
+func exportText(w io.Writer, c *crawler.Crawler, perPage bool) error {
+	fmt.Fprintln(w, "Crawling Complete")
+	sum, err := c.Summary()
+	if err != nil {
+		return err
+	}
+	printSummary(w, sum)
+
+	hosts, err := c.HostSummaries()
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		fmt.Fprintf(w, "Host %s: %d pages, %d images", h.Host, h.Pages, h.Images)
+		if h.FaviconURL != "" {
+			fmt.Fprintf(w, ", favicon mmh3=%d sha256=%s", h.FaviconMMH3, h.FaviconSHA256)
+		}
+		fmt.Fprintln(w)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		return err
+	}
+	printStats(w, stats)
+
+	assets, err := c.Assets()
+	if err != nil {
+		return err
+	}
+	if len(assets) > 0 {
+		fmt.Fprintln(w, "Assets:")
+	}
+	for _, a := range assets {
+		party := "first-party"
+		if a.ThirdParty {
+			party = "third-party"
+		}
+		fmt.Fprintf(w, "  %s %s %s (%d pages)\n", party, a.Kind, a.URL, a.Pages)
+	}
+
+	icons, err := c.Icons()
+	if err != nil {
+		return err
+	}
+	if len(icons) > 0 {
+		fmt.Fprintln(w, "Icons:")
+	}
+	for _, icon := range icons {
+		fmt.Fprintf(w, "  %s %s %s\n", icon.Host, icon.Kind, icon.URL)
+	}
+
+	dataImages, err := c.DataImages()
+	if err != nil {
+		return err
+	}
+	if len(dataImages) > 0 {
+		fmt.Fprintf(w, "Data URI images (%d):\n", len(dataImages))
+	}
+	for _, img := range dataImages {
+		fmt.Fprintf(w, "  %s %s %s", img.ID, img.ContentType, formatBytes(int64(img.Bytes)))
+		if img.Path != "" {
+			fmt.Fprintf(w, " saved to %s", img.Path)
+		}
+		fmt.Fprintln(w)
+	}
+
+	media, err := c.Media()
+	if err != nil {
+		return err
+	}
+	if len(media) > 0 {
+		fmt.Fprintf(w, "Media (%d):\n", len(media))
+	}
+	for _, m := range media {
+		fmt.Fprintln(w, " ", m)
+	}
+
+	failed, err := c.FailedPages("")
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "Failed pages (%d):\n", len(failed))
+	}
+	for _, f := range failed {
+		fmt.Fprintf(w, "  %s %s: %s", f.Class, f.URL, f.Reason)
+		if f.Parent != "" {
+			fmt.Fprintf(w, " (linked from %s)", f.Parent)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// printStats prints the breakdown of images by format and size
+func printStats(w io.Writer, stats crawler.Stats) {
+	if stats.Images == 0 {
+		return
+	}
+
+	formats := make([]string, 0, len(stats.Formats))
+	for f := range stats.Formats {
+		formats = append(formats, f)
+	}
+	sort.Slice(formats, func(i, j int) bool {
+		if stats.Formats[formats[i]] != stats.Formats[formats[j]] {
+			return stats.Formats[formats[i]] > stats.Formats[formats[j]]
+		}
+		return formats[i] < formats[j]
+	})
+	parts := make([]string, len(formats))
+	for i, f := range formats {
+		parts[i] = fmt.Sprintf("%.0f%% %s", 100*float64(stats.Formats[f])/float64(stats.Images), f)
+	}
+	fmt.Fprintf(w, "Image formats: %s\n", strings.Join(parts, ", "))
+
+	if stats.Sized == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Image sizes (%d fetched): median %s", stats.Sized, formatBytes(stats.MedianBytes))
+	for i, n := range stats.SizeCounts {
+		if i < len(crawler.SizeBuckets) {
+			fmt.Fprintf(w, ", <=%s %d", formatBytes(crawler.SizeBuckets[i]), n)
+		} else {
+			fmt.Fprintf(w, ", >%s %d", formatBytes(crawler.SizeBuckets[i-1]), n)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// printSummary prints the pages crawled, the failures among them by status,
+// the images found by extension and how long the crawl took
+func printSummary(w io.Writer, sum crawler.Summary) {
+	failed := 0
+	for _, n := range sum.Failures {
+		failed += n
+	}
+	fmt.Fprintf(w, "Pages crawled: %d of %d hosts, %d failed", sum.Pages, sum.Hosts, failed)
+	if failed > 0 {
+		fmt.Fprintf(w, " (%s)", formatCounts(sum.Failures))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Images found: %d", sum.Images)
+	if sum.Images > 0 {
+		fmt.Fprintf(w, " (%s)", formatCounts(sum.Extensions))
+	}
+	fmt.Fprintln(w)
+
+	if !sum.Started.IsZero() {
+		fmt.Fprintf(w, "Duration: %s, average latency %s\n", sum.Duration.Round(time.Millisecond), sum.AvgLatency.Round(time.Millisecond))
+	}
+}
+
+// formatCounts formats counts as "key n" pairs, largest first
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// exportSummary is the exported form of the crawl's summary
+type exportSummary struct {
+	Pages           int            `json:"pages"`
+	Failures        map[string]int `json:"failures"`
+	Hosts           int            `json:"hosts"`
+	Images          int            `json:"images"`
+	Extensions      map[string]int `json:"imageExtensions"`
+	Started         *time.Time     `json:"started"`
+	Finished        *time.Time     `json:"finished"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	AvgLatencyMs    float64        `json:"avgLatencyMs"`
+}
+
+func newExportSummary(sum crawler.Summary) exportSummary {
+	exp := exportSummary{
+		Pages:           sum.Pages,
+		Failures:        sum.Failures,
+		Hosts:           sum.Hosts,
+		Images:          sum.Images,
+		Extensions:      sum.Extensions,
+		DurationSeconds: sum.Duration.Seconds(),
+		AvgLatencyMs:    float64(sum.AvgLatency) / float64(time.Millisecond),
+	}
+	if !sum.Started.IsZero() {
+		exp.Started, exp.Finished = &sum.Started, &sum.Finished
+	}
+	return exp
+}
+
+// exportJSON writes a single object, the crawl's summary and an array of the
+// images, streamed so the results are never all in memory at once
+func exportJSON(w io.Writer, c *crawler.Crawler, perPage bool) error {
+	sum, err := c.Summary()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(newExportSummary(sum))
+	if err != nil {
+		return err
+	}
+	io.WriteString(w, `{"summary":`)
+	w.Write(data)
+
+	it := newExportIterator(c, perPage)
+	io.WriteString(w, `,"images":[`)
+	for first := true; it.Next(); first = false {
+		if !first {
+			io.WriteString(w, ",")
+		}
+		data, err := json.Marshal(newExportRecord(it.Record()))
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+	io.WriteString(w, "]}\n")
+	return it.Err()
+}
+
+// exportNDJSON writes one JSON record per line
+func exportNDJSON(w io.Writer, c *crawler.Crawler, perPage bool) error {
+	it := newExportIterator(c, perPage)
+	enc := json.NewEncoder(w)
+	for it.Next() {
+		if err := enc.Encode(newExportRecord(it.Record())); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// exportCSV writes a header row followed by one row per record
+func exportCSV(w io.Writer, c *crawler.Crawler, perPage bool) error {
+	it := newExportIterator(c, perPage)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "sourcePage", "sourcePages", "foundAt", "contentType", "bytes", "width", "height"})
+	for it.Next() {
+		rec := newExportRecord(it.Record())
+		foundAt := ""
+		if rec.FoundAt != nil {
+			foundAt = rec.FoundAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{rec.URL, rec.SourcePage, strings.Join(rec.SourcePages, " "), foundAt, rec.ContentType, optionalInt(rec.Bytes), optionalInt(int64(rec.Width)), optionalInt(int64(rec.Height))})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+// optionalInt formats n for a CSV cell, left blank if unknown
+func optionalInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// exportPage is the exported form of a page's record, for auditing the site
+type exportPage struct {
+	URL        string `json:"url"`
+	Status     int    `json:"status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Depth      int    `json:"depth"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	DurationMS int64  `json:"durationMs,omitempty"`
+	Images     int    `json:"images"`
+	Outlinks   int    `json:"outlinks"`
+}
+
+func newExportPage(r crawler.PageRecord) exportPage {
+	return exportPage{
+		URL:        r.URL,
+		Status:     r.Status,
+		Error:      r.Error,
+		Title:      r.Title,
+		Depth:      r.Depth,
+		Bytes:      r.Bytes,
+		DurationMS: r.Duration.Milliseconds(),
+		Images:     r.ImageCount,
+		Outlinks:   r.Outlinks,
+	}
+}
+
+// pageExportFormats are the writers for each supported -pagesFormat
+var pageExportFormats = map[string]func(w io.Writer, c *crawler.Crawler) error{
+	"csv":    exportPagesCSV,
+	"ndjson": exportPagesNDJSON,
+}
+
+// exportPages writes a record of every page visited to output, "-" being
+// stdout
+func exportPages(c *crawler.Crawler, format string, output string) error {
+	export, ok := pageExportFormats[format]
+	if !ok {
+		return fmt.Errorf("invalid -pagesFormat %q", format)
+	}
+	return writeOutput(output, func(w io.Writer) error { return export(w, c) })
+}
+
+// exportPagesNDJSON writes one JSON page record per line
+func exportPagesNDJSON(w io.Writer, c *crawler.Crawler) error {
+	it := c.PageIterator()
+	enc := json.NewEncoder(w)
+	for it.Next() {
+		if err := enc.Encode(newExportPage(it.Record())); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// exportPagesCSV writes a header row followed by one row per page
+func exportPagesCSV(w io.Writer, c *crawler.Crawler) error {
+	it := c.PageIterator()
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "status", "error", "title", "depth", "bytes", "durationMs", "images", "outlinks"})
+	for it.Next() {
+		p := newExportPage(it.Record())
+		cw.Write([]string{p.URL, optionalInt(int64(p.Status)), p.Error, p.Title, strconv.Itoa(p.Depth), optionalInt(p.Bytes), optionalInt(p.DurationMS), strconv.Itoa(p.Images), strconv.Itoa(p.Outlinks)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+// failedExportFormats are the writers for each supported -pagesFormat of
+// -failedOutput
+var failedExportFormats = map[string]func(w io.Writer, failed []crawler.FailedPage) error{
+	"csv":    exportFailedCSV,
+	"ndjson": exportFailedNDJSON,
+}
+
+// exportFailed writes the pages that failed, 4xx, 5xx or not fetched at
+// all, to output, "-" being stdout
+func exportFailed(c *crawler.Crawler, format string, output string) error {
+	export, ok := failedExportFormats[format]
+	if !ok {
+		return fmt.Errorf("invalid -pagesFormat %q", format)
+	}
+	failed, err := c.FailedPages("")
+	if err != nil {
+		return err
+	}
+	return writeOutput(output, func(w io.Writer) error { return export(w, failed) })
+}
+
+// exportFailedNDJSON writes one JSON failed page per line
+func exportFailedNDJSON(w io.Writer, failed []crawler.FailedPage) error {
+	enc := json.NewEncoder(w)
+	for _, f := range failed {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportFailedCSV writes a header row followed by one row per failed page
+func exportFailedCSV(w io.Writer, failed []crawler.FailedPage) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "class", "status", "reason", "parent"})
+	for _, f := range failed {
+		cw.Write([]string{f.URL, f.Class, optionalInt(int64(f.Status)), f.Reason, f.Parent})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// newTestCrawl crawls a site of two pages, b.png being found on both, with
+// a crawler backed by miniredis
+func newTestCrawl(t *testing.T) (*crawler.Crawler, string) {
+	t.Helper()
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><img src="/b.png"><a href="/next">next</a>`))
+		case "/next":
+			w.Write([]byte(`<img src="/b.png">`))
+		}
+	}))
+	t.Cleanup(site.Close)
+
+	mr := miniredis.RunT(t)
+	c := crawler.New(crawler.NewPool("tcp", mr.Addr()))
+	c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.TerminationGrace = 10 * time.Millisecond
+	c.Seed(site.URL)
+	c.Run()
+	return c, site.URL
+}
+
+func TestExportJSON(t *testing.T) {
+	c, site := newTestCrawl(t)
+	buf := bytes.Buffer{}
+	if err := exportJSON(&buf, c, false); err != nil {
+		t.Fatal(err)
+	}
+
+	exported := struct {
+		Summary exportSummary
+		Images  []exportRecord
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatalf("%v in %s", err, buf.String())
+	}
+	if exported.Summary.Pages != 2 || exported.Summary.Images != 2 || exported.Summary.Started == nil {
+		t.Errorf("summary = %+v, want 2 pages and 2 images", exported.Summary)
+	}
+	if len(exported.Images) != 2 {
+		t.Fatalf("exported %d images, want 2", len(exported.Images))
+	}
+	for _, img := range exported.Images {
+		if img.FoundAt == nil || img.ContentType != "image/png" {
+			t.Errorf("image = %+v, want when it was found and its type", img)
+		}
+		if img.URL == site+"/b.png" && len(img.SourcePages) != 2 {
+			t.Errorf("b.png found on %v, want both pages", img.SourcePages)
+		}
+	}
+}
+
+func TestExportNDJSONPerPage(t *testing.T) {
+	c, site := newTestCrawl(t)
+	for _, tt := range []struct {
+		perPage bool
+		want    []string
+	}{
+		{false, []string{"/a.png " + site, "/b.png " + site}},
+		{true, []string{"/a.png " + site, "/b.png " + site, "/b.png " + site + "/next"}},
+	} {
+		buf := bytes.Buffer{}
+		if err := exportNDJSON(&buf, c, tt.perPage); err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		for sc := bufio.NewScanner(&buf); sc.Scan(); {
+			rec := exportRecord{}
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				t.Fatal(err)
+			}
+			if tt.perPage && len(rec.SourcePages) != 0 {
+				t.Errorf("record per page = %+v, want no other pages", rec)
+			}
+			got = append(got, strings.TrimPrefix(rec.URL, site)+" "+rec.SourcePage)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("perPage %v: exported %v, want %v", tt.perPage, got, tt.want)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	c, site := newTestCrawl(t)
+	buf := bytes.Buffer{}
+	if err := exportCSV(&buf, c, false); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "url" {
+		t.Fatalf("rows = %v, want a header and 2 images", rows)
+	}
+	for _, row := range rows[1:] {
+		if row[1] != site || row[3] == "" || row[4] != "image/png" || row[5] != "" {
+			t.Errorf("row = %q, want its first page, time and type, and no unknown size", row)
+		}
+		if row[0] == site+"/b.png" && row[2] != site+" "+site+"/next" && row[2] != site+"/next "+site {
+			t.Errorf("b.png found on %q, want both pages", row[2])
+		}
+	}
+}
+
+func TestExportResults(t *testing.T) {
+	c, _ := newTestCrawl(t)
+	output := filepath.Join(t.TempDir(), "images.ndjson")
+	if err := exportResults(c, "ndjson", output, false); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(output); err != nil || strings.Count(string(data), "\n") != 2 {
+		t.Errorf("wrote %q, %v, want 2 lines", data, err)
+	}
+
+	if err := exportResults(c, "xml", output, false); err == nil || !strings.Contains(err.Error(), `invalid -format "xml"`) {
+		t.Errorf("exportResults(xml) = %v, want an invalid format", err)
+	}
+	if err := exportResults(c, "json", filepath.Join(t.TempDir(), "missing", "out.json"), false); err == nil {
+		t.Error("exportResults to a missing directory succeeded")
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// redisOptions are the connection and job flags shared by every subcommand
+type redisOptions struct {
+	addr      string
+	network   string
+	sentinels string
+	master    string
+	cluster   string
+	password  string
+	db        int
+	tls       bool
+	caFile    string
+	certFile  string
+	keyFile   string
+	job       string
+}
+
+func addRedisFlags(fs *flag.FlagSet) *redisOptions {
+	opts := &redisOptions{}
+	fs.StringVar(&opts.addr, "redisAddr", "", "Required unless using Sentinel or Cluster. The redis host address and port")
+	fs.StringVar(&opts.network, "redisNetwork", "tcp", "The redis network")
+	fs.StringVar(&opts.sentinels, "redisSentinels", "", "Comma-separated Redis Sentinel addresses to find the master from, with -redisMaster")
+	fs.StringVar(&opts.master, "redisMaster", "", "The master name monitored by the Redis Sentinels")
+	fs.StringVar(&opts.cluster, "redisCluster", "", "Comma-separated Redis Cluster node addresses to discover the cluster from, crawls need -job")
+	fs.StringVar(&opts.password, "redisPassword", "", "The redis password, defaults to $REDIS_PASSWORD")
+	fs.IntVar(&opts.db, "redisDB", 0, "The redis database number")
+	fs.BoolVar(&opts.tls, "redisTLS", false, "Connect to redis over TLS")
+	fs.StringVar(&opts.caFile, "redisCA", "", "A PEM CA certificate to verify the redis server with, instead of the system roots")
+	fs.StringVar(&opts.certFile, "redisCert", "", "A PEM client certificate for redis TLS, with -redisKey")
+	fs.StringVar(&opts.keyFile, "redisKey", "", "The PEM private key of -redisCert")
+	fs.StringVar(&opts.job, "job", "", "The crawl job ID, omit to use the legacy un-namespaced keys")
+	return opts
+}
+
+// pool creates a connection pool, exiting if the flags are incomplete
+func (opts *redisOptions) pool() *redis.Pool {
+	modes := 0
+	for _, set := range []bool{opts.addr != "", opts.sentinels != "", opts.cluster != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		fmt.Fprintln(os.Stderr, "exactly one of -redisAddr, -redisSentinels or -redisCluster is required")
+		os.Exit(2)
+	}
+
+	dialOpts, err := opts.dialOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if opts.sentinels != "" {
+		if opts.master == "" {
+			fmt.Fprintln(os.Stderr, "-redisMaster is required with -redisSentinels")
+			os.Exit(2)
+		}
+		return crawler.NewSentinelPool(opts.master, strings.Split(opts.sentinels, ","), dialOpts...)
+	}
+	if opts.cluster != "" {
+		if opts.db != 0 {
+			fmt.Fprintln(os.Stderr, "-redisDB can't be used with -redisCluster")
+			os.Exit(2)
+		}
+		return crawler.NewClusterPool(strings.Split(opts.cluster, ","), dialOpts...)
+	}
+
+	return crawler.NewPool(opts.network, opts.addr, dialOpts...)
+}
+
+// dialOptions builds the auth, database and TLS dial options
+func (opts *redisOptions) dialOptions() ([]redis.DialOption, error) {
+	ro := crawler.RedisOptions{
+		Password: opts.password,
+		DB:       opts.db,
+	}
+	if ro.Password == "" {
+		ro.Password = os.Getenv("REDIS_PASSWORD")
+	}
+
+	if !opts.tls {
+		if opts.caFile != "" || opts.certFile != "" || opts.keyFile != "" {
+			return nil, fmt.Errorf("-redisCA, -redisCert and -redisKey need -redisTLS")
+		}
+		return ro.DialOptions(), nil
+	}
+
+	ro.TLS = &tls.Config{}
+	if opts.caFile != "" {
+		pem, err := os.ReadFile(opts.caFile)
+		if err != nil {
+			return nil, err
+		}
+		ro.TLS.RootCAs = x509.NewCertPool()
+		if !ro.TLS.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.caFile)
+		}
+	}
+	if opts.certFile != "" || opts.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		ro.TLS.Certificates = []tls.Certificate{cert}
+	}
+	return ro.DialOptions(), nil
+}
+
+// crawler creates a Crawler for the selected job, exiting if the job ID is
+// invalid
+func (opts *redisOptions) crawler(pool *redis.Pool) *crawler.Crawler {
+	if opts.job == "" {
+		return crawler.New(pool)
+	}
+
+	if err := crawler.ValidateJobID(opts.job); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return crawler.NewJob(pool, opts.job)
+}
+
+// logOptions are the logging flags shared by every subcommand
+type logOptions struct {
+	level  string
+	format string
+}
+
+func addLogFlags(fs *flag.FlagSet) *logOptions {
+	opts := &logOptions{}
+	fs.StringVar(&opts.level, "logLevel", "info", "The minimum log level: debug, info, warn or error")
+	fs.StringVar(&opts.format, "logFormat", "text", "The log format: text or json")
+	return opts
+}
+
+// logger creates the logger and installs it as the default, exiting if the
+// flags are invalid
+func (opts *logOptions) logger() *slog.Logger {
+	logger, err := newLogger(opts.level, opts.format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	slog.SetDefault(logger)
+	return logger
+}
+
+func newCodec(name string) (crawler.Codec, error) {
+	switch name {
+	case "json":
+		return crawler.JSONCodec{}, nil
+	case "msgpack":
+		return crawler.MsgpackCodec{}, nil
+	}
+
+	return nil, fmt.Errorf("invalid -queueCodec %q", name)
+}
+
+// parseProxies reads the -proxy list and the -proxyFile, one proxy per line
+func parseProxies(list string, file string) ([]*url.URL, error) {
+	raw := []string{}
+	if list != "" {
+		raw = append(raw, strings.Split(list, ",")...)
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				raw = append(raw, line)
+			}
+		}
+	}
+
+	proxies := []*url.URL{}
+	for _, r := range raw {
+		u, err := url.Parse(strings.TrimSpace(r))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", r)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy %q: the scheme must be http, https or socks5", r)
+		}
+		proxies = append(proxies, u)
+	}
+	return proxies, nil
+}
+
+// cookieJar validates the -cookies and -cookieFile flags, returning a func
+// creating a freshly seeded jar for each crawl, or nil if cookies are off
+func cookieJar(mode string, file string) (func() http.CookieJar, error) {
+	if mode == "" && file != "" {
+		mode = "shared"
+	}
+	if mode != "" && mode != "shared" && mode != "host" {
+		return nil, fmt.Errorf("invalid -cookies %q", mode)
+	}
+
+	var seed []byte
+	if file != "" {
+		var err error
+		if seed, err = os.ReadFile(file); err != nil {
+			return nil, err
+		}
+		if err := crawler.LoadCookies(crawler.NewCookieJar(false), bytes.NewReader(seed)); err != nil {
+			return nil, fmt.Errorf("invalid -cookieFile: %w", err)
+		}
+	}
+
+	return func() http.CookieJar {
+		if mode == "" {
+			return nil
+		}
+		jar := crawler.NewCookieJar(mode == "host")
+		crawler.LoadCookies(jar, bytes.NewReader(seed))
+		return jar
+	}, nil
+}
+
+// fingerprintIgnored are the flags that don't change what a crawl does, so
+// processes may differ in them and still share a -lock
+var fingerprintIgnored = map[string]bool{
+	"url": true, "seedFile": true, "sitemap": true, "resume": true, "workers": true, "lock": true,
+	"drainTimeout": true, "metricsAddr": true, "format": true, "output": true,
+	"logLevel": true, "logFormat": true, "config": true,
+}
+
+// configFingerprint hashes the flags that shape the crawl, redis flags
+// aside, so -lock can tell whether two processes are running the same crawl
+func configFingerprint(fs *flag.FlagSet) string {
+	h := sha256.New()
+	fs.VisitAll(func(f *flag.Flag) {
+		if fingerprintIgnored[f.Name] || strings.HasPrefix(f.Name, "redis") || f.Name == "job" {
+			return
+		}
+		fmt.Fprintf(h, "%s=%q\n", f.Name, f.Value.String())
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// headerFlag collects repeated -header "Name: value" flags
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	return fmt.Sprint(http.Header(h))
+}
+
+func (h headerFlag) Set(v string) error {
+	name, value, err := parseHeader(v)
+	if err != nil {
+		return err
+	}
+	http.Header(h).Add(name, value)
+	return nil
+}
+
+// hostHeaderFlag collects repeated -hostHeader "host=Name: value" flags
+type hostHeaderFlag map[string]http.Header
+
+func (h hostHeaderFlag) String() string {
+	return fmt.Sprint(map[string]http.Header(h))
+}
+
+func (h hostHeaderFlag) Set(v string) error {
+	host, header, ok := strings.Cut(v, "=")
+	if !ok || host == "" {
+		return fmt.Errorf("want host=Name: value")
+	}
+	name, value, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+	if h[host] == nil {
+		h[host] = http.Header{}
+	}
+	h[host].Add(name, value)
+	return nil
+}
+
+// urlListFlag collects repeated, or comma-separated, -url flags
+type urlListFlag []string
+
+func (u *urlListFlag) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urlListFlag) Set(v string) error {
+	*u = append(*u, splitList(v)...)
+	return nil
+}
+
+// queryRuleFlag collects repeated -queryRule flags
+type queryRuleFlag []crawler.QueryRule
+
+func (q *queryRuleFlag) String() string {
+	return fmt.Sprint([]crawler.QueryRule(*q))
+}
+
+func (q *queryRuleFlag) Set(v string) error {
+	rule, err := crawler.ParseQueryRule(v)
+	if err != nil {
+		return err
+	}
+	*q = append(*q, rule)
+	return nil
+}
+
+// windowFlag collects repeated -window "HH:MM-HH:MM" flags
+type windowFlag []crawler.Window
+
+func (w *windowFlag) String() string {
+	return fmt.Sprint([]crawler.Window(*w))
+}
+
+func (w *windowFlag) Set(v string) error {
+	window, err := crawler.ParseWindow(v)
+	if err != nil {
+		return err
+	}
+	*w = append(*w, window)
+	return nil
+}
+
+func parseHeader(v string) (name string, value string, err error) {
+	name, value, ok := strings.Cut(v, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("want Name: value")
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// authHeader builds the Authorization header from the -basicAuth and
+// -bearerToken flags, returning "" if neither is set
+func authHeader(basic string, bearer string) (string, error) {
+	switch {
+	case basic != "" && bearer != "":
+		return "", fmt.Errorf("-basicAuth and -bearerToken can't both be set")
+	case basic != "":
+		user, pass, ok := strings.Cut(basic, ":")
+		if !ok {
+			return "", fmt.Errorf("-basicAuth must be user:password")
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(user, pass)
+		return req.Header.Get("Authorization"), nil
+	case bearer != "":
+		return "Bearer " + bearer, nil
+	}
+	return "", nil
+}
+
+// exitUsage reports invalid flags and exits with the usage status
+func exitUsage(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
+
+// isFlagSet reports whether a flag was set rather than left at its default
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+func newLogger(level string, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid -logLevel %q", level)
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, handlerOpts)), nil
+	}
+
+	return nil, fmt.Errorf("invalid -logFormat %q", format)
+}
+
+// splitList splits a comma-separated flag, dropping empty items
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// serveResultsCmd serves a browsable gallery of the crawl's images
+func serveResultsCmd(args []string) {
+	var addr string
+
+	fs := flag.NewFlagSet("crawlsvc serve-results", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	logOpts := addLogFlags(fs)
+	fs.StringVar(&addr, "addr", "localhost:8080", "The address to serve the gallery on")
+	fs.Parse(args)
+
+	logger := logOpts.logger()
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	g := &gallery{c: redisOpts.crawler(pool), logger: logger}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", g.index)
+	mux.HandleFunc("GET /file", g.file)
+
+	logger.Info("serving results", "url", "http://"+addr+"/")
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to serve results:", err)
+		os.Exit(1)
+	}
+}
+
+// reportCmd writes the gallery of a crawl's images as a standalone HTML page
+func reportCmd(args []string) {
+	var output string
+
+	fs := flag.NewFlagSet("crawlsvc report", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.StringVar(&output, "output", "gallery.html", "The file to write the gallery to, - for stdout")
+	fs.Parse(args)
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	exitOnError(exportResults(redisOpts.crawler(pool), "html", output, false))
+}
+
+// gallery renders the crawl's images grouped by the pages they were found on,
+// preferring downloaded copies where there are any
+type gallery struct {
+	c      *crawler.Crawler
+	logger *slog.Logger
+}
+
+type galleryPage struct {
+	URL    string
+	Images []galleryImage
+}
+
+type galleryImage struct {
+	URL string
+	// Local is where a downloaded copy is loaded from, trusted as it's built
+	// here, unlike URL which html/template still sanitizes
+	Local template.URL
+}
+
+var galleryTmpl = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Crawl Results{{with .Job}} - {{.}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h2 { font-size: 1em; word-break: break-all; }
+h2 .count { font-weight: normal; color: #666; }
+.grid { display: flex; flex-wrap: wrap; gap: 8px; }
+.grid a { display: block; width: 160px; height: 160px; background: #eee; }
+.grid img { width: 100%; height: 100%; object-fit: contain; }
+</style>
+</head>
+<body>
+<h1>Crawl Results{{with .Job}} - {{.}}{{end}}</h1>
+<p>{{.Count}} images on {{len .Pages}} pages</p>
+{{range .Pages}}
+<h2><a href="{{.URL}}">{{or .URL "(unknown page)"}}</a> <span class="count">{{len .Images}} images</span></h2>
+<div class="grid">
+{{range .Images}}<a href="{{.URL}}" title="{{.URL}}"><img src="{{or .Local .URL}}" loading="lazy" alt=""></a>
+{{end}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+func (g *gallery) index(w http.ResponseWriter, r *http.Request) {
+	pages, count, err := galleryPages(g.c, func(image string) template.URL {
+		return template.URL("/file?url=" + url.QueryEscape(image))
+	})
+	if err != nil {
+		g.error(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	galleryTmpl.Execute(w, struct {
+		Job   string
+		Count int
+		Pages []galleryPage
+	}{g.c.JobID, count, pages})
+}
+
+// galleryPages groups the crawl's images by the pages they were found on,
+// sorted by URL, along with the number of images. Downloaded images are
+// loaded from downloaded(imageURL), the rest from their own URLs.
+func galleryPages(c *crawler.Crawler, downloaded func(image string) template.URL) ([]galleryPage, int, error) {
+	downloads := map[string]bool{}
+	dl := c.DownloadIterator()
+	for dl.Next() {
+		downloads[dl.Member()] = true
+	}
+	if err := dl.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	byPage := map[string][]galleryImage{}
+	count := 0
+	it := c.RecordIterator()
+	for it.Next() {
+		rec := it.Record()
+		img := galleryImage{URL: rec.URL}
+		if downloads[rec.URL] {
+			img.Local = downloaded(rec.URL)
+		}
+		pages := rec.Pages
+		if len(pages) == 0 {
+			pages = []string{rec.PageURL}
+		}
+		for _, page := range pages {
+			byPage[page] = append(byPage[page], img)
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	pages := make([]galleryPage, 0, len(byPage))
+	for page, imgs := range byPage {
+		sort.Slice(imgs, func(i, j int) bool { return imgs[i].URL < imgs[j].URL })
+		pages = append(pages, galleryPage{URL: page, Images: imgs})
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].URL < pages[j].URL })
+	return pages, count, nil
+}
+
+// exportHTML writes the gallery as a standalone page, for -format html and
+// the report command. Downloaded images are linked to on disk.
+func exportHTML(w io.Writer, c *crawler.Crawler, perPage bool) error {
+	var pathErr error
+	pages, count, err := galleryPages(c, func(image string) template.URL {
+		path, err := c.DownloadPath(image)
+		if err != nil {
+			pathErr = err
+		}
+		if path == "" {
+			return ""
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		// html/template would replace a file: URL as unsafe
+		return template.URL((&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String())
+	})
+	if err == nil {
+		err = pathErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return galleryTmpl.Execute(w, struct {
+		Job   string
+		Count int
+		Pages []galleryPage
+	}{c.JobID, count, pages})
+}
+
+// file serves a downloaded image from disk
+func (g *gallery) file(w http.ResponseWriter, r *http.Request) {
+	path, err := g.c.DownloadPath(r.URL.Query().Get("url"))
+	if err != nil {
+		g.error(w, err)
+		return
+	}
+	if path == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeFile(w, r, path)
+}
+
+func (g *gallery) error(w http.ResponseWriter, err error) {
+	g.logger.Error("failed to read results", "err", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
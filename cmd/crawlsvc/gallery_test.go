@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// newTestGallery serves the gallery of a test crawl, a.png having been
+// downloaded to the returned path
+func newTestGallery(t *testing.T) (*httptest.Server, *crawler.Crawler, string, string) {
+	t.Helper()
+	c, site := newTestCrawl(t)
+	path := filepath.Join(t.TempDir(), "a.png")
+	os.WriteFile(path, []byte("png"), 0o644)
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("HSET", c.KeyDownloads, site+"/a.png", path); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &gallery{c: c, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", g.index)
+	mux.HandleFunc("GET /file", g.file)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, c, site, path
+}
+
+// get fetches url, returning its status and body
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestGalleryIndex(t *testing.T) {
+	srv, _, site, _ := newTestGallery(t)
+
+	code, body := get(t, srv.URL+"/")
+	if code != http.StatusOK || !strings.Contains(body, "2 images on 2 pages") {
+		t.Fatalf("GET / = %d %s, want 2 images on 2 pages", code, body)
+	}
+	if want := `src="/file?url=` + url.QueryEscape(site+"/a.png") + `"`; !strings.Contains(body, want) {
+		t.Errorf("gallery doesn't load the downloaded a.png from %s", want)
+	}
+	if want := `src="` + site + `/b.png"`; strings.Count(body, want) != 2 {
+		t.Errorf("gallery doesn't show b.png on both pages from its own URL %s", want)
+	}
+	if i, j := strings.Index(body, `<a href="`+site+`">`), strings.Index(body, `<a href="`+site+`/next">`); i < 0 || j < i {
+		t.Errorf("pages aren't listed in order of URL")
+	}
+}
+
+func TestGalleryFile(t *testing.T) {
+	srv, _, site, _ := newTestGallery(t)
+
+	if code, body := get(t, srv.URL+"/file?url="+url.QueryEscape(site+"/a.png")); code != http.StatusOK || body != "png" {
+		t.Errorf("GET downloaded file = %d %q, want it served from disk", code, body)
+	}
+	if code, _ := get(t, srv.URL+"/file?url="+url.QueryEscape(site+"/b.png")); code != http.StatusNotFound {
+		t.Errorf("GET file not downloaded = %d, want 404", code)
+	}
+}
+
+func TestGalleryRedisUnreachable(t *testing.T) {
+	c := crawler.New(crawler.NewPool("tcp", "127.0.0.1:1"))
+	g := &gallery{c: c, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	for _, handle := range []http.HandlerFunc{g.index, g.file} {
+		rec := httptest.NewRecorder()
+		handle(rec, httptest.NewRequest("GET", "/file?url=x", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("with Redis unreachable got %d, want 500", rec.Code)
+		}
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	_, c, site, path := newTestGallery(t)
+	buf := bytes.Buffer{}
+	if err := exportHTML(&buf, c, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `src="` + (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String() + `"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("report doesn't link the downloaded a.png on disk with %s", want)
+	}
+	if !strings.Contains(buf.String(), `src="`+site+`/b.png"`) {
+		t.Errorf("report doesn't load b.png from its own URL")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/daveagill/go-imgcrawler/api"
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// grpcServer exposes the job manager as the api.CrawlService
+type grpcServer struct {
+	api.UnimplementedCrawlServiceServer
+	jobs *jobManager
+}
+
+func (s *grpcServer) StartCrawl(ctx context.Context, req *api.StartCrawlRequest) (*api.StartCrawlResponse, error) {
+	spec := jobSpec{
+		ID:         req.Id,
+		Seeds:      req.Seeds,
+		Sitemaps:   req.Sitemaps,
+		Workers:    int(req.Workers),
+		ObeyRobots: req.ObeyRobots,
+		Favicons:   req.Favicons,
+	}
+
+	id, err := s.jobs.start(spec)
+	if errors.Is(err, errJobRunning) || errors.Is(err, errJobExists) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &api.StartCrawlResponse{Id: id}, nil
+}
+
+func (s *grpcServer) GetStatus(ctx context.Context, req *api.GetStatusRequest) (*api.CrawlStatus, error) {
+	st, err := s.jobs.status(req.Id)
+	if err != nil {
+		return nil, jobStatusError(err)
+	}
+
+	resp := &api.CrawlStatus{
+		Id:            st.ID,
+		State:         st.State,
+		Queued:        int64(st.Queued),
+		Visited:       int64(st.Visited),
+		Images:        int64(st.Images),
+		ActiveWorkers: int64(st.ActiveWorkers),
+		Paused:        st.Paused,
+	}
+	if st.Started != nil {
+		resp.Started = timestamppb.New(*st.Started)
+	}
+	if st.Finished != nil {
+		resp.Finished = timestamppb.New(*st.Finished)
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) StreamImages(req *api.StreamImagesRequest, stream api.CrawlService_StreamImagesServer) error {
+	c := crawler.NewJob(s.jobs.pool, req.Id)
+
+	// images are numbered in the order found, so only the last one sent
+	// need be remembered
+	var cursor int64
+	for {
+		st, err := s.jobs.status(req.Id)
+		if err != nil {
+			return jobStatusError(err)
+		}
+		// checked before the pass so images found just before the job
+		// finishes aren't missed
+		stopped := st.State != jobRunning && st.State != jobCancelling
+
+		for {
+			urls, next, err := c.ImagesFoundAfter(cursor, crawler.DefaultScanCount)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			for _, url := range urls {
+				if err := stream.Send(&api.Image{Url: url}); err != nil {
+					return err
+				}
+			}
+			cursor = next
+			if len(urls) < crawler.DefaultScanCount {
+				break
+			}
+		}
+
+		if !req.Follow || stopped {
+			return nil
+		}
+
+		select {
+		case <-time.After(1 * time.Second):
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *grpcServer) CancelCrawl(ctx context.Context, req *api.CancelCrawlRequest) (*api.CancelCrawlResponse, error) {
+	if err := s.jobs.cancel(req.Id); err != nil {
+		return nil, jobStatusError(err)
+	}
+	return &api.CancelCrawlResponse{}, nil
+}
+
+func jobStatusError(err error) error {
+	if errors.Is(err, errUnknownJob) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// serveGRPC runs the gRPC API until ctx is cancelled
+func serveGRPC(ctx context.Context, addr string, jobs *jobManager, logger *slog.Logger) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer()
+	api.RegisterCrawlServiceServer(srv, &grpcServer{jobs: jobs})
+
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+
+	logger.Info("serving crawl gRPC API", "addr", addr)
+	return srv.Serve(lis)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/daveagill/go-imgcrawler/api"
+)
+
+// newTestGRPC serves the gRPC API in memory over a job manager backed by
+// miniredis
+func newTestGRPC(t *testing.T) (api.CrawlServiceClient, *jobManager) {
+	t.Helper()
+	jobs := newTestJobs(t)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	api.RegisterCrawlServiceServer(srv, &grpcServer{jobs: jobs})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	conn, err := grpc.NewClient("passthrough:///bufconn", grpc.WithContextDialer(dial), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return api.NewCrawlServiceClient(conn), jobs
+}
+
+// streamImages reads every image a stream sends until it ends
+func streamImages(t *testing.T, client api.CrawlServiceClient, req *api.StreamImagesRequest) ([]string, error) {
+	t.Helper()
+	stream, err := client.StreamImages(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := []string{}
+	for {
+		img, err := stream.Recv()
+		if err == io.EOF {
+			return urls, nil
+		}
+		if err != nil {
+			return urls, err
+		}
+		urls = append(urls, img.Url)
+	}
+}
+
+func TestGRPCStartAndStatus(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png">`))
+	}))
+	defer site.Close()
+	client, jobs := newTestGRPC(t)
+	ctx := context.Background()
+
+	resp, err := client.StartCrawl(ctx, &api.StartCrawlRequest{Id: "site", Seeds: []string{site.URL}})
+	if err != nil || resp.Id != "site" {
+		t.Fatalf("StartCrawl = %v, %v", resp, err)
+	}
+	_, err = client.StartCrawl(ctx, &api.StartCrawlRequest{Id: "site", Seeds: []string{site.URL}})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("StartCrawl again = %v, want AlreadyExists", err)
+	}
+	_, err = client.StartCrawl(ctx, &api.StartCrawlRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("StartCrawl without seeds = %v, want InvalidArgument", err)
+	}
+	jobs.wait()
+
+	st, err := client.GetStatus(ctx, &api.GetStatusRequest{Id: "site"})
+	if err != nil || st.State != jobFinished || st.Visited != 1 || st.Images != 1 || st.Finished == nil {
+		t.Errorf("GetStatus = %v, %v, want the job finished with 1 page and image", st, err)
+	}
+	if _, err := client.GetStatus(ctx, &api.GetStatusRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetStatus of an unknown job = %v, want NotFound", err)
+	}
+	if _, err := client.CancelCrawl(ctx, &api.CancelCrawlRequest{Id: "site"}); status.Code(err) != codes.NotFound {
+		t.Errorf("CancelCrawl of a finished job = %v, want NotFound", err)
+	}
+}
+
+func TestGRPCStreamImages(t *testing.T) {
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<img src="/a.png"><img src="/b.png"><a href="/more">more</a>`)
+			return
+		}
+		// held until the stream has sent the first page's images
+		<-release
+		fmt.Fprint(w, `<img src="/b.png"><img src="/c.png">`)
+	}))
+	defer site.Close()
+	client, jobs := newTestGRPC(t)
+
+	if _, err := client.StartCrawl(context.Background(), &api.StartCrawlRequest{Id: "site", Seeds: []string{site.URL}}); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := client.StreamImages(context.Background(), &api.StreamImagesRequest{Id: "site", Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{}
+	for len(urls) < 2 {
+		img, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, img.Url)
+	}
+	close(release)
+	for {
+		img, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, img.Url)
+	}
+
+	want := fmt.Sprint([]string{site.URL + "/a.png", site.URL + "/b.png", site.URL + "/c.png"})
+	if fmt.Sprint(urls) != want {
+		t.Errorf("followed images %v, want each once in the order found %v", urls, want)
+	}
+
+	// once finished, the stream sends them all and ends
+	jobs.wait()
+	if urls, err := streamImages(t, client, &api.StreamImagesRequest{Id: "site"}); err != nil || fmt.Sprint(urls) != want {
+		t.Errorf("streamed %v, %v, want %v", urls, err, want)
+	}
+	if _, err := streamImages(t, client, &api.StreamImagesRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("streaming an unknown job = %v, want NotFound", err)
+	}
+}
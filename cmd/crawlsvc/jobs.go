@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// jobsCmd lists, inspects and deletes namespaced crawl jobs
+func jobsCmd(args []string) {
+	usage := "usage: crawlsvc jobs list|inspect|delete [flags]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("crawlsvc jobs "+action, flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.Parse(args[1:])
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	switch action {
+	case "list":
+		ids, err := crawler.ListJobs(pool)
+		exitOnError(err)
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+
+	case "inspect":
+		requireJob(redisOpts)
+		info, err := crawler.InspectJob(pool, redisOpts.job)
+		exitOnError(err)
+		fmt.Println("Job:", info.ID)
+		fmt.Println("Queued:", info.Queued)
+		fmt.Println("Visited:", info.Visited)
+		fmt.Println("Images:", info.Images)
+		fmt.Println("Active Workers:", info.ActiveWorkers)
+		fmt.Println("Paused:", info.Paused)
+
+	case "delete":
+		requireJob(redisOpts)
+		exitOnError(crawler.DeleteJob(pool, redisOpts.job))
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func requireJob(opts *redisOptions) {
+	if opts.job == "" {
+		fmt.Fprintln(os.Stderr, "-job parameter is required")
+		os.Exit(2)
+	}
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// lookupCmd prints everything known about a visited page, or the pages an
+// image was found on
+func lookupCmd(args []string) {
+	var page, image, linksTo string
+
+	fs := flag.NewFlagSet("crawlsvc lookup", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.StringVar(&page, "page", "", "The URL of a page to look up")
+	fs.StringVar(&image, "image", "", "The URL of an image to list the pages of")
+	fs.StringVar(&linksTo, "linksTo", "", "The URL of a page to list the links to, with their anchor text")
+	fs.Parse(args)
+
+	if page == "" && image == "" && linksTo == "" {
+		fmt.Fprintln(os.Stderr, "-page, -image or -linksTo parameter is required")
+		os.Exit(2)
+	}
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	c := redisOpts.crawler(pool)
+
+	if image != "" {
+		pages, err := c.ImagePages(image)
+		exitOnError(err)
+		if len(pages) == 0 {
+			fmt.Println("Not found")
+			os.Exit(1)
+		}
+
+		fmt.Printf("Found On (%d):\n", len(pages))
+		for _, p := range pages {
+			fmt.Println(" ", p)
+		}
+		return
+	}
+
+	if linksTo != "" {
+		links, err := c.LinksTo(linksTo)
+		exitOnError(err)
+		if len(links) == 0 {
+			fmt.Println("Not found")
+			os.Exit(1)
+		}
+
+		fmt.Printf("Linked From (%d):\n", len(links))
+		for _, l := range links {
+			fmt.Println(" ", l.From, anchorContext(l.LinkAnchor))
+		}
+		return
+	}
+
+	rec, found, err := c.LookupPage(page)
+	exitOnError(err)
+	if !found {
+		visited, err := c.Visited(page)
+		exitOnError(err)
+		if visited {
+			fmt.Println("Visited, but no record was kept for this page")
+		} else {
+			fmt.Println("Not visited")
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println("URL:", rec.URL)
+	if rec.Title != "" {
+		fmt.Println("Title:", rec.Title)
+	}
+	if rec.Error != "" {
+		fmt.Println("Error:", rec.Error)
+	}
+	if rec.Status != 0 {
+		fmt.Println("Status:", rec.Status)
+	}
+	if !rec.FetchedAt.IsZero() {
+		fmt.Println("Fetched At:", rec.FetchedAt.Format(time.RFC3339))
+	}
+	if rec.Latency > 0 {
+		fmt.Println("Latency:", rec.Latency.Round(time.Millisecond))
+	}
+	if rec.Duration > 0 {
+		fmt.Println("Duration:", rec.Duration.Round(time.Millisecond))
+	}
+	fmt.Println("Depth:", rec.Depth)
+	if rec.Parent != "" {
+		fmt.Println("Parent:", rec.Parent)
+	}
+	if !rec.DiscoveredAt.IsZero() {
+		fmt.Println("Discovered At:", rec.DiscoveredAt.Format(time.RFC3339))
+	}
+	for _, r := range rec.Redirects {
+		fmt.Println("Redirected:", r.Status, r.URL)
+	}
+	if rec.FinalURL != "" {
+		fmt.Println("Final URL:", rec.FinalURL)
+	}
+	if rec.Canonical != "" {
+		fmt.Println("Canonical:", rec.Canonical)
+	}
+	if rec.Simhash != "" {
+		fmt.Println("Simhash:", rec.Simhash)
+	}
+	if rec.Language != "" {
+		fmt.Println("Language:", rec.Language)
+	}
+	for _, alt := range rec.Alternates {
+		fmt.Printf("Alternate (%s): %s\n", alt.Lang, alt.URL)
+	}
+	if rec.AMP != "" {
+		merged := ""
+		if rec.AMPMerged {
+			merged = " (crawled with the page)"
+		}
+		fmt.Println("AMP:", rec.AMP+merged)
+	}
+	if rec.DuplicateOf != "" {
+		fmt.Println("Duplicate Of:", rec.DuplicateOf, "(crawled through another URL)")
+	}
+	if rec.NearDuplicateOf != "" {
+		fmt.Println("Near Duplicate Of:", rec.NearDuplicateOf, "(links not followed)")
+	}
+	if rec.ExternalRedirect != "" {
+		fmt.Println("External Redirect:", rec.ExternalRedirect, rec.RedirectTarget)
+	}
+	if rec.Skipped {
+		fmt.Println("Skipped: discarded by a hook, e.g. the reputation check")
+	}
+
+	fmt.Println("Outlinks:", rec.Outlinks, "(distinct pages linked to, on any host)")
+	fmt.Printf("Links (%d):\n", len(rec.Links))
+	if len(rec.Anchors) == len(rec.Links) {
+		for _, a := range rec.Anchors {
+			fmt.Println(" ", a.URL, anchorContext(a))
+		}
+	} else {
+		// recorded before anchors were
+		for _, l := range rec.Links {
+			fmt.Println(" ", l)
+		}
+	}
+	if len(rec.Frames) > 0 {
+		fmt.Printf("Frames (%d):\n", len(rec.Frames))
+		for _, f := range rec.Frames {
+			fmt.Println(" ", f)
+		}
+	}
+	fmt.Printf("Images (%d):\n", len(rec.Images))
+	for _, img := range rec.Images {
+		fmt.Println(" ", img)
+	}
+	if len(rec.Media) > 0 {
+		fmt.Printf("Media (%d):\n", len(rec.Media))
+		for _, m := range rec.Media {
+			fmt.Println(" ", m)
+		}
+	}
+	if len(rec.InlineSVGs) > 0 {
+		fmt.Printf("Inline SVGs (%d):\n", len(rec.InlineSVGs))
+		for _, ref := range rec.InlineSVGs {
+			fmt.Println(" ", ref)
+		}
+	}
+	if len(rec.DataImages) > 0 {
+		fmt.Printf("Data URI images (%d):\n", len(rec.DataImages))
+		for _, id := range rec.DataImages {
+			fmt.Println(" ", id)
+		}
+	}
+}
+
+// anchorContext describes a link's anchor text and the heading it's under
+func anchorContext(a crawler.LinkAnchor) string {
+	context := fmt.Sprintf("%q", a.Text)
+	if a.Heading != "" {
+		context += fmt.Sprintf(" under %q", a.Heading)
+	}
+	return context
+}
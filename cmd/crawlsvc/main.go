@@ -1,57 +1,344 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/daveagill/go-imgcrawler/crawler"
 )
 
+// commands are the crawlsvc subcommands, crawling is the default when no
+// subcommand is given
+var commands = map[string]func(args []string){
+	"migrate":       migrateCmd,
+	"check-images":  checkImagesCmd,
+	"jobs":          jobsCmd,
+	"estimate":      estimateCmd,
+	"find-similar":  findSimilarCmd,
+	"lookup":        lookupCmd,
+	"pause":         pauseCmd,
+	"resume":        resumeCmd,
+	"replay":        replayCmd,
+	"report":        reportCmd,
+	"serve-results": serveResultsCmd,
+	"status":        statusCmd,
+	"init":          initCmd,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
+	if err := crawlCmd(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// crawlCmd crawls, returning an error once everything it opened is closed,
+// so main exits only after the deferred closes have run
+func crawlCmd(args []string) error {
 	var (
-		url          string
-		redisAddr    string
-		redisNetwork string
-		workersN     int
+		configPath  string
+		writeConf   string
+		presetNames string
+		lock        bool
+		workersN    int
+		drain       time.Duration
+		showProg    bool
+		metricsAddr string
+		debugAddr   string
+		otlpAddr    string
+		traceSample float64
+		serve       string
+		grpcAddr    string
+		interval    time.Duration
+		cronExpr    string
 	)
 
-	flag.StringVar(&url, "url", "", "Required. The seed URL to crawl from")
-	flag.StringVar(&redisAddr, "redisAddr", "", "Required. The redis host address and port")
-	flag.StringVar(&redisNetwork, "redisNetwork", "tcp", "The redis network")
-	flag.IntVar(&workersN, "workers", 1, "The number of concurrent workers")
-	flag.Parse()
+	fs := flag.NewFlagSet("crawlsvc", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	logOpts := addLogFlags(fs)
+	seedOpts := addSeedFlags(fs)
+	scopeOpts := addScopeFlags(fs)
+	fetchOpts := addFetchFlags(fs)
+	extractOpts := addExtractFlags(fs)
+	renderOpts := addRenderFlags(fs)
+	sinkOpts := addSinkFlags(fs)
+	stateOpts := addStateFlags(fs)
+	outputOpts := addOutputFlags(fs)
+
+	fs.StringVar(&configPath, "config", "", "A YAML or TOML file of options, named as the flags are, which flags given on the command line override")
+	fs.StringVar(&writeConf, "writeConfig", "", "Write the options given as a commented config file, YAML or TOML by its extension, then exit")
+	fs.StringVar(&presetNames, "preset", "", "Comma-separated presets to start from: polite, dedupe and metadata, which flags set explicitly override")
+	fs.BoolVar(&lock, "lock", false, "Fail if the job is already running with different flags, or join it as more workers if they match")
+	fs.IntVar(&workersN, "workers", 1, "The number of concurrent workers")
+	fs.DurationVar(&drain, "drainTimeout", 30*time.Second, "On shutdown, how long to let in-flight pages finish before requeueing them")
+	fs.BoolVar(&showProg, "progress", false, "Show the crawl's progress, updated in place, instead of logging")
+	fs.StringVar(&metricsAddr, "metricsAddr", "", "Serve Prometheus metrics at /metrics on this address, e.g. :9090")
+	fs.StringVar(&debugAddr, "debugAddr", "", "Serve pprof, expvar and a snapshot of the crawls and the pages in flight under /debug/ on this address, e.g. localhost:6060, never a public one")
+	fs.StringVar(&otlpAddr, "otlpEndpoint", "", "Trace each page crawled, exporting the spans over OTLP/gRPC to this endpoint, e.g. http://localhost:4317")
+	fs.Float64Var(&traceSample, "traceSample", 1, "The share of pages traced with -otlpEndpoint, from 0 to 1")
+	fs.StringVar(&serve, "serve", "", "Run as a long-running crawl service, serving the HTTP API on this address, e.g. :8080")
+	fs.StringVar(&grpcAddr, "grpc", "", "Run as a long-running crawl service, serving the gRPC API on this address, e.g. :9000")
+	fs.DurationVar(&interval, "every", 0, "Re-crawl the seeds at this interval, e.g. 6h, reporting what changed after each run")
+	fs.StringVar(&cronExpr, "cron", "", "Re-crawl the seeds on this cron schedule, e.g. \"0 */6 * * *\", reporting what changed after each run")
+	fs.Parse(args)
 
-	if url == "" {
-		fmt.Fprintln(os.Stderr, "-url parameter is required")
-		os.Exit(2)
+	if configPath != "" {
+		if err := applyConfig(fs, configPath); err != nil {
+			exitUsage("%v", err)
+		}
 	}
+	if writeConf != "" {
+		return writeConfig(fs, writeConf)
+	}
+
+	outputOpts.validate()
+	seedOpts.validate()
+	scopeOpts.validate()
+	fetchOpts.validate(seedOpts.urls)
+	extractOpts.validate()
+	sinkOpts.validate()
+	stateOpts.validate()
 
-	if redisAddr == "" {
-		fmt.Fprintln(os.Stderr, "-redisAddr parameter is required")
-		os.Exit(2)
+	service := serve != "" || grpcAddr != ""
+	if !seedOpts.given() && !seedOpts.resume && !service {
+		exitUsage("-url or -seedFile parameter is required")
 	}
 
-	// create Redis connection pool
-	pool := &redis.Pool{
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial(redisNetwork, redisAddr)
-		},
+	sched, err := newSchedule(interval, cronExpr)
+	if err != nil {
+		exitUsage("%v", err)
+	}
+	if sched != nil && (!seedOpts.given() || seedOpts.resume || service) {
+		exitUsage("-every and -cron need -url or -seedFile, and can't be used with -resume, -serve or -grpc")
+	}
+	if sched != nil && seedOpts.file == "-" {
+		exitUsage("-every and -cron can't read -seedFile from stdin, which can only be read once")
+	}
+
+	presets, err := crawler.ParsePresets(presetNames)
+	if err != nil {
+		exitUsage("%v", err)
+	}
+
+	logger := logOpts.logger()
+	if showProg {
+		// log lines would scroll the display away
+		logger = slog.New(slog.DiscardHandler)
+		slog.SetDefault(logger)
+	}
+
+	// a crawl's writes to un-namespaced keys would span cluster slots
+	if redisOpts.cluster != "" && redisOpts.job == "" && !service {
+		exitUsage("-job is required with -redisCluster")
+	}
+	if showProg && (service || sched != nil) {
+		exitUsage("-progress can't be used with -serve, -grpc, -every or -cron")
+	}
+	if metricsAddr != "" && service {
+		exitUsage("-metricsAddr is not supported with -serve or -grpc")
+	}
+	if traceSample < 0 || traceSample > 1 {
+		exitUsage("-traceSample must be from 0 to 1")
+	}
+	if stateOpts.legacyKeys && redisOpts.cluster != "" {
+		exitUsage("-legacyKeys can't be used with -redisCluster")
+	}
+	if stateOpts.bloomCap > 0 && sched != nil && !stateOpts.keepVisited {
+		exitUsage("-every and -cron need -keepVisited with -visitedBloom, to diff the pages of each run")
+	}
+	if lock && (service || redisOpts.job == "auto") {
+		exitUsage("-lock can't be used with -job auto, -serve or -grpc")
+	}
+
+	var tracer *sdktrace.TracerProvider
+	if otlpAddr != "" {
+		if tracer, err = startTracing(otlpAddr, traceSample); err != nil {
+			return err
+		}
+		// flush the spans still batched on the way out
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tracer.Shutdown(ctx); err != nil {
+				logger.Warn("failed to flush traces", "err", err)
+			}
+		}()
+	}
+
+	defer renderOpts.close()
+	if err := renderOpts.open(); err != nil {
+		return err
+	}
+	defer sinkOpts.close()
+	if err := sinkOpts.open(); err != nil {
+		return err
 	}
+
+	// create Redis connection pool
+	pool := redisOpts.pool()
 	defer pool.Close()
 
+	configure := func(c *crawler.Crawler) {
+		c.Logger = logger
+		if tracer != nil {
+			c.TracerProvider = tracer
+		}
+		c.DrainTimeout = drain
+		scopeOpts.apply(c)
+		fetchOpts.apply(c)
+		extractOpts.apply(c)
+		renderOpts.apply(c)
+		sinkOpts.apply(c)
+		stateOpts.apply(c)
+
+		// presets fill in what's been left at its default
+		for _, p := range presets {
+			p(c)
+		}
+		if isFlagSet(fs, "hostConnections") {
+			c.MaxHostConnections = fetchOpts.hostConns
+		}
+		if isFlagSet(fs, "canonical") {
+			c.DedupeCanonical = scopeOpts.canonical
+		}
+		if isFlagSet(fs, "probeImages") {
+			c.ProbeImages = extractOpts.probeImages
+		}
+	}
+
+	// serve the APIs, each request starting its own job
+	if service {
+		ctx := shutdownContext(logger, drain)
+		jobs := newJobManager(ctx, pool, logger, configure)
+		if debugAddr != "" {
+			go serveDebug(debugAddr, jobs.running, logger)
+		}
+
+		errs := make(chan error, 2)
+		servers := 0
+		if serve != "" {
+			servers++
+			go func() { errs <- serveAPI(ctx, serve, jobs, logger) }()
+		}
+		if grpcAddr != "" {
+			servers++
+			go func() { errs <- serveGRPC(ctx, grpcAddr, jobs, logger) }()
+		}
+		for i := 0; i < servers; i++ {
+			if err := <-errs; err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("failed to serve API: %w", err)
+			}
+		}
+
+		// let running jobs drain before exiting
+		jobs.wait()
+		return nil
+	}
+
 	// perform the crawling
-	c := crawler.New(pool)
-	c.Seed(url)
-	c.RunN(workersN)
+	if redisOpts.job == "auto" {
+		redisOpts.job = crawler.NewJobID()
+	}
+	c := redisOpts.crawler(pool)
+	if c.JobID != "" {
+		logger.Info("crawling as job", "job", c.JobID)
+	}
+	configure(c)
+	if lock {
+		if err := c.Lock(configFingerprint(fs)); err != nil {
+			return err
+		}
+		defer c.Unlock()
+	}
+	if metricsAddr != "" {
+		if err := c.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+			return fmt.Errorf("failed to register metrics: %w", err)
+		}
+		go serveMetrics(metricsAddr, logger)
+	}
+	if debugAddr != "" {
+		go serveDebug(debugAddr, func() []*crawler.Crawler { return []*crawler.Crawler{c} }, logger)
+	}
 
-	// report some information about the crawl (URLs visited and <img> tags encountered)
-	imgSrcs, _ := redis.Strings(pool.Get().Do("SMEMBERS", c.KeyImageSrcs))
-	hrefs, _ := redis.Strings(pool.Get().Do("SMEMBERS", c.KeyVisitedHREFs))
+	// re-crawl on a schedule, revalidating rather than re-downloading pages
+	if sched != nil {
+		if !isFlagSet(fs, "conditionalGet") {
+			c.ConditionalGet = true
+		}
+		runScheduled(shutdownContext(logger, drain), c, sched, func() { seedOpts.seed(c) }, workersN, logger)
+		return nil
+	}
 
-	fmt.Println("Crawling Complete")
-	fmt.Println("Visisted HREFS:", hrefs)
-	fmt.Println("Found Images:", imgSrcs)
+	if seedOpts.resume {
+		// carry on from the stored queue, even if the crawl was left paused
+		if err := c.Resume(); err != nil {
+			return fmt.Errorf("failed to resume: %w", err)
+		}
+	} else {
+		seedOpts.seed(c)
+	}
+	var prog *progress
+	if showProg {
+		prog = startProgress(c, os.Stderr, time.Second)
+	}
+	c.RunNContext(shutdownContext(logger, drain), workersN)
+	prog.stop()
+
+	return outputOpts.export(c)
+}
+
+// shutdownContext is cancelled by SIGINT/SIGTERM, after which default signal
+// handling is restored so a second signal kills the process immediately
+func shutdownContext(logger *slog.Logger, drain time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		signal.Reset(os.Interrupt, syscall.SIGTERM)
+		logger.Info("shutting down, draining in-flight pages", "drainTimeout", drain)
+		cancel()
+	}()
+
+	return ctx
+}
+
+func serveMetrics(addr string, logger *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	logger.Error("metrics server stopped", "err", http.ListenAndServe(addr, mux))
+}
+
+// seedFromFile queues the seeds listed in a file, one per line, or on stdin
+// if the path is -
+func seedFromFile(c *crawler.Crawler, path string) (int, error) {
+	if path == "-" {
+		return c.SeedFromReader(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return c.SeedFromReader(f)
 }
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+var (
+	errUnknownJob = errors.New("unknown job")
+	errJobRunning = errors.New("job is already running")
+	errJobExists  = errors.New("job already exists, start a new one instead")
+	errNoSeeds    = errors.New("at least one seed or sitemap is required")
+)
+
+// Job states reported by the job manager
+const (
+	jobRunning    = "running"
+	jobCancelling = "cancelling"
+	jobFinished   = "finished"
+	jobStored     = "stored" // exists in Redis but wasn't run by this process
+)
+
+// finishedJobTTL is how long a finished job is reported as such, after
+// which it's forgotten and reported as stored
+const finishedJobTTL = time.Hour
+
+// jobSpec describes a crawl to start
+type jobSpec struct {
+	ID         string   `json:"id,omitempty"`
+	Seeds      []string `json:"seeds"`
+	Sitemaps   []string `json:"sitemaps,omitempty"`
+	Workers    int      `json:"workers,omitempty"`
+	ObeyRobots *bool    `json:"obeyRobots,omitempty"`
+	Favicons   bool     `json:"favicons,omitempty"`
+}
+
+// jobStatus is a job's progress and lifecycle
+type jobStatus struct {
+	ID             string     `json:"id"`
+	State          string     `json:"state"`
+	Started        *time.Time `json:"started,omitempty"`
+	Finished       *time.Time `json:"finished,omitempty"`
+	Queued         int        `json:"queued"`
+	Visited        int        `json:"visited"`
+	Images         int        `json:"images"`
+	ActiveWorkers  int        `json:"activeWorkers"`
+	Paused         bool       `json:"paused"`
+	PagesPerMinute float64    `json:"pagesPerMinute"`
+	ErrorRate      float64    `json:"errorRate"`
+	StaleWorkers   int        `json:"staleWorkers"`
+
+	// Failed counts the pages that failed by class: 4xx, 5xx or error
+	Failed map[string]int `json:"failed,omitempty"`
+}
+
+// jobListing is a job as listed, without its progress
+type jobListing struct {
+	ID       string     `json:"id"`
+	State    string     `json:"state"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+type runningJob struct {
+	crawler  *crawler.Crawler
+	cancel   context.CancelFunc
+	state    string
+	started  time.Time
+	finished time.Time
+}
+
+// jobManager runs crawl jobs in the background for the long-running service
+// modes, every job it starts is namespaced under its job ID
+type jobManager struct {
+	ctx       context.Context
+	pool      *redis.Pool
+	logger    *slog.Logger
+	configure func(*crawler.Crawler) // applies the service-wide flags
+
+	mu   sync.Mutex
+	jobs map[string]*runningJob
+	wg   sync.WaitGroup
+}
+
+// newJobManager creates a job manager whose jobs are all cancelled with ctx
+func newJobManager(ctx context.Context, pool *redis.Pool, logger *slog.Logger, configure func(*crawler.Crawler)) *jobManager {
+	return &jobManager{
+		ctx:       ctx,
+		pool:      pool,
+		logger:    logger,
+		configure: configure,
+		jobs:      map[string]*runningJob{},
+	}
+}
+
+// start seeds and runs a new job, returning its ID. Jobs already stored in
+// Redis, by this process or another, aren't run again.
+func (m *jobManager) start(spec jobSpec) (string, error) {
+	if len(spec.Seeds) == 0 && len(spec.Sitemaps) == 0 {
+		return "", errNoSeeds
+	}
+
+	id := spec.ID
+	if id == "" {
+		id = crawler.NewJobID()
+	}
+	if err := crawler.ValidateJobID(id); err != nil {
+		return "", err
+	}
+
+	workers := spec.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	c := crawler.NewJob(m.pool, id)
+	m.configure(c)
+	c.Logger = m.logger.With("job", id)
+	c.FingerprintFavicons = c.FingerprintFavicons || spec.Favicons
+	if spec.ObeyRobots != nil && !*spec.ObeyRobots {
+		c.Politeness = crawler.Politeness{}
+	}
+
+	// the ID is claimed before going to Redis, so the same job can't be
+	// started twice at once, but without holding the lock while there
+	m.mu.Lock()
+	if j, ok := m.jobs[id]; ok {
+		m.mu.Unlock()
+		if j.state == jobFinished {
+			return "", errJobExists
+		}
+		return "", errJobRunning
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	job := &runningJob{crawler: c, cancel: cancel, state: jobRunning, started: time.Now().UTC()}
+	m.jobs[id] = job
+	m.wg.Add(1)
+	m.mu.Unlock()
+
+	exists, err := m.exists(id)
+	if err == nil && exists {
+		err = errJobExists
+	}
+	if err != nil {
+		m.mu.Lock()
+		delete(m.jobs, id)
+		m.mu.Unlock()
+		cancel()
+		m.wg.Done()
+		return "", err
+	}
+
+	c.Seed(spec.Seeds...)
+
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+
+		// sitemaps can be slow to fetch so are seeded in the background
+		for _, url := range spec.Sitemaps {
+			if err := c.SeedFromSitemap(url); err != nil {
+				c.Logger.Error("failed to seed from sitemap", "sitemap", url, "err", err)
+			}
+		}
+
+		c.Logger.Info("job started", "workers", workers)
+		c.RunNContext(ctx, workers)
+		c.Logger.Info("job finished")
+
+		m.mu.Lock()
+		job.state = jobFinished
+		job.finished = time.Now().UTC()
+		m.mu.Unlock()
+
+		time.AfterFunc(finishedJobTTL, func() { m.forget(id, job) })
+	}()
+
+	return id, nil
+}
+
+// forget drops a finished job, unless it's since been replaced
+func (m *jobManager) forget(id string, job *runningJob) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.jobs[id] == job {
+		delete(m.jobs, id)
+	}
+}
+
+// local reports the state of a job as known to this process, and whether
+// it was started by this process
+func (m *jobManager) local(id string) (jobStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	status := jobStatus{ID: id, State: jobStored}
+	if ok {
+		status.State = job.state
+		started := job.started
+		status.Started = &started
+		if !job.finished.IsZero() {
+			finished := job.finished
+			status.Finished = &finished
+		}
+	}
+	return status, ok
+}
+
+// list reports every job stored in Redis or started by this process, by
+// ID, without their progress
+func (m *jobManager) list() ([]jobListing, error) {
+	ids, err := crawler.ListJobs(m.pool)
+	if err != nil {
+		return nil, err
+	}
+
+	// jobs only just started may not be stored yet
+	m.mu.Lock()
+	for id := range m.jobs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.Unlock()
+	sort.Strings(ids)
+
+	jobs := make([]jobListing, 0, len(ids))
+	for _, id := range ids {
+		status, _ := m.local(id)
+		jobs = append(jobs, jobListing{ID: id, State: status.State, Started: status.Started, Finished: status.Finished})
+	}
+	return jobs, nil
+}
+
+// status reports a job's progress, jobs not run by this process are still
+// reported if they exist in Redis
+func (m *jobManager) status(id string) (jobStatus, error) {
+	if err := crawler.ValidateJobID(id); err != nil {
+		return jobStatus{}, errUnknownJob
+	}
+
+	status, ok := m.local(id)
+	if !ok {
+		exists, err := m.exists(id)
+		if err != nil {
+			return status, err
+		}
+		if !exists {
+			return status, errUnknownJob
+		}
+	}
+
+	info, err := crawler.NewJob(m.pool, id).Status()
+	if err != nil {
+		return status, err
+	}
+	status.Queued = info.Queued
+	status.Visited = info.Visited
+	status.Images = info.Images
+	status.ActiveWorkers = info.ActiveWorkers
+	status.Paused = info.Paused
+	status.PagesPerMinute = info.PagesPerMinute
+	status.ErrorRate = info.ErrorRate
+	status.Failed = info.Failed
+	for _, hb := range info.Workers {
+		if hb.Stale {
+			status.StaleWorkers++
+		}
+	}
+
+	return status, nil
+}
+
+// cancel stops a running job, its workers drain the pages in hand first
+func (m *jobManager) cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok || job.state == jobFinished {
+		return errUnknownJob
+	}
+
+	job.state = jobCancelling
+	job.cancel()
+	return nil
+}
+
+// exists reports whether the job has ever been started
+func (m *jobManager) exists(id string) (bool, error) {
+	ids, err := crawler.ListJobs(m.pool)
+	if err != nil {
+		return false, err
+	}
+
+	for _, existing := range ids {
+		if existing == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// running returns the crawlers of the jobs not yet finished, by ID
+func (m *jobManager) running() []*crawler.Crawler {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	crawlers := []*crawler.Crawler{}
+	for _, job := range m.jobs {
+		if job.state != jobFinished {
+			crawlers = append(crawlers, job.crawler)
+		}
+	}
+	sort.Slice(crawlers, func(i, j int) bool {
+		return crawlers[i].JobID < crawlers[j].JobID
+	})
+	return crawlers
+}
+
+// wait blocks until every job has stopped
+func (m *jobManager) wait() {
+	m.wg.Wait()
+}
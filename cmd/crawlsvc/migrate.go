@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// migrateCmd converts a crawl stored in the v1 flat-set format
+func migrateCmd(args []string) {
+	var (
+		legacy = crawler.DefaultLegacyKeys
+		codec  string
+	)
+
+	fs := flag.NewFlagSet("crawlsvc migrate", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	logOpts := addLogFlags(fs)
+	fs.StringVar(&legacy.CrawlQ, "fromQueue", legacy.CrawlQ, "The v1 crawl queue key")
+	fs.StringVar(&legacy.VisitedHREFs, "fromVisited", legacy.VisitedHREFs, "The v1 visited URLs key")
+	fs.StringVar(&legacy.ImageSrcs, "fromImages", legacy.ImageSrcs, "The v1 image sources key")
+	fs.StringVar(&codec, "queueCodec", "json", "The crawl queue encoding to migrate to, json or msgpack")
+	fs.Parse(args)
+
+	queueCodec, err := newCodec(codec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger := logOpts.logger()
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	c := redisOpts.crawler(pool)
+	c.Logger = logger
+	c.Codec = queueCodec
+
+	stats, err := c.Migrate(legacy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migration failed:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Migration Complete")
+	fmt.Println("Queue entries converted:", stats.Entries)
+	fmt.Println("Visited URLs copied:", stats.Visited)
+	fmt.Println("Image URLs copied:", stats.Images)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// pauseCmd pauses every worker of a running crawl
+func pauseCmd(args []string) {
+	setPaused("pause", args, true)
+}
+
+// resumeCmd resumes a paused crawl
+func resumeCmd(args []string) {
+	setPaused("resume", args, false)
+}
+
+func setPaused(name string, args []string, paused bool) {
+	fs := flag.NewFlagSet("crawlsvc "+name, flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.Parse(args)
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	c := redisOpts.crawler(pool)
+
+	var err error
+	if paused {
+		err = c.Pause()
+	} else {
+		err = c.Resume()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, name, "failed:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// progressTopHosts is how many hosts the progress display lists
+const progressTopHosts = 5
+
+// progressHostsEvery is how many redraws pass between refreshes of the top
+// hosts, which take a round trip to Redis per host
+const progressHostsEvery = 10
+
+// progress redraws a summary of the crawl in place, for -progress
+type progress struct {
+	c        *crawler.Crawler
+	w        io.Writer
+	interval time.Duration
+
+	lines       int // drawn last time, to redraw over
+	started     time.Time
+	lastVisited int
+	lastAt      time.Time
+	pagesPerSec float64
+	hosts       []crawler.HostSummary
+	draws       int
+	finished    bool
+
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+// startProgress redraws the crawl's progress to w every interval until
+// stopped
+func startProgress(c *crawler.Crawler, w io.Writer, interval time.Duration) *progress {
+	p := &progress{
+		c:        c,
+		w:        w,
+		interval: interval,
+		started:  time.Now(),
+		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+		for {
+			p.draw()
+			select {
+			case <-time.After(p.interval):
+			case <-p.stopped:
+				p.draws, p.finished = 0, true // with the final top hosts
+				p.draw()
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// stop draws the progress a last time, a no-op on a nil progress
+func (p *progress) stop() {
+	if p == nil {
+		return
+	}
+	close(p.stopped)
+	<-p.done
+}
+
+func (p *progress) draw() {
+	status, err := p.c.Status()
+	if err != nil {
+		p.redraw([]string{"Status unavailable: " + err.Error()})
+		return
+	}
+
+	// pages/sec is smoothed over the last few redraws, across every process
+	now := time.Now()
+	if !p.lastAt.IsZero() {
+		rate := float64(status.Visited-p.lastVisited) / now.Sub(p.lastAt).Seconds()
+		p.pagesPerSec = 0.7*p.pagesPerSec + 0.3*max(rate, 0)
+	}
+	p.lastVisited, p.lastAt = status.Visited, now
+
+	if p.draws%progressHostsEvery == 0 {
+		if hosts, err := p.c.HostSummaries(); err == nil {
+			sort.Slice(hosts, func(i, j int) bool { return hosts[i].Pages > hosts[j].Pages })
+			p.hosts = hosts[:min(len(hosts), progressTopHosts)]
+		}
+	}
+	p.draws++
+
+	state := "crawling"
+	switch {
+	case p.finished:
+		state = "finished"
+	case status.Paused:
+		state = "paused"
+	}
+	lines := []string{
+		fmt.Sprintf("Elapsed: %-9s %s, %d active workers", now.Sub(p.started).Round(time.Second), state, status.ActiveWorkers),
+		fmt.Sprintf("Queued: %-10d Visited: %-10d Images: %d", status.Queued, status.Visited, status.Images),
+		fmt.Sprintf("Pages/sec: %-7.1f Errors: %.1f%% of pages in the last 5 minutes", p.pagesPerSec, status.ErrorRate*100),
+	}
+	if len(p.hosts) > 0 {
+		lines = append(lines, "Top hosts:")
+		for _, h := range p.hosts {
+			lines = append(lines, fmt.Sprintf("  %-40s %8d pages %8d images", h.Host, h.Pages, h.Images))
+		}
+	}
+	p.redraw(lines)
+}
+
+// redraw replaces the lines drawn last time
+func (p *progress) redraw(lines []string) {
+	b := strings.Builder{}
+	if p.lines > 0 {
+		// back to the start of the first line, clearing everything after
+		fmt.Fprintf(&b, "\033[%dF\033[J", p.lines)
+	}
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	io.WriteString(p.w, b.String())
+	p.lines = len(lines)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// replayCmd re-extracts archived page snapshots into the crawl results
+func replayCmd(args []string) {
+	var snapshotDir string
+
+	fs := flag.NewFlagSet("crawlsvc replay", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	logOpts := addLogFlags(fs)
+	fs.StringVar(&snapshotDir, "snapshotDir", "", "Required. The directory of snapshots written by a previous -snapshotDir crawl")
+	fs.Parse(args)
+
+	if snapshotDir == "" {
+		fmt.Fprintln(os.Stderr, "-snapshotDir parameter is required")
+		os.Exit(2)
+	}
+
+	logger := logOpts.logger()
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	c := redisOpts.crawler(pool)
+	c.Logger = logger
+	if err := c.Replay(snapshotDir); err != nil {
+		fmt.Fprintln(os.Stderr, "replay failed:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Replay Complete")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// schedule decides when the next run of a recurring crawl starts
+type schedule interface {
+	Next(time.Time) time.Time
+}
+
+// every runs at a fixed interval after each run starts
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// newSchedule parses the -every and -cron flags, returning nil if neither is
+// set
+func newSchedule(interval time.Duration, expr string) (schedule, error) {
+	switch {
+	case interval > 0 && expr != "":
+		return nil, fmt.Errorf("-every and -cron can't both be set")
+	case interval > 0:
+		return every(interval), nil
+	case expr != "":
+		sched, err := cron.ParseStandard(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -cron %q: %v", expr, err)
+		}
+		return sched, nil
+	}
+	return nil, nil
+}
+
+// runScheduled re-crawls from the seeds on the schedule until ctx is
+// cancelled, reporting what changed after each run
+func runScheduled(ctx context.Context, c *crawler.Crawler, sched schedule, seed func(), workersN int, logger *slog.Logger) {
+	for {
+		start := time.Now()
+
+		if err := c.Rotate(); err != nil {
+			logger.Error("failed to start scheduled crawl", "err", err)
+		} else {
+			logger.Info("starting scheduled crawl")
+			seed()
+			c.RunNContext(ctx, workersN)
+			if ctx.Err() != nil {
+				return
+			}
+			reportDiff(c, logger)
+		}
+
+		next := sched.Next(start)
+		logger.Info("next scheduled crawl", "at", next)
+		select {
+		case <-time.After(time.Until(next)):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reportDiff prints what the last run found that the one before didn't, and
+// vice versa
+func reportDiff(c *crawler.Crawler, logger *slog.Logger) {
+	diff, err := c.Diff()
+	if err != nil {
+		logger.Error("failed to compare with the previous crawl", "err", err)
+		return
+	}
+
+	fmt.Printf("Crawl at %s: %d new pages, %d removed pages, %d new images, %d removed images\n",
+		time.Now().Format(time.RFC3339), len(diff.NewPages), len(diff.RemovedPages), len(diff.NewImages), len(diff.RemovedImages))
+	for _, u := range diff.NewPages {
+		fmt.Println("+page ", u)
+	}
+	for _, u := range diff.RemovedPages {
+		fmt.Println("-page ", u)
+	}
+	for _, u := range diff.NewImages {
+		fmt.Println("+image", u)
+	}
+	for _, u := range diff.RemovedImages {
+		fmt.Println("-image", u)
+	}
+}
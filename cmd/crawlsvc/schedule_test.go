@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+func TestNewSchedule(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		interval time.Duration
+		expr     string
+		next     time.Time
+		err      string
+	}{
+		{0, "", time.Time{}, ""},
+		{time.Hour, "", start.Add(time.Hour), ""},
+		{0, "0 3 * * *", time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC), ""},
+		{0, "@hourly", time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC), ""},
+		{time.Hour, "@hourly", time.Time{}, "can't both be set"},
+		{0, "every tuesday", time.Time{}, `invalid -cron "every tuesday"`},
+	}
+	for _, tt := range tests {
+		sched, err := newSchedule(tt.interval, tt.expr)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("newSchedule(%v, %q) = %v, want an error containing %q", tt.interval, tt.expr, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newSchedule(%v, %q) = %v", tt.interval, tt.expr, err)
+			continue
+		}
+		if tt.next.IsZero() {
+			if sched != nil {
+				t.Errorf("newSchedule with neither flag = %v, want none", sched)
+			}
+			continue
+		}
+		if next := sched.Next(start); !next.Equal(tt.next) {
+			t.Errorf("newSchedule(%v, %q) next runs at %v, want %v", tt.interval, tt.expr, next, tt.next)
+		}
+	}
+}
+
+func TestRunScheduled(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png">`))
+	}))
+	defer site.Close()
+
+	mr := miniredis.RunT(t)
+	c := crawler.New(crawler.NewPool("tcp", mr.Addr()))
+	c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.TerminationGrace = 10 * time.Millisecond
+
+	// the second run is cancelled as it starts
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := 0
+	seed := func() {
+		if runs++; runs == 2 {
+			cancel()
+		}
+		c.Seed(site.URL)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runScheduled(ctx, c, every(10*time.Millisecond), seed, 1, c.Logger)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduled crawl didn't stop once cancelled")
+	}
+
+	if runs != 2 {
+		t.Errorf("ran %d times, want 2", runs)
+	}
+	// the first run was set aside for comparing with the next
+	if members, _ := mr.Members(c.KeyVisitedHREFs + ":prev"); len(members) != 1 {
+		t.Errorf("previous run's pages = %v, want the page crawled", members)
+	}
+}
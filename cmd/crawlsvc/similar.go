@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// findSimilarCmd lists crawled images that look like a local image file
+func findSimilarCmd(args []string) {
+	var (
+		image       string
+		maxDistance int
+	)
+
+	fs := flag.NewFlagSet("crawlsvc find-similar", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.StringVar(&image, "image", "", "Required. The path of the image to search for")
+	fs.IntVar(&maxDistance, "maxDistance", 10, "How many of the 64 hash bits may differ, lower is stricter")
+	fs.Parse(args)
+
+	if image == "" {
+		fmt.Fprintln(os.Stderr, "-image parameter is required")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(image)
+	exitOnError(err)
+	hash, err := crawler.ImageHash(f)
+	f.Close()
+	exitOnError(err)
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	matches, err := redisOpts.crawler(pool).FindSimilar(hash, maxDistance)
+	exitOnError(err)
+	for _, m := range matches {
+		fmt.Printf("%d\t%s\n", m.Distance, m.URL)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+// statusCmd prints a crawl's progress, or keeps it up to date with -watch
+func statusCmd(args []string) {
+	var watch bool
+	var interval time.Duration
+
+	fs := flag.NewFlagSet("crawlsvc status", flag.ExitOnError)
+	redisOpts := addRedisFlags(fs)
+	fs.BoolVar(&watch, "watch", false, "Keep the status up to date until interrupted")
+	fs.DurationVar(&interval, "interval", 2*time.Second, "How often -watch refreshes the status")
+	fs.Parse(args)
+
+	pool := redisOpts.pool()
+	defer pool.Close()
+
+	c := redisOpts.crawler(pool)
+
+	for {
+		status, err := c.Status()
+		exitOnError(err)
+		if watch {
+			// clear the screen and start again at the top
+			fmt.Print("\033[H\033[2J")
+		}
+		printStatus(os.Stdout, status)
+		if !watch {
+			return
+		}
+		fmt.Printf("\nUpdated %s, every %s\n", time.Now().Format(time.TimeOnly), interval)
+		time.Sleep(interval)
+	}
+}
+
+func printStatus(w io.Writer, s crawler.Status) {
+	if s.ID != "" {
+		fmt.Fprintln(w, "Job:", s.ID)
+	}
+	fmt.Fprintln(w, "Queued:", s.Queued)
+	fmt.Fprintln(w, "Visited:", s.Visited)
+	fmt.Fprintln(w, "Images:", s.Images)
+	fmt.Fprintln(w, "Active Workers:", s.ActiveWorkers)
+	stale := 0
+	for _, hb := range s.Workers {
+		if hb.Stale {
+			stale++
+		}
+	}
+	fmt.Fprintf(w, "Workers: %d live, %d stale\n", len(s.Workers)-stale, stale)
+	for _, hb := range s.Workers {
+		if hb.Stale {
+			fmt.Fprintf(w, "  stale: %s on %s (pid %d), last seen %s ago\n", hb.ID, hb.Host, hb.PID, time.Since(hb.Seen).Round(time.Second))
+		}
+	}
+	fmt.Fprintln(w, "Paused:", s.Paused)
+	fmt.Fprintf(w, "Pages/min: %.1f\n", s.PagesPerMinute)
+	fmt.Fprintf(w, "Errors: %.1f%%\n", s.ErrorRate*100)
+	fmt.Fprintf(w, "Failed: %d 4xx, %d 5xx, %d errors\n", s.Failed[crawler.FailedClientError], s.Failed[crawler.FailedServerError], s.Failed[crawler.FailedFetch])
+}
@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// ampImageTags are the AMP components that display an image from their src
+var ampImageTags = []string{"amp-img", "amp-anim"}
+
+// mergeAMP fetches the page's AMP variant and adds its images and links to
+// the page's own, returning false if it couldn't be fetched
+func (c *Crawler) mergeAMP(ctx context.Context, page *scrapeResult, logger *slog.Logger) bool {
+	logger.Debug("crawling AMP variant", "amp", page.amp)
+	amp := c.scrape(ctx, page.amp, logger)
+	if amp.err != nil || amp.status >= 400 {
+		logger.Warn("failed to crawl AMP variant", "amp", page.amp, "status", amp.status, "err", amp.err)
+		return false
+	}
+
+	for _, src := range amp.imgSrcs {
+		if !slices.Contains(page.imgSrcs, src) {
+			page.imgSrcs = append(page.imgSrcs, src)
+		}
+	}
+	for i, href := range amp.hrefs {
+		if !slices.Contains(page.hrefs, href) {
+			page.hrefs = append(page.hrefs, href)
+			if i < len(amp.anchors) {
+				page.anchors = append(page.anchors, amp.anchors[i])
+			}
+		}
+	}
+	for _, img := range amp.dataImages {
+		if !slices.ContainsFunc(page.dataImages, func(d dataImage) bool { return d.id == img.id }) {
+			page.dataImages = append(page.dataImages, img)
+		}
+	}
+	page.bytes += amp.bytes
+	return true
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExtractAMP(t *testing.T) {
+	c, _ := newTestCrawler(t)
+
+	body := `<link rel="amphtml" href="/article/amp?utm_source=x"><amp-img src="/a.jpg" width="1" height="1"></amp-img><amp-anim src="/b.gif"></amp-anim>`
+	c.QueryRules = DefaultQueryRules
+	page := c.extract("https://example.com/article", http.Header{}, strings.NewReader(body), c.Logger)
+	if page.amp != "https://example.com/article/amp" {
+		t.Errorf("amp = %q, want the AMP variant without tracking", page.amp)
+	}
+	if want := []string{"https://example.com/a.jpg", "https://example.com/b.gif"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("images = %v, want %v", page.imgSrcs, want)
+	}
+}
+
+func TestFollowAMP(t *testing.T) {
+	ampFetches := atomic.Int32{}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<link rel="amphtml" href="/amp"><img src="/hero.jpg"><a href="/amp">AMP version</a>`))
+		case "/amp":
+			ampFetches.Add(1)
+			w.Write([]byte(`<link rel="canonical" href="/"><amp-img src="/hero.jpg"></amp-img><amp-img src="/gallery.jpg"></amp-img><a href="/more">more</a>`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.FollowAMP = true
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	rec, _, _ := c.LookupPage(site.URL + "/")
+	if want := []string{site.URL + "/hero.jpg", site.URL + "/gallery.jpg"}; !slices.Equal(rec.Images, want) {
+		t.Errorf("images = %v, want %v", rec.Images, want)
+	}
+	if !rec.AMPMerged || rec.AMP != site.URL+"/amp" {
+		t.Errorf("amp = %q merged %v, want the AMP variant merged", rec.AMP, rec.AMPMerged)
+	}
+	if !slices.Contains(rec.Links, site.URL+"/more") {
+		t.Errorf("links = %v, want the AMP variant's too", rec.Links)
+	}
+	if n := ampFetches.Load(); n != 1 {
+		t.Errorf("AMP variant fetched %d times, want once", n)
+	}
+	if _, found, _ := c.LookupPage(site.URL + "/amp"); found {
+		t.Error("AMP variant recorded as a page of its own")
+	}
+}
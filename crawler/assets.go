@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+
+	neturl "net/url"
+)
+
+// AssetRecord is a script, stylesheet or other linked asset found by
+// CensusAssets
+type AssetRecord struct {
+	URL string `json:"url"`
+	// Kind is "script", or the rel of the <link> e.g. "stylesheet"
+	Kind string `json:"kind"`
+	// ThirdParty is set if the asset is served from a different site
+	// (registrable domain) to the first page it was found on
+	ThirdParty bool   `json:"thirdParty"`
+	PageURL    string `json:"pageURL"`
+	// Pages is how many pages load the asset, only populated by Assets
+	Pages int `json:"pages,omitempty"`
+}
+
+// asset is a linked asset as found in a page
+type asset struct {
+	url  string
+	kind string
+}
+
+// assetRels are the <link> rels that load a resource, rather than relate the
+// page to another one
+var assetRels = []string{"stylesheet", "preload", "modulepreload", "prefetch", "icon", "apple-touch-icon", "manifest"}
+
+// matchAsset returns the asset a tag loads, if any
+func matchAsset(tok *html.Token) (asset, bool) {
+	if isScript, src := matchTag(tok, "script", "src"); isScript && src != "" {
+		return asset{url: src, kind: "script"}, true
+	}
+
+	if isLink, href := matchTag(tok, "link", "href"); isLink && href != "" {
+		rel := getAttr(tok, "rel")
+		for _, kind := range assetRels {
+			if hasToken(rel, kind) {
+				return asset{url: href, kind: kind}, true
+			}
+		}
+	}
+	return asset{}, false
+}
+
+// resolveAssets makes asset URLs absolute, dropping any that are invalid
+func resolveAssets(base string, assets []asset) []asset {
+	resolved := []asset{}
+	for _, a := range assets {
+		if urls := resolveURLs(base, []string{a.url}); len(urls) == 1 {
+			resolved = append(resolved, asset{url: urls[0], kind: a.kind})
+		}
+	}
+	return resolved
+}
+
+// isThirdParty reports whether two URLs belong to different sites
+func isThirdParty(pageURL string, assetURL string) bool {
+	return site(pageURL) != site(assetURL)
+}
+
+// site is the registrable domain of a URL, e.g. example.co.uk
+func site(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+func (c *Crawler) assetPagesKey() string {
+	return c.KeyAssets + ":pages"
+}
+
+// recordAssets adds the page's assets to the batch, each is described by the
+// first page it's found on and counted once per page
+func (c *Crawler) recordAssets(b *batch, pageURL string, assets []asset) {
+	seen := map[string]bool{}
+	for _, a := range assets {
+		if seen[a.url] {
+			continue
+		}
+		seen[a.url] = true
+
+		record, err := json.Marshal(AssetRecord{
+			URL:        a.url,
+			Kind:       a.kind,
+			ThirdParty: isThirdParty(pageURL, a.url),
+			PageURL:    pageURL,
+		})
+		if err != nil {
+			continue
+		}
+		b.add("HSETNX", c.KeyAssets, a.url, record)
+		b.add("HINCRBY", c.assetPagesKey(), a.url, 1)
+	}
+}
+
+// Assets returns every asset found by CensusAssets, the most widely loaded
+// first
+func (c *Crawler) Assets() ([]AssetRecord, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	records, err := redis.StringMap(conn.Do("HGETALL", c.KeyAssets))
+	if err != nil {
+		return nil, err
+	}
+	pages, err := redis.IntMap(conn.Do("HGETALL", c.assetPagesKey()))
+	if err != nil {
+		return nil, err
+	}
+
+	assets := []AssetRecord{}
+	for url, data := range records {
+		a := AssetRecord{}
+		if err := json.Unmarshal([]byte(data), &a); err != nil {
+			a = AssetRecord{URL: url}
+		}
+		a.Pages = pages[url]
+		assets = append(assets, a)
+	}
+
+	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Pages != assets[j].Pages {
+			return assets[i].Pages > assets[j].Pages
+		}
+		return assets[i].URL < assets[j].URL
+	})
+	return assets, nil
+}
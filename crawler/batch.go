@@ -0,0 +1,224 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// command is a single deferred Redis write
+type command struct {
+	name   string
+	args   []interface{}
+	script *redis.Script // if set, the command runs the script with args
+}
+
+// batch collects the writes for a page so they can be sent in one round trip,
+// or held back while Redis is unreachable
+type batch []command
+
+func (b *batch) add(name string, args ...interface{}) {
+	*b = append(*b, command{name: name, args: args})
+}
+
+// addScript adds a script run, sent by its hash with EVALSHA
+func (b *batch) addScript(script *redis.Script, keysAndArgs ...interface{}) {
+	*b = append(*b, command{name: "EVALSHA", args: keysAndArgs, script: script})
+}
+
+func (cmd command) send(conn redis.Conn) error {
+	if cmd.script != nil {
+		return cmd.script.SendHash(conn, cmd.args...)
+	}
+	return conn.Send(cmd.name, cmd.args...)
+}
+
+// transaction wraps the batch in MULTI/EXEC, so its writes are applied all
+// together or not at all, still in one round trip
+func (b batch) transaction() batch {
+	if len(b) < 2 {
+		return b
+	}
+	tx := make(batch, 0, len(b)+2)
+	tx = append(tx, command{name: "MULTI"})
+	tx = append(tx, b...)
+	return append(tx, command{name: "EXEC"})
+}
+
+// exec pipelines every command in the batch, returning the first error
+func (b batch) exec(conn redis.Conn) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	for _, cmd := range b {
+		if err := cmd.send(conn); err != nil {
+			return err
+		}
+	}
+
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return err
+	}
+
+	// scripts Redis hasn't cached, as after a restart or on a cluster node
+	// they weren't loaded on, are sent again in full
+	uncached := 0
+	check := func(cmd command, reply interface{}) error {
+		err, ok := reply.(redis.Error)
+		if !ok {
+			return nil
+		}
+		if cmd.script != nil && strings.HasPrefix(string(err), "NOSCRIPT") {
+			uncached++
+			return cmd.script.Send(conn, cmd.args...)
+		}
+		return err
+	}
+
+	// within a transaction the replies are QUEUED, the results coming in
+	// EXEC's reply instead
+	multi := -1
+	for i, reply := range replies {
+		switch strings.ToUpper(b[i].name) {
+		case "MULTI":
+			multi = i
+			continue
+		case "EXEC":
+			if results, ok := reply.([]interface{}); ok && multi >= 0 {
+				for j, result := range results {
+					if err := check(b[multi+1+j], result); err != nil {
+						return err
+					}
+				}
+				multi = -1
+				continue
+			}
+			multi = -1
+		}
+		if multi >= 0 {
+			continue // EXEC fails too if any command failed to queue
+		}
+		if err := check(b[i], reply); err != nil {
+			return err
+		}
+	}
+	if uncached == 0 {
+		return nil
+	}
+
+	replies, err = redis.Values(conn.Do(""))
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit writes a batch, and if Redis is unreachable buffers it and waits for
+// Redis to come back rather than losing the results
+func (c *Crawler) commit(ctx context.Context, w *worker, b batch) {
+	_, span := c.startSpan(ctx, "store", attribute.Int("crawler.commands", len(b)))
+	err := b.exec(w.conn)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	if err == nil {
+		return
+	}
+
+	if w.conn.Err() == nil {
+		w.logger.Error("failed to store results", "err", err)
+		return
+	}
+
+	w.buffer(b, c.OutageBufferSize)
+	c.reconnect(ctx, w)
+}
+
+// buffer holds writes until Redis is reachable again, discarding the oldest
+// once more than max commands are held
+func (w *worker) buffer(b batch, max int) {
+	w.outbox = append(w.outbox, b...)
+
+	if over := len(w.outbox) - max; over > 0 {
+		// and the rest of any transaction cut in two
+		for i, cmd := range w.outbox[over:] {
+			if strings.EqualFold(cmd.name, "MULTI") {
+				break
+			}
+			if strings.EqualFold(cmd.name, "EXEC") {
+				over += i + 1
+				break
+			}
+		}
+		w.logger.Error("outage buffer full, dropping results", "dropped", over)
+		w.outbox = w.outbox[over:]
+	}
+}
+
+// reconnect blocks until Redis is reachable again and any buffered writes have
+// been flushed. It returns false, abandoning the buffer, if ctx is cancelled
+// first.
+func (c *Crawler) reconnect(ctx context.Context, w *worker) bool {
+	w.conn.Close()
+
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		w.conn = c.RedisPool.Get()
+
+		_, err := w.conn.Do("PING")
+		if err == nil {
+			err = w.outbox.exec(w.conn)
+		}
+		if err == nil {
+			if attempt > 0 {
+				w.logger.Info("redis reachable again", "flushed", len(w.outbox))
+			}
+			w.outbox = nil
+			return true
+		}
+
+		if attempt == 0 {
+			w.logger.Warn("redis unreachable, pausing", "buffered", len(w.outbox), "err", err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			if len(w.outbox) > 0 {
+				w.logger.Error("gave up waiting for redis, buffered results lost", "lost", len(w.outbox))
+			}
+			return false
+		}
+
+		if backoff < 10*time.Second {
+			backoff *= 2
+		}
+		w.conn.Close()
+	}
+}
+
+// doRetry runs a command, waiting out any Redis outage and retrying
+func (c *Crawler) doRetry(ctx context.Context, w *worker, name string, args ...interface{}) (interface{}, error) {
+	for {
+		reply, err := w.conn.Do(name, args...)
+		if err == nil || w.conn.Err() == nil {
+			return reply, err
+		}
+
+		if !c.reconnect(ctx, w) {
+			return reply, err
+		}
+	}
+}
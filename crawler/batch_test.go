@@ -0,0 +1,178 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestBatchScriptNotCached(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conn, err := redis.Dial("tcp", mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// as after Redis restarts, the script was never loaded
+	b := batch{}
+	b.add("SADD", "pages", "a")
+	b.addScript(addCappedScript, "pages", "b", 2)
+	b.addScript(addCappedScript, "pages", "c", 2)
+	if err := b.exec(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := redis.Strings(conn.Do("SMEMBERS", "pages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Errorf("members = %v, want a and b only", members)
+	}
+
+	// the script is cached now, by being sent in full
+	cached, err := redis.Ints(conn.Do("SCRIPT", "EXISTS", addCappedScript.Hash()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 1 || cached[0] != 1 {
+		t.Errorf("script wasn't cached")
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conn, err := redis.Dial("tcp", mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	mr.Set("page", "a string")
+	b := batch{}
+	b.add("SADD", "page", "a")
+	if err := b.exec(conn); err == nil {
+		t.Error("exec succeeded writing a set to a string")
+	}
+}
+
+func TestBatchTransaction(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conn, err := redis.Dial("tcp", mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the script isn't cached, so fails inside the transaction
+	b := batch{}
+	b.add("SADD", "pages", "a")
+	b.addScript(addCappedScript, "pages", "b", 2)
+	b.addScript(addCappedScript, "pages", "c", 2)
+	if err := b.transaction().exec(conn); err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := mr.Members("pages"); len(members) != 2 {
+		t.Errorf("members = %v, want a and b only", members)
+	}
+
+	mr.Set("page", "a string")
+	b = batch{}
+	b.add("SADD", "pages", "d")
+	b.add("SADD", "page", "a")
+	if err := b.transaction().exec(conn); err == nil {
+		t.Error("exec succeeded writing a set to a string")
+	}
+}
+
+// newTestWorker is a worker with its own connection to the crawler's Redis
+func newTestWorker(c *Crawler) *worker {
+	return &worker{conn: c.RedisPool.Get(), logger: c.Logger}
+}
+
+func TestBufferDropsOldest(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	w := newTestWorker(c)
+	defer w.conn.Close()
+
+	b := batch{}
+	b.add("SADD", "pages", "a")
+	b.add("SADD", "pages", "b")
+	w.buffer(b, 3)
+	if len(w.outbox) != 2 {
+		t.Fatalf("buffered %d commands, want 2", len(w.outbox))
+	}
+
+	// dropping the oldest cuts into the transaction, so all of it goes
+	b = batch{}
+	b.add("SADD", "pages", "c")
+	b.add("SADD", "pages", "d")
+	w.buffer(b.transaction(), 3)
+	if len(w.outbox) != 0 {
+		t.Errorf("buffer holds %v, want the transaction cut in two dropped", w.outbox)
+	}
+
+	b = batch{}
+	b.add("SADD", "pages", "e")
+	b.add("SADD", "pages", "f")
+	w.buffer(b, 1)
+	if len(w.outbox) != 1 || w.outbox[0].args[1] != "f" {
+		t.Errorf("buffer holds %v, want only the newest", w.outbox)
+	}
+}
+
+func TestCommitWaitsOutOutage(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	w := newTestWorker(c)
+	defer func() { w.conn.Close() }()
+
+	mr.Close()
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		mr.Restart()
+	}()
+
+	b := batch{}
+	b.add("SADD", "pages", "a")
+	c.commit(context.Background(), w, b)
+
+	if members, _ := mr.Members("pages"); len(members) != 1 {
+		t.Errorf("members = %v, want the write made once Redis was back", members)
+	}
+	if len(w.outbox) != 0 {
+		t.Errorf("buffer still holds %v", w.outbox)
+	}
+
+	// writes that fail with Redis reachable aren't buffered
+	mr.Set("page", "a string")
+	b = batch{}
+	b.add("SADD", "page", "a")
+	c.commit(context.Background(), w, b)
+	if len(w.outbox) != 0 {
+		t.Errorf("buffered %v, a write Redis rejected", w.outbox)
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	w := newTestWorker(c)
+	defer func() { w.conn.Close() }()
+
+	mr.Close()
+	b := batch{}
+	b.add("SADD", "pages", "a")
+	w.buffer(b, c.OutageBufferSize)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if c.reconnect(ctx, w) {
+		t.Error("reconnect = true with Redis gone")
+	}
+	if _, err := c.doRetry(ctx, w, "PING"); err == nil {
+		t.Error("doRetry succeeded with Redis gone")
+	}
+}
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// BloomOptions size the Bloom filter that tracks the pages visited in place
+// of the exact set, see Crawler.VisitedBloom
+type BloomOptions struct {
+	// Capacity is how many pages the filter is sized for, 10 million by
+	// default. Past it the false positive rate climbs.
+	Capacity int64
+	// FalsePositiveRate is the chance of a page never visited being taken
+	// for visited, and so skipped, 0.001 by default
+	FalsePositiveRate float64
+	// Module keeps the filter with the RedisBloom module's BF commands
+	// rather than in a plain Redis bitmap, which any Redis supports
+	Module bool
+	// KeepExact also keeps the exact set of pages visited, for Visited
+	// listings, Diff and the like, while the filter does the checking
+	KeepExact bool
+}
+
+// maxBloomBits is the most bits a Redis string can hold
+const maxBloomBits = 1 << 32
+
+// size is how many bits the filter needs, and how many hashes each page sets
+func (o BloomOptions) size() (bits uint64, hashes int) {
+	n, p := float64(o.capacity()), o.falsePositiveRate()
+	m := math.Ceil(-n * math.Log(p) / (math.Ln2 * math.Ln2))
+	bits = uint64(min(m, maxBloomBits))
+	hashes = max(1, int(math.Round(float64(bits)/n*math.Ln2)))
+	return bits, hashes
+}
+
+func (o BloomOptions) capacity() int64 {
+	if o.Capacity <= 0 {
+		return 10_000_000
+	}
+	return o.Capacity
+}
+
+func (o BloomOptions) falsePositiveRate() float64 {
+	if o.FalsePositiveRate <= 0 || o.FalsePositiveRate >= 1 {
+		return 0.001
+	}
+	return o.FalsePositiveRate
+}
+
+// bloomPositions are the bits of the filter the URL sets, by double hashing
+// a 128-bit FNV hash, which every process computes alike
+func bloomPositions(url string, bits uint64, hashes int) []interface{} {
+	h := fnv.New128a()
+	h.Write([]byte(url))
+	sum := h.Sum(nil)
+	h1, h2 := binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:])|1
+
+	positions := make([]interface{}, hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % bits
+	}
+	return positions
+}
+
+// bloomAddScript sets a URL's bits in the filter, returning 1 if any weren't
+// already set, i.e. the URL is newly added
+//
+//	KEYS[1] the filter
+//	ARGV the URL's bits
+var bloomAddScript = redis.NewScript(1, `
+local added = 0
+for _, pos in ipairs(ARGV) do
+	if redis.call("SETBIT", KEYS[1], pos, 1) == 0 then
+		added = 1
+	end
+end
+return added
+`)
+
+// bloomHasScript returns 1 if all of a URL's bits are set in the filter
+//
+//	KEYS[1] the filter
+//	ARGV the URL's bits
+var bloomHasScript = redis.NewScript(1, `
+for _, pos in ipairs(ARGV) do
+	if redis.call("GETBIT", KEYS[1], pos) == 0 then
+		return 0
+	end
+end
+return 1
+`)
+
+// reserveBloom creates the RedisBloom filter sized by the VisitedBloom
+// options, if it doesn't exist yet. A plain bitmap needs no setting up.
+func (c *Crawler) reserveBloom(conn redis.Conn) error {
+	if c.VisitedBloom == nil || !c.VisitedBloom.Module {
+		return nil
+	}
+
+	o := c.VisitedBloom
+	_, err := conn.Do("BF.RESERVE", c.KeyVisitedBloom, o.falsePositiveRate(), o.capacity())
+	if err != nil && strings.Contains(err.Error(), "exists") {
+		return nil
+	}
+	return err
+}
+
+// markVisited records the page as visited, reporting false if it already
+// was. With VisitedBloom the filter decides, and may mistake a page never
+// visited for one that was, at the rate it's sized for.
+func (c *Crawler) markVisited(conn redis.Conn, url string) (bool, error) {
+	if c.VisitedBloom == nil {
+		inserted, err := redis.Int(conn.Do("SADD", c.KeyVisitedHREFs, url))
+		return inserted == 1, err
+	}
+
+	var added bool
+	var err error
+	if c.VisitedBloom.Module {
+		added, err = redis.Bool(conn.Do("BF.ADD", c.KeyVisitedBloom, url))
+	} else {
+		bits, hashes := c.VisitedBloom.size()
+		added, err = redis.Bool(bloomAddScript.Do(conn, redis.Args{c.KeyVisitedBloom}.Add(bloomPositions(url, bits, hashes)...)...))
+	}
+
+	// the exact set only holds the pages actually crawled, so leaves out any
+	// false positives
+	if err == nil && added && c.VisitedBloom.KeepExact {
+		_, err = conn.Do("SADD", c.KeyVisitedHREFs, url)
+	}
+	return added, err
+}
+
+// hasVisited reports whether the page has been visited, by the filter if
+// VisitedBloom is set
+func (c *Crawler) hasVisited(conn redis.Conn, url string) (bool, error) {
+	switch {
+	case c.VisitedBloom == nil || c.VisitedBloom.KeepExact:
+		return redis.Bool(conn.Do("SISMEMBER", c.KeyVisitedHREFs, url))
+	case c.VisitedBloom.Module:
+		return redis.Bool(conn.Do("BF.EXISTS", c.KeyVisitedBloom, url))
+	}
+	bits, hashes := c.VisitedBloom.size()
+	return redis.Bool(bloomHasScript.Do(conn, redis.Args{c.KeyVisitedBloom}.Add(bloomPositions(url, bits, hashes)...)...))
+}
+
+// visitedCount is how many pages have been visited. Without the exact set
+// it's how many have records, which leaves out pages visited before records
+// were kept.
+func (c *Crawler) visitedCount(conn redis.Conn) (int, error) {
+	if c.VisitedBloom == nil || c.VisitedBloom.KeepExact {
+		return redis.Int(conn.Do("SCARD", c.KeyVisitedHREFs))
+	}
+	return redis.Int(conn.Do("HLEN", c.KeyPages))
+}
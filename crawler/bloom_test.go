@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBloomSize(t *testing.T) {
+	bits, hashes := BloomOptions{Capacity: 1_000_000, FalsePositiveRate: 0.01}.size()
+	if bits != 9_585_059 || hashes != 7 {
+		t.Errorf("size() = %d bits, %d hashes, want 9585059 bits, 7 hashes", bits, hashes)
+	}
+}
+
+func TestVisitedBloom(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		for i := range 20 {
+			fmt.Fprintf(w, `<a href="/%d">page</a>`, i)
+		}
+	}))
+	defer site.Close()
+
+	for _, keepExact := range []bool{false, true} {
+		c, mr := newTestCrawler(t)
+		c.VisitedBloom = &BloomOptions{Capacity: 1000, KeepExact: keepExact}
+
+		// put back unfinished, so visited already
+		conn := c.RedisPool.Get()
+		c.markVisited(conn, site.URL+"/")
+		b := batch{}
+		c.putBack(&b, Entry{URL: site.URL + "/", Reclaimed: true})
+		b.exec(conn)
+		conn.Close()
+		c.Run()
+
+		info, err := c.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Visited != 21 {
+			t.Errorf("keepExact %v: visited %d pages, want 21", keepExact, info.Visited)
+		}
+		if visited, _ := c.Visited(site.URL + "/7"); !visited {
+			t.Errorf("keepExact %v: Visited(/7) = false", keepExact)
+		}
+		if visited, _ := c.Visited(site.URL + "/never"); visited {
+			t.Errorf("keepExact %v: Visited(/never) = true", keepExact)
+		}
+		if exact, _ := mr.Members(c.KeyVisitedHREFs); (len(exact) > 0) != keepExact {
+			t.Errorf("keepExact %v: exact set has %d pages", keepExact, len(exact))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DefaultPageCacheTTL is how long pages stay cached for conditional GETs by
+// default
+const DefaultPageCacheTTL = 7 * 24 * time.Hour
+
+// cachedPage is what is kept of a page so that, when a re-crawl finds it
+// unmodified, its results can be reused without re-fetching the body
+type cachedPage struct {
+	Cached       time.Time    `json:"cached"`
+	ETag         string       `json:"etag,omitempty"`
+	LastModified string       `json:"lastModified,omitempty"`
+	Links        []string     `json:"links"`
+	Anchors      []LinkAnchor `json:"anchors,omitempty"`
+	Images       []string     `json:"images"`
+	Icons        []string     `json:"icons,omitempty"`
+	Canonical    string       `json:"canonical,omitempty"`
+	Assets       []string     `json:"assets,omitempty"` // kind then URL, space separated
+	TouchIcons   []string     `json:"touchIcons,omitempty"`
+	AMP          string       `json:"amp,omitempty"`
+	InlineSVGs   int          `json:"inlineSVGs,omitempty"`
+
+	// the page's language and its alternates in others
+	Language   string          `json:"language,omitempty"`
+	Alternates []LangAlternate `json:"alternates,omitempty"`
+
+	// the simhash of the page's text
+	Simhash uint64 `json:"simhash,omitempty"`
+	// its <title>, and how many pages it links to
+	Title    string `json:"title,omitempty"`
+	Outlinks int    `json:"outlinks,omitempty"`
+}
+
+// loadCachedPage returns the cached page, if any cached within PageCacheTTL
+func (c *Crawler) loadCachedPage(url string) (cachedPage, bool) {
+	cached := cachedPage{}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("HGET", c.KeyPageCache, url))
+	if err != nil || json.Unmarshal(data, &cached) != nil {
+		return cached, false
+	}
+	if c.PageCacheTTL > 0 && time.Since(cached.Cached) > c.PageCacheTTL {
+		return cached, false
+	}
+	return cached, true
+}
+
+// saveCachedPage caches the page's results along with its validators, pages
+// without an ETag or Last-Modified can't be conditionally fetched so aren't
+// cached. The cache expires PageCacheTTL after the last page was cached.
+func (c *Crawler) saveCachedPage(url string, header http.Header, page *scrapeResult) error {
+	cached := cachedPage{
+		Cached:       time.Now().UTC(),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Links:        page.hrefs,
+		Anchors:      page.anchors,
+		Images:       page.imgSrcs,
+		Icons:        page.icons,
+		TouchIcons:   page.touchIcons,
+		AMP:          page.amp,
+		InlineSVGs:   page.inlineSVGs,
+		Language:     page.language,
+		Alternates:   page.alternates,
+		Canonical:    page.canonical,
+		Simhash:      page.simhash,
+		Title:        page.title,
+		Outlinks:     page.outlinks,
+	}
+	for _, a := range page.assets {
+		cached.Assets = append(cached.Assets, a.kind+" "+a.url)
+	}
+	if cached.ETag == "" && cached.LastModified == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	b := batch{}
+	b.add("HSET", c.KeyPageCache, url, data)
+	if c.PageCacheTTL > 0 {
+		b.add("PEXPIRE", c.KeyPageCache, c.PageCacheTTL.Milliseconds())
+	}
+	return b.exec(conn)
+}
+
+// header is the conditional request header that revalidates the page
+func (cached cachedPage) header() http.Header {
+	h := http.Header{}
+	if cached.ETag != "" {
+		h.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		h.Set("If-Modified-Since", cached.LastModified)
+	}
+	return h
+}
+
+// result rebuilds the scrape result the page had when it was cached
+func (cached cachedPage) result() *scrapeResult {
+	page := newScrapeResult()
+	page.hrefs = cached.Links
+	page.imgSrcs = cached.Images
+	page.canonical = cached.Canonical
+	page.amp = cached.AMP
+	page.inlineSVGs = cached.InlineSVGs
+	page.language = cached.Language
+	page.alternates = cached.Alternates
+	page.simhash = cached.Simhash
+	page.title = cached.Title
+	page.outlinks = cached.Outlinks
+	if cached.Anchors != nil {
+		page.anchors = cached.Anchors
+	}
+	if cached.Icons != nil {
+		page.icons = cached.Icons
+	}
+	if cached.TouchIcons != nil {
+		page.touchIcons = cached.TouchIcons
+	}
+	for _, a := range cached.Assets {
+		if kind, url, ok := strings.Cut(a, " "); ok {
+			page.assets = append(page.assets, asset{url: url, kind: kind})
+		}
+	}
+	return page
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPageCache(t *testing.T) {
+	fetches, revalidations := 0, 0
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetches++
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`<img src="/a.jpg">`))
+	}))
+	defer site.Close()
+
+	_, mr := newTestCrawler(t)
+	pool := NewPool("tcp", mr.Addr())
+	crawl := func(job string) *Crawler {
+		c := NewJob(pool, job)
+		c.TerminationGrace = 10 * time.Millisecond
+		c.ConditionalGet = true
+		c.Seed(site.URL + "/")
+		c.Run()
+		return c
+	}
+
+	first := crawl("first")
+	if _, ok := first.loadCachedPage(site.URL + "/"); !ok {
+		t.Fatal("page not cached")
+	}
+	if ttl := mr.TTL(first.KeyPageCache); ttl <= 0 || ttl > DefaultPageCacheTTL {
+		t.Errorf("cache TTL = %v, want up to %v", ttl, DefaultPageCacheTTL)
+	}
+
+	// another job doesn't see the first's cache
+	if _, ok := NewJob(pool, "second").loadCachedPage(site.URL + "/"); ok {
+		t.Error("page cached by one job seen by another")
+	}
+
+	// the same job revalidates it, until it's older than the TTL
+	first.Rotate()
+	first.Seed(site.URL + "/")
+	first.Run()
+	if fetches != 1 || revalidations != 1 {
+		t.Errorf("fetched %d times, revalidated %d, want the re-crawl to revalidate", fetches, revalidations)
+	}
+	first.PageCacheTTL = time.Nanosecond
+	if _, ok := first.loadCachedPage(site.URL + "/"); ok {
+		t.Error("page cached longer than PageCacheTTL still used")
+	}
+
+	if err := DeleteJob(pool, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(first.KeyPageCache) {
+		t.Error("page cache left behind by DeleteJob")
+	}
+}
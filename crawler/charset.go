@@ -0,0 +1,29 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// decodeHTML converts an HTML body to UTF-8 from the charset declared by its
+// byte order mark, Content-Type header or <meta charset>. Undeclared
+// charsets are left as UTF-8, as most of the web is, rather than guessed.
+func decodeHTML(body io.Reader, contentType string) io.Reader {
+	// <meta charset> must appear in the first 1024 bytes
+	preview := make([]byte, 1024)
+	n, _ := io.ReadFull(body, preview)
+	preview = preview[:n]
+	whole := io.MultiReader(bytes.NewReader(preview), body)
+
+	enc, name, _ := charset.DetermineEncoding(preview, contentType)
+	// unwrapped Windows-1252 is the guess made when nothing is declared,
+	// declared charsets come wrapped for HTML
+	if name == "utf-8" || enc == charmap.Windows1252 {
+		return whole
+	}
+	return transform.NewReader(whole, enc.NewDecoder())
+}
@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ImageCheck is what checking an image found, see CheckImages
+type ImageCheck struct {
+	URL         string `json:"url"`
+	Status      int    `json:"status,omitempty"` // 0 if the request failed
+	ContentType string `json:"contentType,omitempty"`
+	// Problem is why the image is broken, "" if it isn't
+	Problem string `json:"problem,omitempty"`
+	// Pages are the pages a broken image was found on
+	Pages []string `json:"pages,omitempty"`
+}
+
+// Broken reports whether the image failed its check
+func (ic ImageCheck) Broken() bool {
+	return ic.Problem != ""
+}
+
+// CheckImage requests the image, with a HEAD or, for servers refusing
+// HEAD, a GET of which only the headers are read, finding it broken unless
+// it answers 2xx with an image content type. Images answering without a
+// content type aren't counted as broken, browsers sniff them.
+func (c *Crawler) CheckImage(ctx context.Context, url string) ImageCheck {
+	check := ImageCheck{URL: url}
+
+	resp, err := c.checkRequest(ctx, http.MethodHead, url)
+	if err != nil || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		resp, err = c.checkRequest(ctx, http.MethodGet, url)
+	}
+	if err != nil {
+		check.Problem = err.Error()
+		return check
+	}
+
+	check.Status = resp.StatusCode
+	check.ContentType = resp.Header.Get("content-type")
+	mediaType, _, _ := mime.ParseMediaType(check.ContentType)
+	switch {
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		check.Problem = resp.Status
+	case check.ContentType != "" && !strings.HasPrefix(mediaType, "image/"):
+		check.Problem = fmt.Sprintf("not an image: %s", check.ContentType)
+	}
+	return check
+}
+
+// checkRequest makes a request for an image check, closing the response
+// body unread
+func (c *Crawler) checkRequest(ctx context.Context, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// CheckImages checks every image found, concurrency at a time, calling fn
+// with each image's check, and the pages broken ones were found on, from
+// one goroutine. It's a broken image audit of a finished crawl.
+func (c *Crawler) CheckImages(ctx context.Context, concurrency int, fn func(ImageCheck)) error {
+	urls := make(chan string)
+	checks := make(chan ImageCheck)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < max(concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range urls {
+				checks <- c.CheckImage(ctx, url)
+			}
+		}()
+	}
+
+	var iterErr error
+	go func() {
+		defer close(urls)
+		it := c.ImageIterator()
+		for it.Next() {
+			url := it.Member()
+			// data URI images are kept apart, and only http(s) can be requested
+			if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+				continue
+			}
+			select {
+			case urls <- url:
+			case <-ctx.Done():
+				return
+			}
+		}
+		iterErr = it.Err()
+	}()
+
+	go func() {
+		wg.Wait()
+		close(checks)
+	}()
+
+	var pagesErr error
+	for check := range checks {
+		if check.Broken() && pagesErr == nil {
+			check.Pages, pagesErr = c.ImagePages(check.URL)
+		}
+		fn(check)
+	}
+
+	if iterErr != nil {
+		return iterErr
+	}
+	if pagesErr != nil {
+		return pagesErr
+	}
+	return ctx.Err()
+}
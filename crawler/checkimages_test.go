@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckImages(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<img src="/ok.png"><img src="/missing.png"><img src="/login.jpg"><img src="/nohead.gif">`))
+		case "/ok.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/login.jpg":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/nohead.gif":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "image/gif")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	checks := map[string]ImageCheck{}
+	if err := c.CheckImages(t.Context(), 2, func(ic ImageCheck) { checks[ic.URL] = ic }); err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 4 {
+		t.Fatalf("checked %d images, want 4", len(checks))
+	}
+
+	for path, problem := range map[string]string{
+		"/ok.png":      "",
+		"/missing.png": "404 Not Found",
+		"/login.jpg":   "not an image: text/html; charset=utf-8",
+		"/nohead.gif":  "",
+	} {
+		ic := checks[site.URL+path]
+		if ic.Problem != problem {
+			t.Errorf("%s problem = %q, want %q", path, ic.Problem, problem)
+		}
+		if ic.Broken() && (len(ic.Pages) != 1 || ic.Pages[0] != site.URL+"/") {
+			t.Errorf("%s pages = %v, want the page it's on", path, ic.Pages)
+		}
+	}
+	if ic := checks[site.URL+"/nohead.gif"]; ic.Status != 200 || ic.ContentType != "image/gif" {
+		t.Errorf("image refusing HEAD = %d %q, want it checked with a GET", ic.Status, ic.ContentType)
+	}
+}
@@ -0,0 +1,202 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+
+	neturl "net/url"
+)
+
+// ChromeRenderer is a Renderer loading pages in headless Chrome, each in a
+// new tab of a single shared browser. Chrome makes its own requests, so the
+// Crawler's Proxies, Header, HostHeaders and Cookies don't apply to them.
+type ChromeRenderer struct {
+	// NetworkIdle is how long the network must be quiet after the page has
+	// loaded before it's taken to be fully rendered
+	NetworkIdle time.Duration
+	// Timeout caps how long a page may take to render
+	Timeout time.Duration
+	// ReportDir, if set, is where a report is written for every page
+	// rendered, showing a screenshot with the images on the page outlined
+	// along with their metadata, so reviewers can check which the crawler's
+	// extraction picks up
+	ReportDir string
+
+	browser context.Context
+	cancel  context.CancelFunc
+}
+
+// NewChromeRenderer starts headless Chrome, found on the PATH unless given
+// chromedp.ExecPath, which runs until Close
+func NewChromeRenderer(opts ...chromedp.ExecAllocatorOption) (*ChromeRenderer, error) {
+	opts = append(chromedp.DefaultExecAllocatorOptions[:], opts...)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	browser, cancelBrowser := chromedp.NewContext(allocCtx)
+
+	// the browser is started by the first Run on its context
+	if err := chromedp.Run(browser); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return nil, fmt.Errorf("failed to start chrome: %w", err)
+	}
+
+	return &ChromeRenderer{
+		NetworkIdle: 500 * time.Millisecond,
+		Timeout:     30 * time.Second,
+		browser:     browser,
+		cancel: func() {
+			cancelBrowser()
+			cancelAlloc()
+		},
+	}, nil
+}
+
+// Close shuts down the browser
+func (r *ChromeRenderer) Close() {
+	r.cancel()
+}
+
+// Render loads the page in a new tab, waits for the network to go idle and
+// returns the rendered DOM
+func (r *ChromeRenderer) Render(ctx context.Context, url string) (*http.Response, error) {
+	tab, cancel := chromedp.NewContext(r.browser)
+	defer cancel()
+	tab, cancelTimeout := context.WithTimeout(tab, r.Timeout)
+	defer cancelTimeout()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	tracker := &networkTracker{inFlight: map[network.RequestID]bool{}, lastActive: time.Now()}
+	chromedp.ListenTarget(tab, tracker.observe)
+
+	var html string
+	actions := []chromedp.Action{
+		network.Enable(),
+		chromedp.Navigate(url),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			return tracker.waitIdle(ctx, r.NetworkIdle, r.Timeout/2)
+		}),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	}
+	var screenshot []byte
+	var images []reportImage
+	if r.ReportDir != "" {
+		actions = append(actions,
+			chromedp.Evaluate(reportImagesJS, &images),
+			chromedp.FullScreenshot(&screenshot, 100),
+		)
+	}
+
+	err := chromedp.Run(tab, actions...)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the page is no less rendered for its report failing
+	if r.ReportDir != "" {
+		if err := r.writeReport(url, screenshot, images); err != nil {
+			slog.Warn("failed to write render report", "url", url, "err", err)
+		}
+	}
+
+	doc := tracker.document()
+	if doc == nil {
+		return nil, fmt.Errorf("no document loaded from %s", url)
+	}
+
+	header := http.Header{}
+	for k, v := range doc.Headers {
+		// repeated headers are joined by newlines
+		for _, line := range strings.Split(fmt.Sprint(v), "\n") {
+			header.Add(k, line)
+		}
+	}
+	// the DOM is serialized as UTF-8 HTML whatever the page was served as
+	if ct := header.Get("Content-Type"); strings.HasPrefix(ct, "text/html") || strings.HasPrefix(ct, "application/xhtml+xml") {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	// relative URLs resolve against wherever any redirects led
+	var req *http.Request
+	if final, err := neturl.Parse(doc.URL); err == nil {
+		req = &http.Request{Method: http.MethodGet, URL: final}
+	}
+
+	return &http.Response{
+		Request:       req,
+		Status:        fmt.Sprintf("%d %s", doc.Status, doc.StatusText),
+		StatusCode:    int(doc.Status),
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(html)),
+		ContentLength: int64(len(html)),
+	}, nil
+}
+
+// networkTracker follows a tab's requests, to tell when the network is idle
+// and what the page's document was served with
+type networkTracker struct {
+	mu         sync.Mutex
+	inFlight   map[network.RequestID]bool
+	lastActive time.Time
+	doc        *network.Response
+}
+
+func (t *networkTracker) observe(ev any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		t.inFlight[ev.RequestID] = true
+	case *network.EventResponseReceived:
+		if ev.Type == network.ResourceTypeDocument && t.doc == nil {
+			t.doc = ev.Response
+		}
+	case *network.EventLoadingFinished:
+		delete(t.inFlight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inFlight, ev.RequestID)
+	default:
+		return
+	}
+	t.lastActive = time.Now()
+}
+
+// waitIdle blocks until no request has been in flight for idle, or for at
+// most maxWait, as pages that poll never go quiet
+func (t *networkTracker) waitIdle(ctx context.Context, idle time.Duration, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	for time.Now().Before(deadline) {
+		t.mu.Lock()
+		quiet := len(t.inFlight) == 0 && time.Since(t.lastActive) >= idle
+		t.mu.Unlock()
+		if quiet {
+			return nil
+		}
+
+		select {
+		case <-time.After(idle / 5):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (t *networkTracker) document() *network.Response {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.doc
+}
@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// reportImage is an image found on the rendered page, with where it was laid
+// out in document coordinates
+type reportImage struct {
+	Kind          string  `json:"kind"` // img, or background for CSS images
+	Src           string  `json:"src"`  // the src attribute, or the CSS url()
+	CurrentSrc    string  `json:"currentSrc"`
+	Alt           string  `json:"alt"`
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Width         float64 `json:"width"`
+	Height        float64 `json:"height"`
+	NaturalWidth  int     `json:"naturalWidth"`
+	NaturalHeight int     `json:"naturalHeight"`
+}
+
+// Extracted reports whether the crawler's extraction picks the image up,
+// which it does for <img> tags with a src attribute only
+func (img reportImage) Extracted() bool {
+	return img.Kind == "img" && img.Src != ""
+}
+
+// reportImagesJS lists the rendered page's visible <img> tags and CSS
+// background images, with their bounding boxes
+const reportImagesJS = `(() => {
+	const images = [];
+	const box = (el) => {
+		const r = el.getBoundingClientRect();
+		return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height};
+	};
+	for (const el of document.querySelectorAll("*")) {
+		const b = box(el);
+		if (b.width === 0 || b.height === 0) continue;
+		if (el.tagName === "IMG") {
+			images.push(Object.assign(b, {
+				kind: "img", src: el.getAttribute("src") || "", currentSrc: el.currentSrc || "",
+				alt: el.alt || "", naturalWidth: el.naturalWidth, naturalHeight: el.naturalHeight,
+			}));
+		}
+		const bg = getComputedStyle(el).backgroundImage.match(/url\("?(.*?)"?\)/);
+		if (bg) {
+			images.push(Object.assign(b, {kind: "background", src: bg[1], currentSrc: bg[1], alt: ""}));
+		}
+	}
+	return images;
+})()`
+
+var reportTmpl = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Render Report - {{.URL}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h1 { font-size: 1.2em; word-break: break-all; }
+.shot { position: relative; display: inline-block; border: 1px solid #ccc; }
+.shot > img { display: block; }
+.box { position: absolute; box-sizing: border-box; border: 2px solid #0a0; }
+.box.missed { border-color: #d00; border-style: dashed; }
+.box span { position: absolute; top: 0; left: 0; background: #0a0; color: #fff; font-size: 11px; padding: 0 3px; }
+.box.missed span { background: #d00; }
+table { border-collapse: collapse; margin-top: 2em; font-size: 0.9em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; word-break: break-all; }
+tr.missed { background: #fee; }
+</style>
+</head>
+<body>
+<h1>{{.URL}}</h1>
+<p>Rendered {{.RenderedAt.Format "2006-01-02 15:04:05 MST"}}: {{len .Images}} images, {{.Extracted}} extracted by the crawler. Images outlined in red are not extracted.</p>
+<div class="shot">
+<img src="{{.Screenshot}}" alt="screenshot">
+{{range $i, $img := .Images}}<div class="box{{if not .Extracted}} missed{{end}}" style="left: {{.X}}px; top: {{.Y}}px; width: {{.Width}}px; height: {{.Height}}px" title="{{.CurrentSrc}}"><span>{{$i}}</span></div>
+{{end}}</div>
+<table>
+<tr><th>#</th><th>Kind</th><th>Src</th><th>Loaded</th><th>Alt</th><th>Natural size</th><th>Box</th><th>Extracted</th></tr>
+{{range $i, $img := .Images}}<tr{{if not .Extracted}} class="missed"{{end}}><td>{{$i}}</td><td>{{.Kind}}</td><td>{{.Src}}</td><td>{{.CurrentSrc}}</td><td>{{.Alt}}</td><td>{{if .NaturalWidth}}{{.NaturalWidth}}x{{.NaturalHeight}}{{end}}</td><td>{{printf "%.0fx%.0f at %.0f,%.0f" .Width .Height .X .Y}}</td><td>{{if .Extracted}}yes{{else}}no{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// writeReport writes a page's screenshot and report into the report dir,
+// one pair of files per page so concurrent renders never share a file
+func (r *ChromeRenderer) writeReport(url string, screenshot []byte, images []reportImage) error {
+	if err := os.MkdirAll(r.ReportDir, 0755); err != nil {
+		return err
+	}
+
+	name := urlHash(url)
+	if err := os.WriteFile(filepath.Join(r.ReportDir, name+".png"), screenshot, 0644); err != nil {
+		return err
+	}
+
+	extracted := 0
+	for _, img := range images {
+		if img.Extracted() {
+			extracted++
+		}
+	}
+
+	f, err := os.Create(filepath.Join(r.ReportDir, name+".html"))
+	if err != nil {
+		return err
+	}
+	err = reportTmpl.Execute(f, struct {
+		URL        string
+		RenderedAt time.Time
+		Screenshot string
+		Images     []reportImage
+		Extracted  int
+	}{url, time.Now().UTC(), name + ".png", images, extracted})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
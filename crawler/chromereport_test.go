@@ -0,0 +1,38 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	r := &ChromeRenderer{ReportDir: t.TempDir()}
+	images := []reportImage{
+		{Kind: "img", Src: "a.png", CurrentSrc: "https://example.com/a.png", X: 10, Y: 20, Width: 100, Height: 50},
+		{Kind: "background", Src: "https://example.com/bg.png", X: 0, Y: 0, Width: 800, Height: 600},
+	}
+	if err := r.writeReport("https://example.com/", []byte("png"), images); err != nil {
+		t.Fatal(err)
+	}
+
+	name := urlHash("https://example.com/")
+	if _, err := os.Stat(filepath.Join(r.ReportDir, name+".png")); err != nil {
+		t.Errorf("screenshot not written: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(r.ReportDir, name+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"2 images, 1 extracted",
+		"left: 10px; top: 20px; width: 100px; height: 50px",
+		`class="box missed"`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// fakeNode is a Redis Cluster node serving just enough of the protocol to
+// migrate keys to another node
+type fakeNode struct {
+	addr string
+
+	mu     sync.Mutex
+	data   map[string]string
+	serves func(key string, asking bool) string // "" or the error to reply
+	slots  func() string                        // the node serving every slot
+	asked  int                                  // ASKING commands received
+	keyed  int                                  // commands with keys received
+}
+
+// clientState is a connection's ASKING flag and transaction
+type clientState struct {
+	asking  bool
+	queued  [][]string
+	inMulti bool
+	aborted bool
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	n := &fakeNode{addr: l.Addr().String(), data: map[string]string{}}
+	n.serves = func(string, bool) string { return "" }
+	n.slots = func() string { return n.addr }
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(conn)
+		}
+	}()
+	return n
+}
+
+func (n *fakeNode) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	state := &clientState{}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		io.WriteString(conn, n.exec(state, args))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func (n *fakeNode) exec(state *clientState, args []string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	cmd := strings.ToUpper(args[0])
+	asking := state.asking
+	state.asking = false
+
+	switch cmd {
+	case "ASKING":
+		n.asked++
+		state.asking = true
+		return "+OK\r\n"
+	case "CLUSTER":
+		host, port, _ := net.SplitHostPort(n.slots())
+		return fmt.Sprintf("*1\r\n*3\r\n:0\r\n:%d\r\n*2\r\n$%d\r\n%s\r\n:%s\r\n", clusterSlots-1, len(host), host, port)
+	case "MULTI":
+		state.inMulti, state.aborted, state.queued = true, false, nil
+		return "+OK\r\n"
+	case "EXEC":
+		state.inMulti = false
+		if state.aborted {
+			return "-EXECABORT Transaction discarded because of previous errors.\r\n"
+		}
+		reply := fmt.Sprintf("*%d\r\n", len(state.queued))
+		for _, queued := range state.queued {
+			reply += n.run(queued)
+		}
+		return reply
+	}
+
+	n.keyed++
+	if errReply := n.serves(args[1], asking); errReply != "" {
+		if state.inMulti {
+			state.aborted = true
+		}
+		return "-" + errReply + "\r\n"
+	}
+	if state.inMulti {
+		state.queued = append(state.queued, args)
+		return "+QUEUED\r\n"
+	}
+	return n.run(args)
+}
+
+func (n *fakeNode) run(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "SET":
+		n.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		v, ok := n.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// migrating sets up a slot part way through migrating from one node to
+// another: the source redirects with ASK, and the target serves the slot
+// only to commands preceded by ASKING
+func migrating(source, target *fakeNode) {
+	slot := strconv.Itoa(keySlot("{job}"))
+	source.serves = func(key string, asking bool) string {
+		return "ASK " + slot + " " + target.addr
+	}
+	target.serves = func(key string, asking bool) string {
+		if asking {
+			return ""
+		}
+		return "MOVED " + slot + " " + source.addr
+	}
+	target.slots = func() string { return source.addr }
+}
+
+func TestClusterAsk(t *testing.T) {
+	source, target := newFakeNode(t), newFakeNode(t)
+	migrating(source, target)
+
+	pool := NewClusterPool([]string{source.addr})
+	conn := pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", "{job}:a", "1"); err != nil {
+		t.Fatalf("SET: %v", err)
+	}
+	if got, err := redis.String(conn.Do("GET", "{job}:a")); err != nil || got != "1" {
+		t.Fatalf("GET = %q, %v, want 1", got, err)
+	}
+	if target.data["{job}:a"] != "1" {
+		t.Errorf("the key wasn't written to the node importing its slot")
+	}
+	if target.asked == 0 {
+		t.Errorf("the node importing the slot was never sent ASKING")
+	}
+}
+
+func TestClusterAskPipeline(t *testing.T) {
+	source, target := newFakeNode(t), newFakeNode(t)
+	migrating(source, target)
+
+	pool := NewClusterPool([]string{source.addr})
+	conn := pool.Get()
+	defer conn.Close()
+
+	b := batch{}
+	b.add("SET", "{job}:a", "1")
+	b.add("SET", "{job}:b", "2")
+	if err := b.exec(conn); err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+
+	// as the sinks record deliveries
+	tx := batch{{name: "MULTI"}}
+	tx.add("SET", "{job}:c", "3")
+	tx.add("EXEC")
+	if err := tx.exec(conn); err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+
+	for key, want := range map[string]string{"{job}:a": "1", "{job}:b": "2", "{job}:c": "3"} {
+		if got := target.data[key]; got != want {
+			t.Errorf("%s = %q on the importing node, want %q", key, got, want)
+		}
+	}
+}
+
+func TestClusterMoved(t *testing.T) {
+	source, target := newFakeNode(t), newFakeNode(t)
+	slot := strconv.Itoa(keySlot("{job}"))
+	source.serves = func(string, bool) string { return "MOVED " + slot + " " + target.addr }
+	// the slot moves once the pool has loaded the mapping
+	source.slots = func() string {
+		if source.keyed == 0 {
+			return source.addr
+		}
+		return target.addr
+	}
+
+	pool := NewClusterPool([]string{source.addr})
+	conn := pool.Get()
+	defer conn.Close()
+
+	// a stale mapping is followed through the MOVED, then reloaded
+	if _, err := conn.Do("SET", "{job}:a", "1"); err != nil {
+		t.Fatalf("SET: %v", err)
+	}
+	if source.keyed != 1 {
+		t.Fatalf("the source was sent %d commands, want 1", source.keyed)
+	}
+	conn.Send("SET", "{job}:b", "2")
+	if _, err := conn.Do(""); err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	if target.data["{job}:a"] != "1" || target.data["{job}:b"] != "2" {
+		t.Errorf("the keys weren't written to the node their slot moved to")
+	}
+	if source.keyed != 1 {
+		t.Errorf("the pipeline went to the source, its slot's mapping wasn't reloaded")
+	}
+}
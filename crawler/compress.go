@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is the Accept-Encoding sent with requests that don't set
+// their own
+const acceptEncoding = "gzip, br"
+
+// decoders decode the response bodies of each Content-Encoding understood
+var decoders = map[string]func(io.Reader) (io.Reader, error){
+	"gzip":   func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	"x-gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	"br":     func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+}
+
+// decompressTransport asks for gzip and brotli compressed responses and
+// decodes them, as net/http does for gzip alone. Responses are passed on
+// decoded, without their Content-Encoding and Content-Length, and with
+// Uncompressed set, as net/http leaves them.
+type decompressTransport struct {
+	base http.RoundTripper
+}
+
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		// a RoundTripper mustn't modify the request it's given
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	decode, ok := decoders[strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))]
+	if !ok || resp.Body == nil || req.Method == http.MethodHead {
+		return resp, nil
+	}
+	resp.Body = &decodedBody{body: resp.Body, decode: decode}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decodedBody decodes a response body, starting on the first read so that
+// empty bodies aren't an error until read
+type decodedBody struct {
+	body   io.ReadCloser
+	decode func(io.Reader) (io.Reader, error)
+	r      io.Reader
+	err    error
+}
+
+func (d *decodedBody) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = d.decode(d.body)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decodedBody) Close() error {
+	return d.body.Close()
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDecompress(t *testing.T) {
+	compress := map[string]func([]byte) []byte{
+		"br": func(b []byte) []byte {
+			buf := bytes.Buffer{}
+			w := brotli.NewWriter(&buf)
+			w.Write(b)
+			w.Close()
+			return buf.Bytes()
+		},
+		"gzip": func(b []byte) []byte {
+			buf := bytes.Buffer{}
+			w := gzip.NewWriter(&buf)
+			w.Write(b)
+			w.Close()
+			return buf.Bytes()
+		},
+	}
+
+	accepted := make(chan string, 10)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted <- r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write(compress["br"]([]byte(`<img src="/br.jpg"><a href="/gzip">gzip</a><a href="/plain">plain</a>`)))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compress["gzip"]([]byte(`<img src="/gzip.jpg">`)))
+		case "/plain":
+			w.Write([]byte(`<img src="/plain.jpg">`))
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	images, _ := mr.Members(c.KeyImageSrcs)
+	if want := []string{site.URL + "/br.jpg", site.URL + "/gzip.jpg", site.URL + "/plain.jpg"}; !slices.Equal(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+	if got := <-accepted; got != acceptEncoding {
+		t.Errorf("Accept-Encoding = %q, want %q", got, acceptEncoding)
+	}
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	neturl "net/url"
+)
+
+// NewCookieJar creates a jar for Crawler.Cookies. A shared jar follows the
+// usual browser rules, so cookies set for a domain are sent to all its
+// subdomains, whereas a per-host jar keeps every host's cookies apart.
+func NewCookieJar(perHost bool) http.CookieJar {
+	if perHost {
+		return &perHostJar{jars: map[string]*cookiejar.Jar{}}
+	}
+	return newJar()
+}
+
+func newJar() *cookiejar.Jar {
+	// only fails given options with a nil PublicSuffixList
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return jar
+}
+
+// perHostJar keeps a separate jar for each host
+type perHostJar struct {
+	mu   sync.Mutex
+	jars map[string]*cookiejar.Jar
+}
+
+func (j *perHostJar) jar(u *neturl.URL) *cookiejar.Jar {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	jar, ok := j.jars[host]
+	if !ok {
+		jar = newJar()
+		j.jars[host] = jar
+	}
+	return jar
+}
+
+func (j *perHostJar) SetCookies(u *neturl.URL, cookies []*http.Cookie) {
+	j.jar(u).SetCookies(u, cookies)
+}
+
+func (j *perHostJar) Cookies(u *neturl.URL) []*http.Cookie {
+	return j.jar(u).Cookies(u)
+}
+
+// LoadCookies pre-seeds a jar from a Netscape cookies.txt file, as exported
+// by browsers and written by curl -c
+func LoadCookies(jar http.CookieJar, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// curl marks HttpOnly cookies with a prefix that looks like a comment
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("line %d: want 7 tab-separated fields, got %d", n, len(fields))
+		}
+		domain, subdomains, path, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+		cookie := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(subdomains, "TRUE") {
+			cookie.Domain = domain
+		}
+		// 0 marks a session cookie
+		if expires != "0" {
+			unix, err := strconv.ParseInt(expires, 10, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid expiry %q", n, expires)
+			}
+			cookie.Expires = time.Unix(unix, 0)
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		u := &neturl.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: path}
+		jar.SetCookies(u, []*http.Cookie{cookie})
+	}
+	return scanner.Err()
+}
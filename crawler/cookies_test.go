@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	neturl "net/url"
+)
+
+const cookiesTxt = "# Netscape HTTP Cookie File\n" +
+	".example.com\tTRUE\t/\tFALSE\t0\tconsent\tyes\n" +
+	"#HttpOnly_www.example.com\tFALSE\t/\tTRUE\t4102444800\tsession\tabc\n"
+
+func TestLoadCookies(t *testing.T) {
+	jar := NewCookieJar(false)
+	if err := LoadCookies(jar, strings.NewReader(cookiesTxt)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.example.com/", "consent=yes; session=abc"},
+		{"http://www.example.com/", "consent=yes"},  // session is secure only
+		{"https://img.example.com/", "consent=yes"}, // session is host only
+		{"https://example.org/", ""},
+	}
+	for _, tt := range tests {
+		if got := cookieHeader(jar.Cookies(mustParse(t, tt.url))); got != tt.want {
+			t.Errorf("cookies for %s = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPerHostCookieJar(t *testing.T) {
+	jar := NewCookieJar(true)
+	if err := LoadCookies(jar, strings.NewReader(cookiesTxt)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the domain cookie was loaded into example.com's jar alone
+	if got := cookieHeader(jar.Cookies(mustParse(t, "https://img.example.com/"))); got != "" {
+		t.Errorf("cookies for img.example.com = %q, want none", got)
+	}
+	if got := cookieHeader(jar.Cookies(mustParse(t, "https://www.example.com/"))); got != "session=abc" {
+		t.Errorf("cookies for www.example.com = %q, want session=abc", got)
+	}
+}
+
+func TestLoadCookiesInvalid(t *testing.T) {
+	if err := LoadCookies(NewCookieJar(false), strings.NewReader("example.com\tFALSE\t/\n")); err == nil {
+		t.Error("loaded a line missing fields")
+	}
+}
+
+func cookieHeader(cookies []*http.Cookie) string {
+	parts := []string{}
+	for _, c := range cookies {
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func mustParse(t *testing.T, url string) *neturl.URL {
+	t.Helper()
+	u, err := neturl.Parse(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,183 @@
+package crawler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	neturl "net/url"
+)
+
+func newTestCrawler(t *testing.T) (*Crawler, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c := New(NewPool("tcp", mr.Addr()))
+	c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.TerminationGrace = 10 * time.Millisecond
+	return c, mr
+}
+
+func TestExtractBaseHref(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	body := `<html><head>
+		<base href="/static/">
+		<base href="https://ignored.example/">
+	</head><body>
+		<img src="a.png">
+		<img src="/b.png">
+		<a href="page.html">same host</a>
+	</body></html>`
+
+	page := c.extract("https://example.com/dir/index.html", http.Header{}, strings.NewReader(body), c.Logger)
+
+	wantImgs := []string{"https://example.com/static/a.png", "https://example.com/b.png"}
+	if !slices.Equal(page.imgSrcs, wantImgs) {
+		t.Errorf("imgSrcs = %v, want %v", page.imgSrcs, wantImgs)
+	}
+	if want := []string{"https://example.com/static/page.html"}; !slices.Equal(page.hrefs, want) {
+		t.Errorf("hrefs = %v, want %v", page.hrefs, want)
+	}
+}
+
+func TestExtractExternalBaseHref(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	body := `<base href="https://cdn.example.net/"><img src="a.png"><a href="page.html">`
+
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+
+	if want := []string{"https://cdn.example.net/a.png"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("imgSrcs = %v, want %v", page.imgSrcs, want)
+	}
+	// links the base takes off the page's host aren't followed
+	if len(page.hrefs) != 0 {
+		t.Errorf("hrefs = %v, want none", page.hrefs)
+	}
+}
+
+func TestExtractCanonical(t *testing.T) {
+	c, _ := newTestCrawler(t)
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`<link rel="canonical" href="/article?id=1"><link rel="canonical" href="/ignored">`, "https://example.com/article?id=1"},
+		{`<base href="/news/"><link rel="Canonical" href="article">`, "https://example.com/news/article"},
+		// syndicated pages pointing elsewhere aren't deduplicated
+		{`<link rel="canonical" href="https://other.example/article">`, ""},
+		{`<link rel="alternate" href="/article">`, ""},
+	}
+	for _, tt := range tests {
+		page := c.extract("https://example.com/print/article?utm_source=x", http.Header{}, strings.NewReader(tt.body), c.Logger)
+		if page.canonical != tt.want {
+			t.Errorf("%s: canonical = %q, want %q", tt.body, page.canonical, tt.want)
+		}
+	}
+}
+
+func TestExtractCharset(t *testing.T) {
+	c, _ := newTestCrawler(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"header", "text/html; charset=ISO-8859-1", "<img src=\"caf\xe9.png\">", "https://example.com/caf%C3%A9.png"},
+		{"meta", "text/html", "<meta charset=\"shift_jis\"><img src=\"\x89\xe6\x91\x9c.png\">", "https://example.com/%E7%94%BB%E5%83%8F.png"},
+		{"undeclared", "text/html", "<img src=\"caf\xc3\xa9.png\">", "https://example.com/caf%C3%A9.png"},
+	}
+	for _, tt := range tests {
+		header := http.Header{"Content-Type": {tt.contentType}}
+		page := c.extract("https://example.com/", header, strings.NewReader(tt.body), c.Logger)
+		if len(page.imgSrcs) != 1 || page.imgSrcs[0] != tt.want {
+			t.Errorf("%s: imgSrcs = %v, want %s", tt.name, page.imgSrcs, tt.want)
+		}
+	}
+}
+
+func TestExtractAnchors(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	body := `<a href="/home">Home</a>
+		<h2>Latest <em>news</em></h2>
+		<p><a href="/a">Read
+			the <b>story</b></a></p>
+		<a href="/b"><img src="b.png" alt="Photo"></a>
+		<a href="https://elsewhere.example/">external</a>
+		<a href="/c">unclosed`
+
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+
+	want := []LinkAnchor{
+		{URL: "https://example.com/home", Text: "Home"},
+		{URL: "https://example.com/a", Text: "Read the story", Heading: "Latest news"},
+		{URL: "https://example.com/b", Text: "Photo", Heading: "Latest news"},
+		{URL: "https://example.com/c", Text: "unclosed", Heading: "Latest news"},
+	}
+	if !slices.Equal(page.anchors, want) {
+		t.Errorf("anchors = %+v, want %+v", page.anchors, want)
+	}
+}
+
+func TestHTMLTypes(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		w.Write([]byte(`<html xmlns="http://www.w3.org/1999/xhtml"><body><img src="/a.png"/></body></html>`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	tests := []struct {
+		contentType string
+		images      int
+	}{
+		{"text/html; charset=utf-8", 1},
+		{"application/xhtml+xml", 1},
+		{"Application/XHTML+XML; charset=utf-8", 1},
+		{"application/xml", 0},
+		{"application/pdf", 0},
+	}
+	for _, tt := range tests {
+		page := c.scrape(t.Context(), site.URL+"/?type="+neturl.QueryEscape(tt.contentType), c.Logger)
+		if len(page.imgSrcs) != tt.images {
+			t.Errorf("%s: imgSrcs = %v, want %d", tt.contentType, page.imgSrcs, tt.images)
+		}
+	}
+
+	c.HTMLTypes = append(c.HTMLTypes, "application/xml")
+	page := c.scrape(t.Context(), site.URL+"/?type=application/xml", c.Logger)
+	if len(page.imgSrcs) != 1 {
+		t.Errorf("added application/xml: imgSrcs = %v, want 1", page.imgSrcs)
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	// an endless site, each page linking to two more
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="` + r.URL.Path + `a">a</a><a href="` + r.URL.Path + `b">b</a>`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.MaxDuration = 200 * time.Millisecond
+	c.Seed(site.URL + "/")
+
+	start := time.Now()
+	c.RunN(2)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ran for %s, want it stopped after about 200ms", elapsed)
+	}
+	if info, err := c.Info(); err != nil || info.Queued == 0 || info.Visited == 0 {
+		t.Errorf("info = %+v, %v, want pages visited and more left queued", info, err)
+	}
+}
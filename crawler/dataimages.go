@@ -0,0 +1,134 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DataImageRecord is an image embedded in a page as a data: URI, rather
+// than linked to, identified by the hash of its decoded bytes
+type DataImageRecord struct {
+	ID          string `json:"id"` // "sha256:" then the hex digest
+	ContentType string `json:"contentType"`
+	Bytes       int    `json:"bytes"`
+	PageURL     string `json:"pageURL"` // the first page it was found on
+	// Path is where it was saved, with DecodeDataImages
+	Path string `json:"path,omitempty"`
+}
+
+// dataImage is a decoded data: URI image
+type dataImage struct {
+	id          string
+	contentType string
+	data        []byte
+}
+
+// isDataURI reports whether the URL is a data: URI, which can't be resolved
+// or fetched like other URLs
+func isDataURI(url string) bool {
+	return len(url) >= 5 && strings.EqualFold(url[:5], "data:")
+}
+
+// splitDataURIs separates the data: URIs from the other URLs
+func splitDataURIs(urls []string) (others []string, dataURIs []string) {
+	others = []string{}
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if isDataURI(url) {
+			dataURIs = append(dataURIs, url)
+		} else {
+			others = append(others, url)
+		}
+	}
+	return others, dataURIs
+}
+
+// decodeDataImages decodes the data: URIs of images, skipping any that are
+// malformed or aren't images
+func decodeDataImages(uris []string, logger *slog.Logger) []dataImage {
+	images := []dataImage{}
+	for _, uri := range uris {
+		meta, _, _ := strings.Cut(uri[len("data:"):], ",")
+		contentType, _, err := mime.ParseMediaType(strings.TrimSuffix(meta, ";base64"))
+		if err != nil || !strings.HasPrefix(contentType, "image/") {
+			logger.Debug("skipping data URI that isn't an image", "type", meta)
+			continue
+		}
+
+		data, err := decodeDataURI("data:" + uri[len("data:"):])
+		if err != nil {
+			logger.Debug("skipping malformed data URI", "err", err)
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		images = append(images, dataImage{id: "sha256:" + hex.EncodeToString(sum[:]), contentType: contentType, data: data})
+	}
+	return images
+}
+
+// dataImageIDs are the IDs of the images
+func dataImageIDs(images []dataImage) []string {
+	ids := make([]string, len(images))
+	for i, img := range images {
+		ids[i] = img.id
+	}
+	return ids
+}
+
+// recordDataImages adds records of the page's data: URI images to the
+// batch, saving each to the download directory with DecodeDataImages
+func (c *Crawler) recordDataImages(b *batch, pageURL string, images []dataImage, logger *slog.Logger) {
+	for _, img := range images {
+		rec := DataImageRecord{ID: img.id, ContentType: img.contentType, Bytes: len(img.data), PageURL: pageURL}
+
+		if c.DecodeDataImages && c.DownloadDir != "" {
+			name := strings.TrimPrefix(img.id, "sha256:")
+			if exts, _ := mime.ExtensionsByType(img.contentType); len(exts) > 0 {
+				name += exts[0]
+			}
+			dest, _, err := c.saveDownload(bytes.NewReader(img.data), name)
+			if err != nil {
+				logger.Warn("failed to save data URI image", "id", img.id, "page", pageURL, "err", err)
+				c.reportError(pageURL, err)
+			} else {
+				rec.Path = dest
+				b.add("HSET", c.KeyDownloads, img.id, dest)
+			}
+		}
+
+		if data, err := json.Marshal(rec); err == nil {
+			b.add("HSETNX", c.KeyDataImages, img.id, data)
+		}
+	}
+}
+
+// DataImages returns every data: URI image found, by ID
+func (c *Crawler) DataImages() ([]DataImageRecord, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	records, err := redis.StringMap(conn.Do("HGETALL", c.KeyDataImages))
+	if err != nil {
+		return nil, err
+	}
+
+	images := []DataImageRecord{}
+	for id, data := range records {
+		img := DataImageRecord{}
+		if err := json.Unmarshal([]byte(data), &img); err != nil {
+			img = DataImageRecord{ID: id}
+		}
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].ID < images[j].ID })
+	return images, nil
+}
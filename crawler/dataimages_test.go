@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractDataImages(t *testing.T) {
+	c, _ := newTestCrawler(t)
+
+	png := []byte("\x89PNG\r\n\x1a\nnot really")
+	sum := sha256.Sum256(png)
+	body := `<img src="/a.png">
+		<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `">
+		<img src="DATA:image/svg+xml;charset=utf-8,%3Csvg%2F%3E">
+		<img src="data:text/plain,hello">
+		<img src="data:image/gif;base64,!!!">`
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+
+	if want := []string{"https://example.com/a.png"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("images = %v, want %v", page.imgSrcs, want)
+	}
+	svgSum := sha256.Sum256([]byte("<svg/>"))
+	want := []string{"sha256:" + hex.EncodeToString(sum[:]), "sha256:" + hex.EncodeToString(svgSum[:])}
+	if ids := dataImageIDs(page.dataImages); !slices.Equal(ids, want) {
+		t.Errorf("data images = %v, want %v", ids, want)
+	}
+}
+
+func TestDecodeDataImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nnot really")
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `">`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.DownloadDir = t.TempDir()
+	c.DecodeDataImages = true
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	images, err := c.DataImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("data images = %+v, want 1", images)
+	}
+	img := images[0]
+	if img.ContentType != "image/png" || img.Bytes != len(png) || img.PageURL != site.URL+"/" {
+		t.Errorf("data image = %+v", img)
+	}
+	if filepath.Ext(img.Path) != ".png" {
+		t.Errorf("saved to %q, want a .png", img.Path)
+	}
+	if saved, err := os.ReadFile(img.Path); err != nil || !bytes.Equal(saved, png) {
+		t.Errorf("saved %q, %v, want the decoded image", saved, err)
+	}
+	if path, _ := c.DownloadPath(img.ID); path != img.Path {
+		t.Errorf("download path = %q, want %q", path, img.Path)
+	}
+	if rec, _, _ := c.LookupPage(site.URL + "/"); !slices.Equal(rec.DataImages, []string{img.ID}) || len(rec.Images) != 0 {
+		t.Errorf("page images %v and data images %v, want just the data image", rec.Images, rec.DataImages)
+	}
+}
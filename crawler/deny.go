@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	neturl "net/url"
+)
+
+// HostList is a set of hosts, each standing for its subdomains too
+type HostList map[string]bool
+
+// DefaultDenyHosts are common ad network and tracker hosts, whose links and
+// images are never the page's own content
+var DefaultDenyHosts = NewHostList(
+	"doubleclick.net",
+	"googlesyndication.com",
+	"googleadservices.com",
+	"google-analytics.com",
+	"googletagmanager.com",
+	"googletagservices.com",
+	"adservice.google.com",
+	"amazon-adsystem.com",
+	"adnxs.com",
+	"adsrvr.org",
+	"criteo.com",
+	"criteo.net",
+	"rubiconproject.com",
+	"pubmatic.com",
+	"openx.net",
+	"casalemedia.com",
+	"moatads.com",
+	"scorecardresearch.com",
+	"quantserve.com",
+	"taboola.com",
+	"outbrain.com",
+	"hotjar.com",
+	"bat.bing.com",
+	"facebook.net",
+	"ads.linkedin.com",
+	"ads-twitter.com",
+)
+
+// NewHostList makes a list of the hosts
+func NewHostList(hosts ...string) HostList {
+	l := HostList{}
+	l.Add(hosts...)
+	return l
+}
+
+// Add adds the hosts to the list, ignoring case and any trailing dot
+func (l HostList) Add(hosts ...string) {
+	for _, host := range hosts {
+		if host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), "."); host != "" {
+			l[host] = true
+		}
+	}
+}
+
+// Contains reports whether the host, or any domain it's a subdomain of, is
+// in the list
+func (l HostList) Contains(host string) bool {
+	if len(l) == 0 {
+		return false
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for host != "" {
+		if l[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return false
+}
+
+// ReadHostList reads a list of hosts, one per line. Blank lines and
+// anything after a # are skipped. Lines in hosts file format, an address
+// then hosts, e.g. "0.0.0.0 ads.example.com", as many published block
+// lists are, list the hosts after the address.
+func ReadHostList(r io.Reader) (HostList, error) {
+	l := HostList{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+		for _, host := range fields {
+			// blocking the local host would block nothing intended
+			if host != "localhost" && net.ParseIP(host) == nil {
+				l.Add(host)
+			}
+		}
+	}
+	return l, scanner.Err()
+}
+
+// LoadHostList reads a list of hosts from the file, see ReadHostList
+func LoadHostList(path string) (HostList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadHostList(f)
+}
+
+// denied reports whether the URL is on one of the DenyHosts
+func (c *Crawler) denied(url string) bool {
+	if len(c.DenyHosts) == 0 {
+		return false
+	}
+	u, err := neturl.Parse(url)
+	return err == nil && c.DenyHosts.Contains(u.Hostname())
+}
+
+// allowed filters the URLs down to those not on the DenyHosts
+func (c *Crawler) allowed(urls []string) []string {
+	if len(c.DenyHosts) == 0 {
+		return urls
+	}
+	kept := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if !c.denied(url) {
+			kept = append(kept, url)
+		}
+	}
+	return kept
+}
+
+// filterDenied drops the page's links and images on the DenyHosts
+func (c *Crawler) filterDenied(page *scrapeResult) {
+	if len(c.DenyHosts) == 0 {
+		return
+	}
+
+	hrefs, anchors := []string{}, []LinkAnchor{}
+	for i, href := range page.hrefs {
+		if c.denied(href) {
+			continue
+		}
+		hrefs = append(hrefs, href)
+		if i < len(page.anchors) {
+			anchors = append(anchors, page.anchors[i])
+		}
+	}
+	page.hrefs, page.anchors = hrefs, anchors
+	page.frames = c.allowed(page.frames)
+	page.imgSrcs = c.allowed(page.imgSrcs)
+	page.media = c.allowed(page.media)
+}
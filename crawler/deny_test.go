@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadHostList(t *testing.T) {
+	l, err := ReadHostList(strings.NewReader(`# ad networks
+Ads.Example.com
+0.0.0.0 tracker.example.net pixel.example.org # hosts file format
+127.0.0.1 localhost
+0.0.0.0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"ads.example.com":      true,
+		"img.ads.example.com.": true,
+		"example.com":          false,
+		"tracker.example.net":  true,
+		"pixel.example.org":    true,
+		"localhost":            false,
+		"badads.example.com":   false,
+	}
+	for host, want := range tests {
+		if got := l.Contains(host); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestDenyHosts(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.jpg"><img src="https://ad.doubleclick.net/pixel.gif"><img src="https://cdn.example.com/b.jpg">`))
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.DenyHosts = NewHostList("localhost", "doubleclick.net")
+	local := strings.Replace(site.URL, "127.0.0.1", "localhost", 1)
+	c.Seed(site.URL+"/", local+"/")
+	c.Run()
+
+	images, _ := mr.Members(c.KeyImageSrcs)
+	if want := []string{site.URL + "/a.jpg", "https://cdn.example.com/b.jpg"}; !slices.Equal(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+	if _, found, _ := c.LookupPage(local + "/"); found {
+		t.Errorf("crawled %s on a denied host", local)
+	}
+}
@@ -0,0 +1,273 @@
+package crawler
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+// DNSCacheOptions configure the in-process cache of DNS lookups
+type DNSCacheOptions struct {
+	// Size is how many hosts' addresses are cached, the least recently used
+	// evicted beyond it
+	Size int
+	// DefaultTTL is how long addresses that came without a TTL, e.g. from
+	// /etc/hosts, are cached. Others are cached for as long as their
+	// records' TTL.
+	DefaultTTL time.Duration
+}
+
+// dnsEntry is a host's cached addresses
+type dnsEntry struct {
+	host    string
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// dnsCache caches the addresses hosts resolve to, for as long as their
+// TTL, so a crawl of many pages on few hosts looks each up once. TTLs are
+// read from the DNS responses the resolver receives, which net.Resolver
+// doesn't otherwise expose.
+type dnsCache struct {
+	opts     DNSCacheOptions
+	resolver *net.Resolver
+	metrics  *metrics
+	group    singleflight.Group
+	now      func() time.Time
+	// dial connects to the nameservers, replaced in tests
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// ttls are the TTLs of the responses received, by the name queried,
+	// until the lookup they're for takes them
+	ttls map[string]uint32
+}
+
+func newDNSCache(opts DNSCacheOptions, m *metrics) *dnsCache {
+	d := &dnsCache{
+		opts:    opts,
+		metrics: m,
+		now:     time.Now,
+		dial:    (&net.Dialer{}).DialContext,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		ttls:    map[string]uint32{},
+	}
+	d.resolver = &net.Resolver{PreferGo: true, Dial: d.dialNameserver}
+	return d
+}
+
+// lookup returns the addresses of the host, from the cache if they've not
+// expired
+func (d *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	d.mu.Lock()
+	if el, ok := d.entries[host]; ok {
+		entry := el.Value.(*dnsEntry)
+		if d.now().Before(entry.expires) {
+			d.lru.MoveToFront(el)
+			d.mu.Unlock()
+			d.metrics.observeDNS(true)
+			return entry.addrs, nil
+		}
+		d.lru.Remove(el)
+		delete(d.entries, host)
+	}
+	d.mu.Unlock()
+	d.metrics.observeDNS(false)
+
+	// concurrent lookups of the same host share one
+	addrs, err, _ := d.group.Do(host, func() (interface{}, error) {
+		addrs, err := d.resolver.LookupIPAddr(ctx, host)
+		ttl, ok := d.takeTTL(host)
+		if err != nil {
+			return nil, err
+		}
+
+		expiry := d.opts.DefaultTTL
+		if ok {
+			expiry = time.Duration(ttl) * time.Second
+		}
+		if expiry > 0 {
+			d.store(&dnsEntry{host: host, addrs: addrs, expires: d.now().Add(expiry)})
+		}
+		return addrs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addrs.([]net.IPAddr), nil
+}
+
+// store caches the entry, evicting the least recently used beyond Size
+func (d *dnsCache) store(entry *dnsEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[entry.host]; ok {
+		d.lru.Remove(el)
+	}
+	d.entries[entry.host] = d.lru.PushFront(entry)
+	for d.lru.Len() > max(d.opts.Size, 1) {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.entries, oldest.Value.(*dnsEntry).host)
+	}
+}
+
+// takeTTL returns and forgets the lowest TTL of the responses to queries
+// of the host, false if none were received
+func (d *dnsCache) takeTTL(host string) (uint32, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ttl, ok := d.ttls[host+"."]
+	delete(d.ttls, host+".")
+	return ttl, ok
+}
+
+// observe notes the lowest TTL of the answers in a DNS response
+func (d *dnsCache) observe(msg []byte) {
+	p := dnsmessage.Parser{}
+	header, err := p.Start(msg)
+	if err != nil || header.RCode != dnsmessage.RCodeSuccess {
+		return
+	}
+	q, err := p.Question()
+	if err != nil {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+
+	answered, ttl := false, uint32(0)
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			break
+		}
+		if !answered || h.TTL < ttl {
+			answered, ttl = true, h.TTL
+		}
+		if err := p.SkipAnswer(); err != nil {
+			break
+		}
+	}
+	if !answered {
+		return
+	}
+
+	name := strings.ToLower(q.Name.String())
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if prev, ok := d.ttls[name]; !ok || ttl < prev {
+		d.ttls[name] = ttl
+	}
+}
+
+// dialNameserver connects the resolver to a nameserver, over a connection
+// that observes the responses read from it
+func (d *dnsCache) dialNameserver(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dial(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	// the resolver frames its messages for streams, but not for packet
+	// connections, which it tells apart by their type
+	if pc, ok := conn.(net.PacketConn); ok {
+		return &dnsPacketConn{Conn: conn, pc: pc, d: d}, nil
+	}
+	return &dnsStreamConn{Conn: conn, d: d}, nil
+}
+
+// dnsPacketConn observes each DNS message read from a packet connection
+type dnsPacketConn struct {
+	net.Conn
+	pc net.PacketConn
+	d  *dnsCache
+}
+
+func (c *dnsPacketConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.d.observe(b[:n])
+	}
+	return n, err
+}
+
+func (c *dnsPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.pc.ReadFrom(b)
+	if n > 0 {
+		c.d.observe(b[:n])
+	}
+	return n, addr, err
+}
+
+func (c *dnsPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.pc.WriteTo(b, addr)
+}
+
+// dnsStreamConn observes each length-prefixed DNS message read from a
+// stream connection
+type dnsStreamConn struct {
+	net.Conn
+	d   *dnsCache
+	buf []byte
+}
+
+func (c *dnsStreamConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.buf = append(c.buf, b[:n]...)
+	for len(c.buf) >= 2 {
+		size := 2 + int(binary.BigEndian.Uint16(c.buf))
+		if len(c.buf) < size {
+			break
+		}
+		c.d.observe(c.buf[2:size])
+		c.buf = c.buf[size:]
+	}
+	return n, err
+}
+
+// dialContext dials the address, looking its host up in the cache, and
+// trying each of its addresses in turn until one connects
+func (d *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		addrs, err := d.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var firstErr error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if firstErr == nil {
+			firstErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		return nil, firstErr
+	}
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeNameserver answers A queries for the hosts with their address, with
+// the given TTL, counting the queries it receives
+func fakeNameserver(t *testing.T, hosts map[string][4]byte, ttl uint32) (string, *atomic.Int32) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	queries := &atomic.Int32{}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			queries.Add(1)
+
+			query := dnsmessage.Message{}
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			if ip, ok := hosts[q.Name.String()]; ok && q.Type == dnsmessage.TypeA {
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+					Body:   &dnsmessage.AResource{A: ip},
+				}}
+			}
+			msg, _ := resp.Pack()
+			conn.WriteTo(msg, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), queries
+}
+
+func TestDNSCache(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer site.Close()
+	_, port, _ := net.SplitHostPort(site.Listener.Addr().String())
+
+	nameserver, queries := fakeNameserver(t, map[string][4]byte{
+		"img.test.":   {127, 0, 0, 1},
+		"other.test.": {127, 0, 0, 2},
+	}, 60)
+
+	d := newDNSCache(DNSCacheOptions{Size: 1, DefaultTTL: time.Hour}, nil)
+	d.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "udp", nameserver)
+	}
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	dial := d.dialContext(&net.Dialer{})
+	for i := 0; i < 3; i++ {
+		conn, err := dial(context.Background(), "tcp", net.JoinHostPort("img.test", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	// one lookup, of A and AAAA records
+	if n := queries.Load(); n != 2 {
+		t.Fatalf("sent %d queries for 3 connections, want 2", n)
+	}
+
+	// the records' 60s TTL applies, not the default
+	now = now.Add(61 * time.Second)
+	if _, err := d.lookup(context.Background(), "img.test"); err != nil {
+		t.Fatal(err)
+	}
+	if n := queries.Load(); n != 4 {
+		t.Fatalf("sent %d queries after the TTL expired, want 4", n)
+	}
+
+	// beyond Size, the least recently used is evicted
+	addrs, err := d.lookup(context.Background(), "other.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(127, 0, 0, 2)) {
+		t.Errorf("other.test resolved to %v, want 127.0.0.2", addrs)
+	}
+	if _, err := d.lookup(context.Background(), "IMG.test."); err != nil {
+		t.Fatal(err)
+	}
+	if n := queries.Load(); n != 8 {
+		t.Fatalf("sent %d queries after eviction, want 8", n)
+	}
+}
@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// DefaultMaxDownloadBytes is the largest image downloaded by default
+const DefaultMaxDownloadBytes = 100 << 20
+
+// DefaultSignedURLParams are query parameters that commonly indicate a
+// signed or expiring URL (S3, GCS, CloudFront, Azure SAS and generic tokens)
+var DefaultSignedURLParams = []string{
+	"X-Amz-Signature",
+	"X-Amz-Expires",
+	"X-Goog-Signature",
+	"X-Goog-Expires",
+	"Signature",
+	"Key-Pair-Id",
+	"sig",
+	"se",
+	"token",
+	"expires",
+}
+
+// isSignedURL reports whether the URL carries any of the given query params
+func isSignedURL(url string, params []string) bool {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return false
+	}
+
+	query := u.Query()
+	for _, p := range params {
+		for k := range query {
+			if strings.EqualFold(k, p) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// download saves the resource at url into the download directory, adding a
+// record of where it was written to the batch
+func (c *Crawler) download(ctx context.Context, b *batch, url string) error {
+	if c.DownloadDir == "" {
+		return fmt.Errorf("cannot download %s: no download directory configured", url)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	if c.MaxDownloadBytes > 0 && resp.ContentLength > c.MaxDownloadBytes {
+		return fmt.Errorf("downloading %s: %d bytes is larger than the %d allowed", url, resp.ContentLength, c.MaxDownloadBytes)
+	}
+
+	dest, n, err := c.saveDownload(resp.Body, downloadFilename(url, resp.Header.Get("content-type")))
+	if err != nil {
+		return err
+	}
+
+	b.add("HSET", c.KeyDownloads, url, dest)
+	c.recordImageSize(b, url, imageSize{ContentType: resp.Header.Get("content-type"), Bytes: n})
+	return nil
+}
+
+// saveDownload writes r to the named file in the download directory,
+// returning its path and size. Anything larger than MaxDownloadBytes is
+// abandoned, leaving no file behind.
+func (c *Crawler) saveDownload(r io.Reader, name string) (string, int64, error) {
+	if err := os.MkdirAll(c.DownloadDir, 0755); err != nil {
+		return "", 0, err
+	}
+
+	// write to a temp file first so partial downloads are never visible
+	tmp, err := os.CreateTemp(c.DownloadDir, ".download-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	limit := c.MaxDownloadBytes
+	if limit > 0 {
+		// one byte over is enough to tell it's too large
+		r = io.LimitReader(r, limit+1)
+	}
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && limit > 0 && n > limit {
+		err = fmt.Errorf("download is larger than the %d bytes allowed", limit)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	dest := filepath.Join(c.DownloadDir, name)
+	return dest, n, os.Rename(tmp.Name(), dest)
+}
+
+// urlHash is a stable, filesystem-safe name for a URL
+func urlHash(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// downloadFilename derives a filename from the URL, keeping its extension
+func downloadFilename(url string, contentType string) string {
+	name := urlHash(url)
+
+	ext := ""
+	if u, err := neturl.Parse(url); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+
+	return name + ext
+}
+
+// DownloadPath returns where an image was downloaded to, or "" if it wasn't
+func (c *Crawler) DownloadPath(url string) (string, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	path, err := redis.String(conn.Do("HGET", c.KeyDownloads, url))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return path, err
+}
@@ -0,0 +1,150 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsSignedURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://bucket.s3.amazonaws.com/a.jpg?X-Amz-Signature=abc&X-Amz-Expires=60", true},
+		{"https://storage.googleapis.com/b/a.jpg?x-goog-signature=abc", true},
+		{"https://cdn.example/a.jpg?Expires=1&Signature=abc&Key-Pair-Id=K", true},
+		{"https://acct.blob.core.windows.net/c/a.jpg?sv=2020&se=2030-01-01&sig=abc", true},
+		{"https://example.com/a.jpg?TOKEN=abc", true},
+		{"https://example.com/a.jpg?w=100&h=100", false},
+		{"https://example.com/signature/a.jpg", false},
+		{"https://example.com/a.jpg", false},
+		{"%zz", false},
+	}
+	for _, tt := range tests {
+		if got := isSignedURL(tt.url, DefaultSignedURLParams); got != tt.want {
+			t.Errorf("isSignedURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	if isSignedURL("https://example.com/a.jpg?token=abc", []string{"auth"}) {
+		t.Error("isSignedURL matched a param not asked for")
+	}
+}
+
+func TestDownloadFilename(t *testing.T) {
+	tests := []struct {
+		url, contentType string
+		want             string
+	}{
+		{"https://example.com/a.jpg?sig=1", "image/png", ".jpg"},
+		{"https://example.com/a", "image/png", ".png"},
+		{"https://example.com/a", "application/x-unknown", ""},
+		{"https://example.com/a", "", ""},
+	}
+	for _, tt := range tests {
+		got := downloadFilename(tt.url, tt.contentType)
+		if want := urlHash(tt.url) + tt.want; got != want {
+			t.Errorf("downloadFilename(%q, %q) = %q, want %q", tt.url, tt.contentType, got, want)
+		}
+	}
+	if downloadFilename("https://example.com/a", "") == downloadFilename("https://example.com/b", "") {
+		t.Error("different URLs got the same filename")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	img := "\x89PNG\r\n\x1a\nnot really"
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.png":
+			w.Write([]byte(img))
+		case "/streamed":
+			// sent chunked, so its size is only found by reading it
+			w.Header().Set("Content-Type", "image/png")
+			w.(http.Flusher).Flush()
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/declared":
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte(strings.Repeat("x", 100)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.DownloadDir = filepath.Join(t.TempDir(), "downloads")
+	c.MaxDownloadBytes = int64(len(img))
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	b := batch{}
+	if err := c.download(context.Background(), &b, site.URL+"/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/streamed", "/declared", "/missing"} {
+		if err := c.download(context.Background(), &b, site.URL+path); err == nil {
+			t.Errorf("download(%s) succeeded, want an error", path)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.download(ctx, &b, site.URL+"/a.png"); err == nil {
+		t.Error("download with a cancelled context succeeded")
+	}
+	if err := b.exec(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := c.DownloadPath(site.URL + "/a.png")
+	if err != nil || path != filepath.Join(c.DownloadDir, downloadFilename(site.URL+"/a.png", "")) {
+		t.Fatalf("download path = %q, %v", path, err)
+	}
+	if saved, err := os.ReadFile(path); err != nil || string(saved) != img {
+		t.Errorf("saved %q, %v, want the image", saved, err)
+	}
+	if path, _ := c.DownloadPath(site.URL + "/streamed"); path != "" {
+		t.Errorf("oversized download recorded at %q", path)
+	}
+
+	// nothing is left behind by the downloads abandoned
+	files, _ := os.ReadDir(c.DownloadDir)
+	if len(files) != 1 {
+		t.Errorf("download directory holds %v, want just the image", files)
+	}
+
+	c.DownloadDir = ""
+	if err := c.download(context.Background(), &b, site.URL+"/a.png"); err == nil {
+		t.Error("download without a directory succeeded")
+	}
+}
+
+func TestDownloadSigned(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<img src="/signed.jpg?X-Amz-Signature=abc"><img src="/plain.jpg">`))
+			return
+		}
+		w.Write([]byte("jpeg"))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.DownloadDir = t.TempDir()
+	c.DownloadSigned = true
+	c.Seed(site.URL)
+	c.Run()
+
+	if path, _ := c.DownloadPath(site.URL + "/signed.jpg?X-Amz-Signature=abc"); filepath.Ext(path) != ".jpg" {
+		t.Errorf("signed image downloaded to %q, want a .jpg", path)
+	}
+	if path, _ := c.DownloadPath(site.URL + "/plain.jpg"); path != "" {
+		t.Errorf("unsigned image downloaded to %q", path)
+	}
+}
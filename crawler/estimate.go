@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"context"
+	"time"
+)
+
+// Estimate extrapolates the cost of a full crawl from a sample crawl
+type Estimate struct {
+	SampledPages   int
+	SampledImages  int // distinct
+	SampledBytes   int64
+	SampleDuration time.Duration
+	Workers        int
+
+	// Discovered is the pages visited or queued after the sample, a lower
+	// bound on the site's size
+	Discovered int
+	// Complete is set if the sample crawled the whole site
+	Complete bool
+
+	// Pages is the estimated total, 0 if the sample saw too little of the
+	// site's link structure to estimate from. Images, Bytes and Duration are
+	// scaled up from the sample in proportion, Duration assuming the same
+	// number of workers. Images is an upper bound when images repeat across
+	// pages.
+	Pages    int
+	Images   int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Estimate crawls up to budget pages with the given number of workers and
+// extrapolates the size of the whole crawl. The sample is recorded like any
+// other crawl, so run it under a throwaway job.
+//
+// The total page count is a capture-recapture estimate: links found in the
+// first and second halves of the sample are treated as two independent
+// samples of the site, and the more they overlap the smaller the site.
+func (c *Crawler) Estimate(ctx context.Context, budget int, workers int) (Estimate, error) {
+	est := Estimate{Workers: workers}
+
+	maxPages := c.MaxPages
+	c.MaxPages = budget
+	defer func() { c.MaxPages = maxPages }()
+
+	pages := c.Pages()
+	sample := make(chan []PageRecord)
+	go func() {
+		recs := []PageRecord{}
+		for rec := range pages {
+			recs = append(recs, rec)
+		}
+		sample <- recs
+	}()
+
+	start := time.Now()
+	c.RunNContext(ctx, workers)
+	est.SampleDuration = time.Since(start)
+	recs := <-sample
+
+	info, err := c.Info()
+	if err != nil {
+		return est, err
+	}
+	est.Discovered = info.Visited + info.Queued
+	est.Complete = info.Queued == 0
+
+	images := map[string]bool{}
+	for _, rec := range recs {
+		est.SampledBytes += rec.Bytes
+		for _, img := range rec.Images {
+			images[img] = true
+		}
+	}
+	est.SampledPages = len(recs)
+	est.SampledImages = len(images)
+
+	switch {
+	case est.Complete:
+		est.Pages = est.Discovered
+	case len(recs) >= 2:
+		est.Pages = recaptureEstimate(recs[:len(recs)/2], recs[len(recs)/2:])
+		if est.Pages > 0 && est.Pages < est.Discovered {
+			est.Pages = est.Discovered
+		}
+	}
+
+	if est.Pages > 0 && est.SampledPages > 0 {
+		scale := float64(est.Pages) / float64(est.SampledPages)
+		est.Images = int(float64(est.SampledImages) * scale)
+		est.Bytes = int64(float64(est.SampledBytes) * scale)
+		est.Duration = time.Duration(float64(est.SampleDuration) * scale)
+	}
+
+	return est, nil
+}
+
+// recaptureEstimate is the Lincoln-Petersen estimate of the number of pages
+// given the links found by two samples, 0 if they don't overlap
+func recaptureEstimate(first []PageRecord, second []PageRecord) int {
+	a := map[string]bool{}
+	for _, rec := range first {
+		for _, l := range rec.Links {
+			a[l] = true
+		}
+	}
+
+	b := map[string]bool{}
+	overlap := 0
+	for _, rec := range second {
+		for _, l := range rec.Links {
+			if b[l] {
+				continue
+			}
+			b[l] = true
+			if a[l] {
+				overlap++
+			}
+		}
+	}
+
+	if overlap == 0 {
+		return 0
+	}
+	return len(a) * len(b) / overlap
+}
@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newTestSite serves n pages, /0 to /n-1, each with an image of its own and
+// links to the next links pages around the site, and with scatter links to
+// pages spread about it as well
+func newTestSite(t *testing.T, n int, links int, scatter int) *httptest.Server {
+	t.Helper()
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(r.URL.Path[1:])
+		if err != nil || i >= n {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<img src="/%d.png">`, i)
+		for k := 1; k <= links; k++ {
+			fmt.Fprintf(w, `<a href="/%d">%d</a>`, (i+k)%n, k)
+		}
+		for k := 1; k <= scatter; k++ {
+			fmt.Fprintf(w, `<a href="/%d">%d</a>`, (i*31+k*17)%n, k)
+		}
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+func TestMaxPages(t *testing.T) {
+	site := newTestSite(t, 10, 3, 0)
+	c, _ := newTestCrawler(t)
+	c.MaxPages = 3
+	c.Seed(site.URL + "/0")
+
+	for run, workers := range []int{1, 4} {
+		c.RunN(workers)
+		info, err := c.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 3 * (run + 1); info.Visited != want || info.Queued == 0 {
+			t.Errorf("after run %d with %d workers: visited %d, %d queued, want %d visited and the rest left queued", run+1, workers, info.Visited, info.Queued, want)
+		}
+	}
+}
+
+func TestRecaptureEstimate(t *testing.T) {
+	page := func(links ...string) PageRecord { return PageRecord{Links: links} }
+	tests := []struct {
+		first, second []PageRecord
+		want          int
+	}{
+		{[]PageRecord{page("1", "2"), page("3", "4")}, []PageRecord{page("3", "4", "5", "6")}, 8},
+		{[]PageRecord{page("1", "2")}, []PageRecord{page("1", "2"), page("2", "1")}, 2},
+		{[]PageRecord{page("1", "2")}, []PageRecord{page("3")}, 0},
+		{nil, []PageRecord{page("1")}, 0},
+	}
+	for _, tt := range tests {
+		if got := recaptureEstimate(tt.first, tt.second); got != tt.want {
+			t.Errorf("recaptureEstimate(%v, %v) = %d, want %d", tt.first, tt.second, got, tt.want)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	// a site small enough to be sampled whole
+	c, _ := newTestCrawler(t)
+	c.Seed(newTestSite(t, 5, 2, 0).URL + "/0")
+	est, err := c.Estimate(context.Background(), 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Complete || est.Pages != 5 || est.SampledPages != 5 || est.Images != 5 || est.Workers != 1 {
+		t.Errorf("estimate = %+v, want the whole site of 5 pages sampled", est)
+	}
+
+	c, _ = newTestCrawler(t)
+	c.MaxPages = 100
+	c.Seed(newTestSite(t, 200, 5, 5).URL + "/0")
+	est, err = c.Estimate(context.Background(), 20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Complete || est.SampledPages != 20 || est.SampledImages != 20 || est.SampledBytes == 0 {
+		t.Errorf("estimate = %+v, want a sample of 20 pages", est)
+	}
+	if est.Pages == 0 || est.Pages < est.Discovered || est.Images < est.SampledImages || est.Bytes < est.SampledBytes || est.Duration < est.SampleDuration {
+		t.Errorf("estimate = %+v, want at least what was discovered and sampled", est)
+	}
+	if c.MaxPages != 100 {
+		t.Errorf("MaxPages = %d after estimating, want it restored", c.MaxPages)
+	}
+}
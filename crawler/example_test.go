@@ -0,0 +1,42 @@
+package crawler_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/daveagill/go-imgcrawler/crawler"
+)
+
+func ExampleCrawlSite() {
+	images, err := crawler.CrawlSite(context.Background(), "https://example.com", crawler.SiteOptions{
+		MaxPages: 100,
+		Presets:  []crawler.Preset{crawler.PresetPolite, crawler.PresetMetadata},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, img := range images {
+		fmt.Printf("%s %dx%d, found on %d pages\n", img.URL, img.Width, img.Height, len(img.Pages))
+	}
+}
+
+func ExampleNew() {
+	c := crawler.New(crawler.NewPool("tcp", "localhost:6379"))
+	c.MaxPages = 100
+	crawler.PresetDedupe(c)
+
+	images := c.Images()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for img := range images {
+			fmt.Println(img.URL, "on", img.PageURL)
+		}
+	}()
+
+	c.Seed("https://example.com")
+	c.RunN(4)
+	<-done
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// The classes of failed pages
+const (
+	FailedClientError = "4xx"   // the server answered 400-499, e.g. broken links
+	FailedServerError = "5xx"   // the server answered 500-599
+	FailedFetch       = "error" // the page couldn't be fetched, or read
+)
+
+// failedClasses are every class of failed pages
+var failedClasses = []string{FailedClientError, FailedServerError, FailedFetch}
+
+// FailedPage is a page that failed, as last crawled, so broken links can be
+// found and fixed
+type FailedPage struct {
+	URL    string `json:"url"`
+	Class  string `json:"class"`            // FailedClientError, FailedServerError or FailedFetch
+	Status int    `json:"status,omitempty"` // 0 if the fetch failed
+	Reason string `json:"reason"`
+	// Parent is the page that linked to it, if any
+	Parent   string    `json:"parent,omitempty"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// failure classes a page's record, returning its FailedPage, or false if
+// it didn't fail
+func failure(rec PageRecord) (FailedPage, bool) {
+	f := FailedPage{URL: rec.URL, Status: rec.Status, Reason: rec.Error, Parent: rec.Parent, FailedAt: rec.FetchedAt}
+	switch {
+	case rec.Status >= 500:
+		f.Class = FailedServerError
+	case rec.Status >= 400:
+		f.Class = FailedClientError
+	case rec.Error != "":
+		f.Class = FailedFetch
+	default:
+		return f, false
+	}
+	if f.Reason == "" {
+		f.Reason = fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status))
+	}
+	return f, true
+}
+
+// failedKey is the key of the hash of failed pages of the class, by URL
+func (c *Crawler) failedKey(class string) string {
+	return c.KeyFailed + ":" + class
+}
+
+// recordFailure adds writes to the batch keeping the page in the failed
+// pages of its class, or, when it didn't fail, as on a re-crawl after it
+// was fixed, in none
+func (c *Crawler) recordFailure(b *batch, rec PageRecord) {
+	f, failed := failure(rec)
+	for _, class := range failedClasses {
+		if failed && class == f.Class {
+			if data, err := json.Marshal(f); err == nil {
+				b.add("HSET", c.failedKey(class), rec.URL, data)
+			}
+		} else {
+			b.add("HDEL", c.failedKey(class), rec.URL)
+		}
+	}
+}
+
+// FailedPages returns the pages that failed of the class, or of every
+// class if it's empty, by URL
+func (c *Crawler) FailedPages(class string) ([]FailedPage, error) {
+	classes := failedClasses
+	if class != "" {
+		classes = []string{class}
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	pages := []FailedPage{}
+	for _, class := range classes {
+		records, err := redis.StringMap(conn.Do("HGETALL", c.failedKey(class)))
+		if err != nil {
+			return nil, err
+		}
+		for url, data := range records {
+			f := FailedPage{}
+			if err := json.Unmarshal([]byte(data), &f); err != nil {
+				f = FailedPage{URL: url, Class: class}
+			}
+			pages = append(pages, f)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].URL != pages[j].URL {
+			return pages[i].URL < pages[j].URL
+		}
+		return pages[i].Class < pages[j].Class
+	})
+	return pages, nil
+}
+
+// failedCounts counts the pages that failed by class
+func (c *Crawler) failedCounts(conn redis.Conn) (map[string]int, error) {
+	for _, class := range failedClasses {
+		conn.Send("HLEN", c.failedKey(class))
+	}
+	counts, err := redis.Ints(conn.Do(""))
+	if err != nil {
+		return nil, err
+	}
+
+	failed := map[string]int{}
+	for i, class := range failedClasses {
+		failed[class] = counts[i]
+	}
+	return failed, nil
+}
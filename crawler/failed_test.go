@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFailedPages(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/missing">Missing</a><a href="/broken">Broken</a><a href="/ok">OK</a>`))
+		case "/broken":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		case "/ok":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<p>OK</p>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.MaxRetries = 0
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	failed, err := c.FailedPages("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("failed pages = %+v, want /broken and /missing", failed)
+	}
+	if f := failed[0]; f.URL != site.URL+"/broken" || f.Class != FailedServerError || f.Status != 500 || f.Parent != site.URL+"/" {
+		t.Errorf("failed[0] = %+v, want /broken, 5xx, 500, linked from /", f)
+	}
+	if f := failed[1]; f.URL != site.URL+"/missing" || f.Class != FailedClientError || f.Reason != "404 Not Found" {
+		t.Errorf("failed[1] = %+v, want /missing, 4xx, 404 Not Found", f)
+	}
+
+	if only, _ := c.FailedPages(FailedClientError); len(only) != 1 || only[0].URL != site.URL+"/missing" {
+		t.Errorf("4xx pages = %+v, want only /missing", only)
+	}
+	status, _ := c.Status()
+	if status.Failed[FailedClientError] != 1 || status.Failed[FailedServerError] != 1 || status.Failed[FailedFetch] != 0 {
+		t.Errorf("Status().Failed = %v, want one 4xx and one 5xx", status.Failed)
+	}
+
+	// once fixed, it's no longer failed
+	b := batch{}
+	c.recordFailure(&b, PageRecord{URL: site.URL + "/missing", Status: 200})
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	if err := b.exec(conn); err != nil {
+		t.Fatal(err)
+	}
+	if only, _ := c.FailedPages(FailedClientError); len(only) != 0 {
+		t.Errorf("4xx pages after fixing = %+v, want none", only)
+	}
+}
@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// fetch GETs the page with any extra request headers, retrying network
+// errors and retryable statuses with exponential backoff. The final response
+// is returned whatever its status.
+func (c *Crawler) fetch(ctx context.Context, url string, header http.Header, logger *slog.Logger) (*http.Response, error) {
+	backoff := c.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		start := time.Now()
+		resp, err := c.client().Do(req)
+		c.metrics.observeFetch(resp, err, time.Since(start))
+
+		if attempt >= c.MaxRetries || !isRetryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		c.metrics.observeRetry()
+		logger.Debug("retrying fetch", "url", url, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// bodyLimitReader fails reads past a limit, so that oversized pages are
+// abandoned part way through rather than read in full
+type bodyLimitReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	exceeded  bool
+}
+
+// newBodyLimitReader limits r to limit bytes, or not at all if limit is 0
+func newBodyLimitReader(r io.Reader, limit int64) *bodyLimitReader {
+	return &bodyLimitReader{r: r, limit: limit, remaining: limit}
+}
+
+func (l *bodyLimitReader) Read(p []byte) (int, error) {
+	if l.limit <= 0 {
+		return l.r.Read(p)
+	}
+	if l.remaining <= 0 {
+		// a body of exactly the limit is fine, only one going on isn't
+		if n, _ := l.r.Read(make([]byte, 1)); n > 0 {
+			l.exceeded = true
+			return 0, l.err()
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// oversizedError is the error recorded for a page declared larger than the
+// limit
+func oversizedError(n int64, limit int64) error {
+	return fmt.Errorf("page of %d bytes is larger than the %d allowed", n, limit)
+}
+
+func (l *bodyLimitReader) err() error {
+	return fmt.Errorf("page is larger than the %d bytes allowed", l.limit)
+}
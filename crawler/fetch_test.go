@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	page := `<img src="/a.png">` + strings.Repeat(" ", 100)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/declared", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/streamed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+		// flushing sends the body chunked, without a Content-Length
+		w.(http.Flusher).Flush()
+		w.Write([]byte(page))
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.MaxBodyBytes = int64(len(page))
+
+	got := c.scrape(t.Context(), site.URL+"/declared", c.Logger)
+	if got.err != nil || len(got.imgSrcs) != 1 {
+		t.Errorf("page of exactly MaxBodyBytes: err = %v, imgSrcs = %v", got.err, got.imgSrcs)
+	}
+
+	got = c.scrape(t.Context(), site.URL+"/streamed", c.Logger)
+	if got.err == nil || len(got.imgSrcs) != 0 {
+		t.Errorf("oversized page: err = %v, imgSrcs = %v, want an error and no images", got.err, got.imgSrcs)
+	}
+	if got.bytes > c.MaxBodyBytes {
+		t.Errorf("read %d bytes of the oversized page, want at most %d", got.bytes, c.MaxBodyBytes)
+	}
+
+	c.MaxBodyBytes = 10
+	got = c.scrape(t.Context(), site.URL+"/declared", c.Logger)
+	if got.err == nil || got.bytes != 0 {
+		t.Errorf("page declared oversized: err = %v, bytes = %d, want an error before reading", got.err, got.bytes)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.RequestTimeout = 50 * time.Millisecond
+	c.MaxRetries = 0
+
+	start := time.Now()
+	got := c.scrape(t.Context(), site.URL, c.Logger)
+	if got.err == nil {
+		t.Error("slow page: no error, want a timeout")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("slow page took %s, want it abandoned after the timeout", time.Since(start))
+	}
+}
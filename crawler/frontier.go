@@ -0,0 +1,255 @@
+package crawler
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// EntryVersion is the version of the Entry format queued by this code.
+// Entries queued by older versions decode with the fields they lack left
+// zero, and by newer ones with the fields added since ignored, so mixed
+// versions can share a queue while they're rolled out.
+const EntryVersion = 1
+
+// Entry is a URL waiting in the crawl queue, along with how it was found
+type Entry struct {
+	// Version is the EntryVersion it was queued under, 0 for entries queued
+	// before entries were versioned
+	Version    int       `json:"v,omitempty" msgpack:"v,omitempty"`
+	URL        string    `json:"url" msgpack:"url"`
+	Depth      int       `json:"depth,omitempty" msgpack:"depth,omitempty"`
+	Parent     string    `json:"parent,omitempty" msgpack:"parent,omitempty"`
+	Discovered time.Time `json:"discovered,omitzero" msgpack:"discovered,omitempty"`
+	Priority   float64   `json:"priority,omitempty" msgpack:"priority,omitempty"`
+	Retries    int       `json:"retries,omitempty" msgpack:"retries,omitempty"`
+	// Reclaimed marks an entry put back unfinished after it was marked as
+	// visited, with a VisitedBloom that can't be unmarked, so it's crawled
+	// regardless
+	Reclaimed bool `json:"reclaimed,omitempty" msgpack:"reclaimed,omitempty"`
+	// Frame is how many frames deep the page is embedded, with
+	// FollowFrames, 0 for pages that were linked to
+	Frame int `json:"frame,omitempty" msgpack:"frame,omitempty"`
+	// Trace is the traceparent of the span of the page that queued it, when
+	// tracing, which the span of crawling it links to
+	Trace string `json:"trace,omitempty" msgpack:"trace,omitempty"`
+
+	visited bool // marked as visited as it was claimed
+}
+
+// Codec serializes queue entries, every worker sharing a queue must use the
+// same codec
+type Codec interface {
+	Marshal(e Entry) ([]byte, error)
+	Unmarshal(data []byte, e *Entry) error
+}
+
+// JSONCodec stores entries as JSON, readable with redis-cli
+type JSONCodec struct{}
+
+// Marshal implements Codec
+func (JSONCodec) Marshal(e Entry) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal implements Codec
+func (JSONCodec) Unmarshal(data []byte, e *Entry) error {
+	return json.Unmarshal(data, e)
+}
+
+// MsgpackCodec stores entries as msgpack, which is more compact than JSON
+type MsgpackCodec struct{}
+
+// Marshal implements Codec
+func (MsgpackCodec) Marshal(e Entry) ([]byte, error) {
+	return msgpack.Marshal(&e)
+}
+
+// Unmarshal implements Codec
+func (MsgpackCodec) Unmarshal(data []byte, e *Entry) error {
+	return msgpack.Unmarshal(data, e)
+}
+
+// PriorityFunc scores a URL found on the crawl, pages with the highest scores
+// being crawled first. depth is how many links the URL is from a seed and
+// source the page it was found on, nil for seeds.
+type PriorityFunc func(url string, depth int, source *Page) float64
+
+// priority scores a URL by the Priority hook, 0 if there isn't one
+func (c *Crawler) priority(url string, depth int, source *Page) float64 {
+	if c.Priority == nil {
+		return 0
+	}
+	return c.Priority(url, depth, source)
+}
+
+// push adds entries to the crawl queue in a single round trip
+func (c *Crawler) push(conn redis.Conn, entries ...Entry) error {
+	if _, err := c.upgradeQueue(conn); err != nil {
+		return err
+	}
+
+	b := batch{}
+	if err := c.enqueue(&b, entries...); err != nil {
+		return err
+	}
+	return b.exec(conn)
+}
+
+// enqueueScript queues URLs, each once however many pages link to it. The
+// queue holds just the URLs, scored by priority, and a hash their entries.
+// A URL queued again keeps the entry it was first queued with, its score
+// only raised if it's found with a higher priority. When ARGV[1] is "put
+// back", for entries claimed and returned, the entries replace those
+// queued, and if ARGV[2] isn't 0 aren't to be claimed before it. With "skip
+// visited" URLs already in the set of pages visited are left out. The
+// queue's epoch advances when anything is queued.
+//
+//	KEYS[1] the crawl queue, KEYS[2] its entries, KEYS[3] the pages visited,
+//	KEYS[4] the queue's epoch, KEYS[5] the deferred URLs
+//	ARGV[1] the mode, ARGV[2] the time, in Unix milliseconds, entries put
+//	back are deferred until, then the URL, priority and encoded entry of each
+var enqueueScript = redis.NewScript(5, `
+local changed = 0
+for i = 3, #ARGV, 3 do
+	local url, score, entry = ARGV[i], ARGV[i + 1], ARGV[i + 2]
+	if ARGV[1] == "put back" then
+		redis.call("ZADD", KEYS[1], "GT", score, url)
+		redis.call("HSET", KEYS[2], url, entry)
+		if ARGV[2] ~= "0" then
+			redis.call("ZADD", KEYS[5], ARGV[2], url)
+		end
+		changed = changed + 1
+	elseif ARGV[1] ~= "skip visited" or redis.call("SISMEMBER", KEYS[3], url) == 0 then
+		changed = changed + redis.call("ZADD", KEYS[1], "GT", "CH", score, url)
+		redis.call("HSETNX", KEYS[2], url, entry)
+	end
+end
+if changed > 0 then
+	redis.call("INCR", KEYS[4])
+end
+return changed
+`)
+
+// enqueue adds a write of entries to the crawl queue to the batch, each
+// scored by its Priority. URLs already queued aren't queued again, but
+// move up if found with a higher priority, and pages already visited are
+// left out. New entries are stamped with the time they were discovered.
+// Entries on the DenyHosts are left out.
+func (c *Crawler) enqueue(b *batch, entries ...Entry) error {
+	mode := ""
+	if c.VisitedBloom == nil || c.VisitedBloom.KeepExact {
+		mode = "skip visited"
+	}
+	return c.queue(b, mode, time.Time{}, entries)
+}
+
+// putBack adds a write returning claimed entries to the crawl queue to the
+// batch, as they are, keeping the time they were discovered
+func (c *Crawler) putBack(b *batch, entries ...Entry) error {
+	return c.queue(b, "put back", time.Time{}, entries)
+}
+
+// deferUntil adds a write returning a claimed entry to the crawl queue to
+// the batch, as putBack, but not to be claimed again before notBefore. It
+// keeps its place in the queue, so the order pages are crawled in holds.
+func (c *Crawler) deferUntil(b *batch, notBefore time.Time, entry Entry) error {
+	return c.queue(b, "put back", notBefore, []Entry{entry})
+}
+
+func (c *Crawler) queue(b *batch, mode string, notBefore time.Time, entries []Entry) error {
+	if len(c.DenyHosts) > 0 {
+		entries = slices.DeleteFunc(slices.Clone(entries), func(e Entry) bool { return c.denied(e.URL) })
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	deferred := int64(0)
+	if !notBefore.IsZero() {
+		deferred = notBefore.UnixMilli()
+	}
+	args := make([]interface{}, 0, 3*len(entries)+7)
+	args = append(args, c.KeyCrawlQ, c.queueEntriesKey(), c.KeyVisitedHREFs, c.KeyEpoch, c.queueDeferredKey(), mode, deferred)
+	for _, e := range entries {
+		e.Version = EntryVersion
+		if e.Discovered.IsZero() {
+			e.Discovered = now
+		}
+		data, err := c.Codec.Marshal(e)
+		if err != nil {
+			return err
+		}
+		args = append(args, e.URL, e.Priority, data)
+	}
+
+	b.addScript(enqueueScript, args...)
+	return nil
+}
+
+// queueEntriesKey is the key of the hash of the entries queued, by URL
+func (c *Crawler) queueEntriesKey() string {
+	return c.KeyCrawlQ + ":entries"
+}
+
+// queueDeferredKey is the key of the sorted set of the URLs queued that
+// aren't to be claimed before their scores, in Unix milliseconds, see
+// claimHost
+func (c *Crawler) queueDeferredKey() string {
+	return c.KeyCrawlQ + ":deferred"
+}
+
+// queueLen is the number of entries waiting in the crawl queue
+func (c *Crawler) queueLen(conn redis.Conn) (int, error) {
+	return redis.Int(conn.Do("ZCARD", c.KeyCrawlQ))
+}
+
+// upgradeQueue converts a crawl queue left by earlier versions, an unordered
+// set, into the sorted set queued to now, returning how many of its members
+// were bare v1 URLs rather than entries. The set is first moved aside, so an
+// interrupted upgrade picks up where it left off.
+func (c *Crawler) upgradeQueue(conn redis.Conn) (int, error) {
+	old := c.KeyCrawlQ + ":set"
+
+	kind, err := redis.String(conn.Do("TYPE", c.KeyCrawlQ))
+	if err != nil {
+		return 0, err
+	}
+	if kind == "set" {
+		if _, err := conn.Do("RENAME", c.KeyCrawlQ, old); err != nil {
+			return 0, err
+		}
+	} else if exists, err := redis.Bool(conn.Do("EXISTS", old)); err != nil || !exists {
+		return 0, err
+	}
+
+	bare := 0
+	err = scanSet(conn, old, func(members []string) error {
+		entries := make([]Entry, 0, len(members))
+		for _, m := range members {
+			e := Entry{}
+			if err := c.Codec.Unmarshal([]byte(m), &e); err != nil || e.URL == "" {
+				e = Entry{URL: m}
+				bare++
+			}
+			entries = append(entries, e)
+		}
+
+		b := batch{}
+		if err := c.enqueue(&b, entries...); err != nil {
+			return err
+		}
+		return b.exec(conn)
+	})
+	if err != nil {
+		return bare, err
+	}
+
+	c.Logger.Info("converted the crawl queue to a priority queue", "queue", c.KeyCrawlQ)
+	_, err = conn.Do("DEL", old)
+	return bare, err
+}
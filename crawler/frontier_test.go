@@ -0,0 +1,288 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+
+	scores := map[string]float64{"/c": 3, "/a": 2, "/b": 1}
+	c.Priority = func(url string, depth int, source *Page) float64 {
+		if depth != 1 || source == nil || len(source.Links) != 3 {
+			t.Errorf("Priority(%s) called with depth %d, source %v", url, depth, source)
+		}
+		return scores[strings.TrimPrefix(url, site.URL)]
+	}
+	crawled := []string{}
+	c.OnPageCrawled = func(p *Page) error {
+		crawled = append(crawled, strings.TrimPrefix(p.URL, site.URL))
+		return nil
+	}
+
+	c.Run()
+
+	if want := []string{"/", "/c", "/a", "/b"}; !slices.Equal(crawled, want) {
+		t.Errorf("crawled %v, want %v", crawled, want)
+	}
+}
+
+func TestQueueDedupes(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/">home</a><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>`))
+	}))
+	defer site.Close()
+
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		c, mr := newTestCrawler(t)
+		c.Codec = codec
+		c.MaxPages = 3
+		c.Seed(site.URL)
+		c.Run()
+
+		// every page links to every other, but each URL is queued once and
+		// those visited not again
+		queued, _ := mr.ZMembers(c.KeyCrawlQ)
+		if len(queued) != 1 {
+			t.Errorf("%T: queue = %v, want the one page left", codec, queued)
+		}
+		for _, url := range queued {
+			if visited, _ := c.Visited(url); visited {
+				t.Errorf("%T: visited page %s queued again", codec, url)
+			}
+		}
+		if entries, _ := mr.HKeys(c.queueEntriesKey()); !slices.Equal(entries, queued) {
+			t.Errorf("%T: queued entries %v, want %v", codec, entries, queued)
+		}
+	}
+
+	// found again with a higher priority it moves up, but not down
+	c, mr := newTestCrawler(t)
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	c.push(conn, Entry{URL: "https://example.com/", Priority: 1, Parent: "https://example.com/first"})
+	c.push(conn, Entry{URL: "https://example.com/", Priority: 3, Parent: "https://example.com/second"})
+	c.push(conn, Entry{URL: "https://example.com/", Priority: 2})
+	if score, _ := mr.ZScore(c.KeyCrawlQ, "https://example.com/"); score != 3 {
+		t.Errorf("priority = %v, want raised to 3", score)
+	}
+	e := Entry{}
+	c.Codec.Unmarshal([]byte(mr.HGet(c.queueEntriesKey(), "https://example.com/")), &e)
+	if e.Parent != "https://example.com/first" {
+		t.Errorf("entry parent = %q, want it kept as first queued", e.Parent)
+	}
+}
+
+func TestClaimLegacyEntries(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	data, _ := c.Codec.Marshal(Entry{URL: "https://example.com/", Depth: 1})
+	mr.ZAdd(c.KeyCrawlQ, 0, string(data))
+
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+	entry, _, err := c.claim(t.Context(), w)
+	if err != nil || entry == nil || entry.URL != "https://example.com/" || entry.Depth != 1 {
+		t.Errorf("claim = %+v, %v, want the entry queued by an earlier version", entry, err)
+	}
+}
+
+func TestUpgradeQueue(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	entry, _ := c.Codec.Marshal(Entry{URL: "https://example.com/b", Depth: 2, Priority: 5})
+	mr.SAdd(c.KeyCrawlQ, "https://example.com/a", string(entry))
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	bare, err := c.upgradeQueue(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bare != 1 {
+		t.Errorf("converted %d bare URLs, want 1", bare)
+	}
+
+	queued, err := mr.ZMembers(c.KeyCrawlQ)
+	if err != nil || len(queued) != 2 {
+		t.Fatalf("queue = %v, %v, want both entries", queued, err)
+	}
+	e := Entry{}
+	c.Codec.Unmarshal([]byte(mr.HGet(c.queueEntriesKey(), "https://example.com/b")), &e)
+	if score, _ := mr.ZScore(c.KeyCrawlQ, "https://example.com/b"); score != 5 || e.Depth != 2 {
+		t.Errorf("entry %+v scored %v, want it kept with its priority 5", e, score)
+	}
+	if mr.Exists(c.KeyCrawlQ + ":set") {
+		t.Error("old queue left behind")
+	}
+}
+
+func TestTraversal(t *testing.T) {
+	links := map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `<a href="/a/1">a1</a>`,
+		"/b": `<a href="/b/1">b1</a>`,
+	}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(links[r.URL.Path]))
+	}))
+	defer site.Close()
+	depthOf := map[string]int{"/": 0, "/a": 1, "/b": 1, "/a/1": 2, "/b/1": 2}
+
+	tests := []struct {
+		traversal string
+		depths    []int
+	}{
+		{"bfs", []int{0, 1, 1, 2, 2}},
+		{"dfs", []int{0, 1, 2, 1, 2}},
+	}
+	for _, tt := range tests {
+		c, _ := newTestCrawler(t)
+		priority, err := ParseTraversal(tt.traversal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Priority = priority
+
+		depths := []int{}
+		c.OnPageCrawled = func(p *Page) error {
+			depths = append(depths, depthOf[strings.TrimPrefix(p.URL, site.URL)])
+			return nil
+		}
+		c.Seed(site.URL + "/")
+		c.Run()
+
+		if !slices.Equal(depths, tt.depths) {
+			t.Errorf("%s: crawled pages at depths %v, want %v", tt.traversal, depths, tt.depths)
+		}
+	}
+
+	if _, err := ParseTraversal("sideways"); err == nil {
+		t.Error("ParseTraversal(sideways) succeeded, want an error")
+	}
+}
+
+func TestEntryEnvelope(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		c, mr := newTestCrawler(t)
+		c.Codec = codec
+
+		conn := c.RedisPool.Get()
+		defer conn.Close()
+		if err := c.push(conn, Entry{URL: "https://example.com/", Depth: 2, Parent: "https://example.com/up"}); err != nil {
+			t.Fatal(err)
+		}
+		queued := mr.HGet(c.queueEntriesKey(), "https://example.com/")
+		got := Entry{}
+		if err := codec.Unmarshal([]byte(queued), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != EntryVersion || got.Depth != 2 || got.Parent != "https://example.com/up" || got.Discovered.IsZero() {
+			t.Errorf("%T: queued %+v, want a versioned entry stamped with its discovery", codec, got)
+		}
+
+		// found again later it's still queued once, as first discovered
+		time.Sleep(time.Millisecond)
+		c.push(conn, Entry{URL: "https://example.com/", Depth: 1, Parent: "https://example.com/"})
+		if queued, _ := mr.ZMembers(c.KeyCrawlQ); len(queued) != 1 {
+			t.Errorf("%T: queue = %v, want the page once", codec, queued)
+		}
+		first := Entry{}
+		codec.Unmarshal([]byte(mr.HGet(c.queueEntriesKey(), "https://example.com/")), &first)
+		if !first.Discovered.Equal(got.Discovered) {
+			t.Errorf("%T: rediscovered entry discovered at %v, want %v", codec, first.Discovered, got.Discovered)
+		}
+
+		// going back in the queue keeps when it was discovered
+		mr.Del(c.KeyCrawlQ)
+		mr.Del(c.queueEntriesKey())
+		c.push(conn, got)
+		again := Entry{}
+		codec.Unmarshal([]byte(mr.HGet(c.queueEntriesKey(), "https://example.com/")), &again)
+		if !again.Discovered.Equal(got.Discovered) {
+			t.Errorf("%T: requeued entry discovered at %v, want %v", codec, again.Discovered, got.Discovered)
+		}
+	}
+
+	// entries from newer versions still decode
+	e := Entry{}
+	if err := (JSONCodec{}).Unmarshal([]byte(`{"v":9,"url":"https://example.com/","depth":1,"future":true}`), &e); err != nil || e.URL != "https://example.com/" {
+		t.Errorf("decoding a newer entry = %+v, %v", e, err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="` + r.URL.Path + `x">deeper</a>`))
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.MaxDepth = 2
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	if visited, _ := mr.Members(c.KeyVisitedHREFs); len(visited) != 3 {
+		t.Errorf("visited %v, want the seed and 2 deeper", visited)
+	}
+}
+
+func TestFollowFrames(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<iframe src="/gallery"></iframe><iframe src="https://other.example/ad"></iframe>`))
+		case "/gallery":
+			w.Write([]byte(`<img src="/photo.jpg"><frameset><frame src="/nested/x"></frameset>`))
+		default:
+			// frames nesting frames without end
+			w.Write([]byte(`<img src="` + r.URL.Path + `.png"><iframe src="` + r.URL.Path + `x"></iframe>`))
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+	if images, _ := mr.Members(c.KeyImageSrcs); len(images) != 0 {
+		t.Errorf("images %v, want none without FollowFrames", images)
+	}
+
+	c, mr = newTestCrawler(t)
+	c.FollowFrames = true
+	c.MaxFrameDepth = 3
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	want := []string{site.URL + "/photo.jpg", site.URL + "/nested/x.png", site.URL + "/nested/xx.png"}
+	images, _ := mr.Members(c.KeyImageSrcs)
+	slices.Sort(want)
+	if !slices.Equal(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+
+	rec, _, _ := c.LookupPage(site.URL + "/")
+	if want := []string{site.URL + "/gallery"}; !slices.Equal(rec.Frames, want) {
+		t.Errorf("frames = %v, want %v", rec.Frames, want)
+	}
+	if rec, _, _ := c.LookupPage(site.URL + "/gallery"); rec.Depth != 0 {
+		t.Errorf("frame depth = %d, want that of the page embedding it", rec.Depth)
+	}
+}
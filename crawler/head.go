@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// headFirst HEADs the page before it's fetched, reporting false if there's
+// no need to GET it as it's not one of the HTMLTypes or is larger than
+// MaxBodyBytes, in which case the page's status and any error are set.
+// Anything inconclusive, e.g. a server not supporting HEAD or leaving out
+// the Content-Type, goes ahead with the GET.
+func (c *Crawler) headFirst(ctx context.Context, url string, page *scrapeResult, logger *slog.Logger) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return true
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		logger.Debug("HEAD failed, fetching anyway", "url", url, "err", err)
+		return true
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return true
+	}
+
+	if ct := resp.Header.Get("content-type"); ct != "" && !c.parseable(ct) && !c.sniffable(ct) {
+		logger.Info("skipping non-HTML page without fetching it", "url", url, "contentType", ct)
+		page.status = resp.StatusCode
+		return false
+	}
+	if c.MaxBodyBytes > 0 && resp.ContentLength > c.MaxBodyBytes {
+		page.status = resp.StatusCode
+		page.err = oversizedError(resp.ContentLength, c.MaxBodyBytes)
+		logger.Warn("skipping oversized page without fetching it", "url", url, "bytes", resp.ContentLength, "maxBytes", c.MaxBodyBytes)
+		c.reportError(url, page.err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestHeadFirst(t *testing.T) {
+	var mu sync.Mutex
+	gets := map[string]int{}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nohead" && r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			gets[r.URL.Path]++
+			mu.Unlock()
+		}
+
+		body := []byte(`<img src="/a.png">`)
+		switch r.URL.Path {
+		case "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		case "/huge":
+			body = bytes.Repeat(body, 100)
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.HeadFirst = true
+	c.MaxBodyBytes = 1000
+
+	tests := []struct {
+		path    string
+		gets    int
+		images  int
+		wantErr bool
+	}{
+		{"/page", 1, 1, false},
+		{"/report.pdf", 0, 0, false},
+		{"/huge", 0, 0, true},
+		// servers not supporting HEAD are fetched regardless
+		{"/nohead", 1, 1, false},
+	}
+	for _, tt := range tests {
+		page := c.scrape(t.Context(), site.URL+tt.path, c.Logger)
+		if page.status != http.StatusOK || len(page.imgSrcs) != tt.images {
+			t.Errorf("%s: status = %d, imgSrcs = %v, want 200 and %d images", tt.path, page.status, page.imgSrcs, tt.images)
+		}
+		if (page.err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.path, page.err, tt.wantErr)
+		}
+		mu.Lock()
+		if gets[tt.path] != tt.gets {
+			t.Errorf("%s: fetched %d times, want %d", tt.path, gets[tt.path], tt.gets)
+		}
+		mu.Unlock()
+	}
+}
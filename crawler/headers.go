@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// headerTransport adds the crawl's extra request headers to every request,
+// leaving any header the request already sets alone
+type headerTransport struct {
+	base        http.RoundTripper
+	header      http.Header
+	hostHeaders map[string]http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hostHeader := t.hostHeaders[strings.ToLower(req.URL.Hostname())]
+	if len(t.header) == 0 && len(hostHeader) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// a RoundTripper mustn't modify the request it's given
+	req = req.Clone(req.Context())
+	for _, h := range []http.Header{hostHeader, t.header} {
+		for k, v := range h {
+			if _, set := req.Header[k]; !set {
+				req.Header[k] = v
+			}
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// canonicalHeader copies the header with canonical names, so it matches
+// whatever case names are given in
+func canonicalHeader(h http.Header) http.Header {
+	canonical := make(http.Header, len(h))
+	for k, v := range h {
+		canonical[http.CanonicalHeaderKey(k)] = append(canonical[http.CanonicalHeaderKey(k)], v...)
+	}
+	return canonical
+}
+
+// canonicalHostHeaders keys the per-host headers by lower-cased host, with
+// canonical header names
+func canonicalHostHeaders(hostHeaders map[string]http.Header) map[string]http.Header {
+	canonical := make(map[string]http.Header, len(hostHeaders))
+	for host, h := range hostHeaders {
+		host = strings.ToLower(host)
+		if canonical[host] == nil {
+			canonical[host] = http.Header{}
+		}
+		for k, v := range canonicalHeader(h) {
+			canonical[host][k] = append(canonical[host][k], v...)
+		}
+	}
+	return canonical
+}
@@ -0,0 +1,33 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostHeaders(t *testing.T) {
+	got := http.Header{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Header = http.Header{"accept-language": {"fr"}, "Authorization": {"Bearer everyone"}}
+	c.HostHeaders = map[string]http.Header{"127.0.0.1": {"Authorization": {"Bearer host"}}}
+
+	resp, err := c.fetch(t.Context(), srv.URL, http.Header{"Accept-Language": {"de"}}, c.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the request's own headers come first, then the host's, then everyone's
+	if v := got.Get("Accept-Language"); v != "de" {
+		t.Errorf("Accept-Language = %q, want the request's own", v)
+	}
+	if v := got.Get("Authorization"); v != "Bearer host" {
+		t.Errorf("Authorization = %q, want the host's", v)
+	}
+}
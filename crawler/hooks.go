@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+)
+
+// Page describes a crawled page to the OnPageCrawled hook. The hook may edit
+// Links and Images to filter what is followed and recorded.
+type Page struct {
+	URL    string
+	Links  []string
+	Images []string
+}
+
+// ErrSkipPage can be returned from OnPageCrawled to discard a page, its
+// images are not recorded and its links are not followed. Returned from
+// BeforeFetch it skips the page without fetching it.
+var ErrSkipPage = errors.New("skip page")
+
+// runBeforeFetch asks BeforeFetch whether the page may be fetched, any error
+// skips it so that a failing check never lets a flagged URL through
+func (c *Crawler) runBeforeFetch(ctx context.Context, url string) bool {
+	if c.BeforeFetch == nil {
+		return true
+	}
+
+	if err := c.BeforeFetch(ctx, url); err != nil {
+		if err != ErrSkipPage {
+			c.reportError(url, err)
+		}
+		return false
+	}
+	return true
+}
+
+// runPageHook hands the page to OnPageCrawled, reporting whether the page
+// should still be recorded
+func (c *Crawler) runPageHook(url string, page *scrapeResult) bool {
+	if c.OnPageCrawled == nil {
+		return true
+	}
+
+	p := &Page{
+		URL:    url,
+		Links:  page.hrefs,
+		Images: page.imgSrcs,
+	}
+
+	if err := c.OnPageCrawled(p); err != nil {
+		if err != ErrSkipPage {
+			c.reportError(url, err)
+		}
+		return false
+	}
+
+	page.hrefs = p.Links
+	page.imgSrcs = p.Images
+	page.anchors = anchorsOf(page.anchors, p.Links)
+	return true
+}
+
+// anchorsOf filters the anchors down to those of the links the hook kept
+func anchorsOf(anchors []LinkAnchor, links []string) []LinkAnchor {
+	kept := map[string]bool{}
+	for _, l := range links {
+		kept[l] = true
+	}
+
+	filtered := []LinkAnchor{}
+	for _, a := range anchors {
+		if kept[a.URL] {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+func (c *Crawler) reportImage(imgURL string, pageURL string) {
+	if c.OnImageFound != nil {
+		c.OnImageFound(imgURL, pageURL)
+	}
+}
+
+func (c *Crawler) reportError(url string, err error) {
+	if c.OnError != nil {
+		c.OnError(url, err)
+	}
+}
@@ -0,0 +1,136 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOnImageFound(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><img src="/b.png"><a href="/next">next</a>`))
+		case "/next":
+			w.Write([]byte(`<img src="/a.png">`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	mu := sync.Mutex{}
+	found := []string{}
+	c.OnImageFound = func(imgURL string, pageURL string) {
+		mu.Lock()
+		defer mu.Unlock()
+		found = append(found, strings.TrimPrefix(imgURL, site.URL)+" on "+strings.TrimPrefix(pageURL, site.URL))
+	}
+	c.Seed(site.URL)
+	c.Run()
+
+	// every sighting is reported, not just the first
+	sort.Strings(found)
+	if want := []string{"/a.png on ", "/a.png on /next", "/b.png on "}; !slices.Equal(found, want) {
+		t.Errorf("found %q, want %q", found, want)
+	}
+}
+
+func TestOnPageCrawled(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><img src="/ad.png"><a href="/next">next</a><a href="/ads">ads</a><a href="/skip">skip</a>`))
+		default:
+			w.Write([]byte(`<img src="` + r.URL.Path + `.png">`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.OnPageCrawled = func(p *Page) error {
+		if strings.HasSuffix(p.URL, "/skip") {
+			return ErrSkipPage
+		}
+		// drop whatever mentions ads
+		p.Images = slices.DeleteFunc(p.Images, func(s string) bool { return strings.Contains(s, "/ad") })
+		p.Links = slices.DeleteFunc(p.Links, func(s string) bool { return strings.Contains(s, "/ad") })
+		return nil
+	}
+	c.Seed(site.URL)
+	c.Run()
+
+	images := []string{}
+	it := c.ImageIterator()
+	for it.Next() {
+		images = append(images, strings.TrimPrefix(it.Member(), site.URL))
+	}
+	sort.Strings(images)
+	if want := []string{"/a.png", "/next.png"}; !slices.Equal(images, want) {
+		t.Errorf("images = %v, want those the hook kept, none from the skipped page", images)
+	}
+	if _, found, _ := c.LookupPage(site.URL + "/ads"); found {
+		t.Error("link dropped by the hook was followed")
+	}
+	if rec, found, _ := c.LookupPage(site.URL + "/skip"); !found || !rec.Skipped {
+		t.Errorf("skipped page = %+v, found %v, want it recorded as skipped", rec, found)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+	hookErr := errors.New("hook failed")
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="` + gone.URL + `/down">down</a><a href="/hook">hook</a><a href="/blocked">blocked</a><a href="/skipped">skipped</a>`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.MaxRetries = 0
+	c.BeforeFetch = func(ctx context.Context, url string) error {
+		switch {
+		case strings.HasSuffix(url, "/blocked"):
+			return errors.New("blocked")
+		case strings.HasSuffix(url, "/skipped"):
+			return ErrSkipPage
+		}
+		return nil
+	}
+	c.OnPageCrawled = func(p *Page) error {
+		if strings.HasSuffix(p.URL, "/hook") {
+			return hookErr
+		}
+		return nil
+	}
+	mu := sync.Mutex{}
+	errs := map[string]error{}
+	c.OnError = func(url string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs[url] = err
+	}
+	c.Seed(site.URL)
+	c.Run()
+
+	if len(errs) != 3 {
+		t.Errorf("errors reported for %v, want the unreachable page, the hook's and BeforeFetch's, but not skips", errs)
+	}
+	if errs[gone.URL+"/down"] == nil {
+		t.Error("fetch failure not reported")
+	}
+	if !errors.Is(errs[site.URL+"/hook"], hookErr) {
+		t.Errorf("hook error = %v, want %v", errs[site.URL+"/hook"], hookErr)
+	}
+	if err := errs[site.URL+"/blocked"]; err == nil || err.Error() != "blocked" {
+		t.Errorf("BeforeFetch error = %v, want it reported", err)
+	}
+}
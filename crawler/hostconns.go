@@ -0,0 +1,220 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// hostSlotScript takes or renews one of a host's connection slots, unless
+// the host already has as many as allowed. Each host's slots are a JSON
+// object of slot IDs to lease expiries, so the slots of processes that die
+// lapse on their own.
+//
+//	KEYS[1] the slots, by host
+//	ARGV[1] the host, ARGV[2] the slot, ARGV[3] the most slots per host,
+//	ARGV[4] now and ARGV[5] the lease in milliseconds
+var hostSlotScript = redis.NewScript(1, `
+local now, max = tonumber(ARGV[4]), tonumber(ARGV[3])
+local slots = cjson.decode(redis.call("HGET", KEYS[1], ARGV[1]) or "{}")
+local held = 0
+for id, expiry in pairs(slots) do
+	if expiry < now then
+		slots[id] = nil
+	elseif id ~= ARGV[2] then
+		held = held + 1
+	end
+end
+
+if slots[ARGV[2]] == nil and held >= max then
+	return 0
+end
+slots[ARGV[2]] = now + tonumber(ARGV[5])
+redis.call("HSET", KEYS[1], ARGV[1], cjson.encode(slots))
+return 1
+`)
+
+// releaseHostSlotScript frees a host's connection slot
+//
+//	KEYS[1] the slots, by host
+//	ARGV[1] the host, ARGV[2] the slot
+var releaseHostSlotScript = redis.NewScript(1, `
+local slots = redis.call("HGET", KEYS[1], ARGV[1])
+if not slots then
+	return 0
+end
+slots = cjson.decode(slots)
+slots[ARGV[2]] = nil
+if next(slots) == nil then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+else
+	redis.call("HSET", KEYS[1], ARGV[1], cjson.encode(slots))
+end
+return 1
+`)
+
+// hostSlot is a connection slot held by a request in flight, or a worker
+// slot held by a page being crawled, renewed in the background until
+// released
+type hostSlot struct {
+	c    *Crawler
+	key  string // KeyHostConns or KeyHostWorkers
+	max  int
+	host string
+	id   string
+	once sync.Once
+	stop chan struct{}
+}
+
+func (c *Crawler) newHostSlot(key string, max int, host string) *hostSlot {
+	return &hostSlot{c: c, key: key, max: max, host: host, id: NewJobID(), stop: make(chan struct{})}
+}
+
+// acquireHostSlot blocks until one of the host's MaxHostConnections slots is
+// free, then takes it. If Redis can't be reached the request goes ahead
+// without a slot, returning a nil slot, as stalling the crawl would be worse.
+func (c *Crawler) acquireHostSlot(ctx context.Context, host string) (*hostSlot, error) {
+	slot := c.newHostSlot(c.KeyHostConns, c.MaxHostConnections, host)
+
+	wait := 10 * time.Millisecond
+	for {
+		taken, err := slot.take()
+		if err != nil {
+			c.Logger.Warn("failed to take a host connection slot, going without", "host", host, "err", err)
+			return nil, nil
+		}
+		if taken {
+			go slot.renew()
+			return slot, nil
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wait = min(wait*2, time.Second)
+	}
+}
+
+func (s *hostSlot) take() (bool, error) {
+	conn := s.c.RedisPool.Get()
+	defer conn.Close()
+
+	return redis.Bool(hostSlotScript.Do(conn, s.key, s.host, s.id, s.max, time.Now().UnixMilli(), workerLease.Milliseconds()))
+}
+
+// renew keeps the slot's lease from lapsing for as long as it's held, which
+// may be a while for a large download
+func (s *hostSlot) renew() {
+	for {
+		select {
+		case <-time.After(workerLease / 3):
+		case <-s.stop:
+			return
+		}
+
+		if _, err := s.take(); err != nil {
+			s.c.Logger.Warn("failed to renew a host slot", "host", s.host, "err", err)
+		}
+	}
+}
+
+// release frees the slot, it's safe to call more than once or on a nil slot
+func (s *hostSlot) release() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		close(s.stop)
+
+		conn := s.c.RedisPool.Get()
+		defer conn.Close()
+
+		if _, err := releaseHostSlotScript.Do(conn, s.key, s.host, s.id); err != nil {
+			// it lapses with its lease regardless
+			s.c.Logger.Warn("failed to release a host slot", "host", s.host, "err", err)
+		}
+	})
+}
+
+// claimHost takes one of MaxConcurrentPerHost worker slots of the claimed
+// entry's host, for the page to be crawled under. If the host already has as
+// many workers as allowed the entry goes back in the queue and false is
+// returned, so the worker moves on to another host rather than waiting for
+// this one. The entry keeps its priority but isn't claimed again for a
+// while, longer each time in a row the worker defers one. Like
+// acquireHostSlot it fails open if Redis can't be reached.
+func (c *Crawler) claimHost(w *worker, entry Entry) (*hostSlot, bool) {
+	u, err := neturl.Parse(entry.URL)
+	if err != nil || u.Hostname() == "" {
+		return nil, true
+	}
+
+	slot := c.newHostSlot(c.KeyHostWorkers, c.MaxConcurrentPerHost, u.Hostname())
+	taken, err := slot.take()
+	if err != nil {
+		w.logger.Warn("failed to take a host worker slot, going without", "host", slot.host, "err", err)
+		return nil, true
+	}
+	if taken {
+		w.deferred = 0
+		go slot.renew()
+		return slot, true
+	}
+
+	w.logger.Debug("host busy, deferring page", "url", entry.URL)
+	w.deferred++
+	notBefore := time.Now().Add(min(10*time.Millisecond<<min(w.deferred, 7), time.Second))
+	b := batch{}
+	if entry.visited {
+		b.add("SREM", c.KeyVisitedHREFs, entry.URL)
+	}
+	c.deferUntil(&b, notBefore, entry)
+	if err := b.exec(w.conn); err != nil {
+		// crawl it regardless rather than lose it
+		w.logger.Warn("failed to defer page", "url", entry.URL, "err", err)
+		return nil, true
+	}
+	return nil, false
+}
+
+// hostLimitTransport holds one of the host's connection slots for each
+// request, from when it's sent until its body is closed
+type hostLimitTransport struct {
+	base http.RoundTripper
+	c    *Crawler
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slot, err := t.c.acquireHostSlot(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		slot.release()
+		return nil, err
+	}
+	resp.Body = &slotBody{ReadCloser: resp.Body, slot: slot}
+	return resp, nil
+}
+
+// slotBody releases the request's slot once the body is closed
+type slotBody struct {
+	io.ReadCloser
+	slot *hostSlot
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.slot.release()
+	return err
+}
@@ -0,0 +1,150 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostSlots(t *testing.T) {
+	c1, mr := newTestCrawler(t)
+	// a second process sharing the crawl
+	c2 := New(NewPool("tcp", mr.Addr()))
+	c2.Logger = c1.Logger
+	c1.MaxHostConnections, c2.MaxHostConnections = 1, 1
+
+	slot, err := c1.acquireHostSlot(t.Context(), "example.com")
+	if err != nil || slot == nil {
+		t.Fatalf("acquireHostSlot = %v, %v", slot, err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c2.acquireHostSlot(ctx, "example.com"); err == nil {
+		t.Fatal("took a second slot for the host, want to wait for the first")
+	}
+	// other hosts have their own slots
+	other, err := c2.acquireHostSlot(t.Context(), "example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.release()
+
+	slot.release()
+	slot.release()
+	slot, err = c2.acquireHostSlot(t.Context(), "example.com")
+	if err != nil {
+		t.Fatalf("acquireHostSlot after release: %v", err)
+	}
+	slot.release()
+
+	if hosts, _ := mr.HKeys(c1.KeyHostConns); len(hosts) != 0 {
+		t.Errorf("slots left held for %v", hosts)
+	}
+}
+
+func TestHostLimitTransport(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.MaxHostConnections = 1
+
+	resp, err := c.client().Get(site.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the slot is held until the body is closed
+	if !mr.Exists(c.KeyHostConns) {
+		t.Error("no slot held while reading the body")
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if mr.Exists(c.KeyHostConns) {
+		t.Error("slot still held after closing the body")
+	}
+}
+
+func TestMaxConcurrentPerHost(t *testing.T) {
+	var inFlight, most atomic.Int32
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "text/html")
+		for i := range 8 {
+			fmt.Fprintf(w, `<a href="/%d">page</a>`, i)
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.MaxConcurrentPerHost = 2
+	// seeded with every page, so all the workers start at once
+	c.Seed(site.URL + "/")
+	for i := range 8 {
+		c.Seed(fmt.Sprintf("%s/%d", site.URL, i))
+	}
+	c.RunN(6)
+
+	if n := most.Load(); n != 2 {
+		t.Errorf("at most %d pages fetched at once, want 2", n)
+	}
+	if visited, _ := mr.Members(c.KeyVisitedHREFs); len(visited) != 9 {
+		t.Errorf("visited %d pages, want all 9", len(visited))
+	}
+	if mr.Exists(c.KeyHostWorkers) {
+		t.Error("host worker slots left held")
+	}
+}
+
+func TestClaimHostDefers(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	c.MaxConcurrentPerHost = 1
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+
+	slot, ok := c.claimHost(w, Entry{URL: "https://example.com/a"})
+	if !ok {
+		t.Fatal("claimHost deferred the first page of a host")
+	}
+	if _, ok := c.claimHost(w, Entry{URL: "https://example.com/b", Priority: 5}); ok {
+		t.Error("claimHost took a second slot for the host")
+	}
+	if queued, _ := mr.ZMembers(c.KeyCrawlQ); len(queued) != 1 {
+		t.Errorf("queue = %v, want the deferred page back in it", queued)
+	}
+	// it keeps its place, but isn't claimed until later
+	if score, _ := mr.ZScore(c.KeyCrawlQ, "https://example.com/b"); score != 5 {
+		t.Errorf("deferred page priority = %v, want 5 as it was", score)
+	}
+	if notBefore, err := mr.ZScore(c.queueDeferredKey(), "https://example.com/b"); err != nil || notBefore <= float64(time.Now().UnixMilli()) {
+		t.Errorf("deferred page not before %v, want later than now", notBefore)
+	}
+
+	// a page queued behind it is claimed first, then it once it's due
+	c.push(w.conn, Entry{URL: "https://example.org/"})
+	for _, want := range []string{"https://example.org/", "https://example.com/b"} {
+		if entry, _, err := c.claim(t.Context(), w); err != nil || entry == nil || entry.URL != want {
+			t.Errorf("claimed %v, %v, want %s", entry, err, want)
+		}
+	}
+
+	other, ok := c.claimHost(w, Entry{URL: "https://example.net/"})
+	if !ok {
+		t.Error("claimHost deferred a page of an idle host")
+	}
+	other.release()
+	slot.release()
+}
@@ -0,0 +1,187 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/bits"
+	"net/http"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// HostSummary describes what the crawl found on a single host
+type HostSummary struct {
+	Host   string
+	Pages  int
+	Images int
+
+	// favicon fingerprint, only populated if FingerprintFavicons was set
+	FaviconURL    string
+	FaviconMMH3   int32 // compatible with Shodan's http.favicon.hash
+	FaviconSHA256 string
+}
+
+// HostSummaries returns a summary of every host crawled so far
+func (c *Crawler) HostSummaries() ([]HostSummary, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	hosts, err := redis.Strings(conn.Do("SMEMBERS", c.KeyHosts))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []HostSummary{}
+	for _, host := range hosts {
+		fields, err := redis.StringMap(conn.Do("HGETALL", c.hostKey(host)))
+		if err != nil {
+			return nil, err
+		}
+
+		s := HostSummary{
+			Host:          host,
+			FaviconURL:    fields["faviconURL"],
+			FaviconSHA256: fields["faviconSHA256"],
+		}
+		fmt.Sscan(fields["pages"], &s.Pages)
+		fmt.Sscan(fields["images"], &s.Images)
+		fmt.Sscan(fields["faviconMMH3"], &s.FaviconMMH3)
+		summaries = append(summaries, s)
+	}
+
+	return summaries, nil
+}
+
+func (c *Crawler) hostKey(host string) string {
+	return c.KeyHosts + ":" + host
+}
+
+// recordHost adds a tally of the page against its host to the batch,
+// fingerprinting the host's favicon the first time the host is seen
+func (c *Crawler) recordHost(conn redis.Conn, b *batch, pageURL string, page *scrapeResult, logger *slog.Logger) error {
+	u, err := neturl.Parse(pageURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+
+	b.add("SADD", c.KeyHosts, host)
+	b.add("HINCRBY", c.hostKey(host), "pages", 1)
+	b.add("HINCRBY", c.hostKey(host), "images", len(page.imgSrcs))
+
+	if !c.FingerprintFavicons {
+		return nil
+	}
+
+	// prefer a declared icon, falling back to the conventional location
+	faviconURL := u.Scheme + "://" + u.Host + "/favicon.ico"
+	if len(page.icons) > 0 {
+		faviconURL = page.icons[0]
+	}
+
+	// claim the host so only the first worker to see it fingerprints it
+	claimed, err := redis.Int(conn.Do("HSETNX", c.hostKey(host), "faviconURL", faviconURL))
+	if err != nil || claimed == 0 {
+		return err
+	}
+
+	mmh3, sha, err := c.fingerprintFavicon(faviconURL)
+	if err != nil {
+		logger.Warn("failed to fingerprint favicon", "host", host, "url", faviconURL, "err", err)
+		return nil
+	}
+
+	b.add("HSET", c.hostKey(host), "faviconMMH3", mmh3, "faviconSHA256", sha)
+	return nil
+}
+
+func (c *Crawler) fingerprintFavicon(url string) (mmh3 int32, sha string, err error) {
+	resp, err := c.client().Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return shodanFaviconHash(data), hex.EncodeToString(sum[:]), nil
+}
+
+// shodanFaviconHash hashes the way Shodan does: murmur3 over the favicon
+// base64-encoded in 76 character lines, each terminated with a newline
+func shodanFaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+
+	return int32(murmur3([]byte(b.String()), 0))
+}
+
+// murmur3 is the 32-bit x86 variant of MurmurHash3
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[nblocks*4:]
+	k := uint32(0)
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
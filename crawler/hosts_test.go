@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0},
+		{"", 1, 0x514e28b7},
+		{"hello", 0, 0x248bfa47},
+		{"The quick brown fox jumps over the lazy dog", 0, 0x2e4ff723},
+	}
+	for _, tt := range tests {
+		if got := murmur3([]byte(tt.data), tt.seed); got != tt.want {
+			t.Errorf("murmur3(%q, %d) = %#x, want %#x", tt.data, tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestShodanFaviconHash(t *testing.T) {
+	// 100 bytes encode to 136 characters, wrapped after 76
+	data := []byte(strings.Repeat("\x00", 100))
+	wrapped := strings.Repeat("A", 76) + "\n" + strings.Repeat("A", 56) + "AA==\n"
+	if got, want := shodanFaviconHash(data), int32(murmur3([]byte(wrapped), 0)); got != want {
+		t.Errorf("shodanFaviconHash = %d, want %d, the hash of the wrapped base64", got, want)
+	}
+}
+
+func TestFingerprintFavicons(t *testing.T) {
+	icon := []byte("\x89PNG\r\n\x1a\nan icon")
+	iconFetches := atomic.Int32{}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/icon.png":
+			iconFetches.Add(1)
+			w.Write(icon)
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<link rel="icon" href="/icon.png"><img src="/a.png"><img src="/b.png"><a href="/next">next</a>`))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<img src="/a.png">`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.FingerprintFavicons = true
+	c.Seed(site.URL)
+	c.Run()
+
+	summaries, err := c.HostSummaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("summaries = %+v, want one host", summaries)
+	}
+	s := summaries[0]
+	sum := sha256.Sum256(icon)
+	if s.Host != "127.0.0.1" || s.Pages != 2 || s.Images != 3 {
+		t.Errorf("summary = %+v, want 2 pages with 3 images between them", s)
+	}
+	if s.FaviconURL != site.URL+"/icon.png" || s.FaviconSHA256 != hex.EncodeToString(sum[:]) || s.FaviconMMH3 != shodanFaviconHash(icon) {
+		t.Errorf("favicon = %q %q %d, want the declared icon's fingerprint", s.FaviconURL, s.FaviconSHA256, s.FaviconMMH3)
+	}
+	if n := iconFetches.Load(); n != 1 {
+		t.Errorf("icon fetched %d times, want once per host", n)
+	}
+}
+
+func TestHostSummariesWithoutFavicons(t *testing.T) {
+	fetched := atomic.Bool{}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			fetched.Store(true)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png">`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL)
+	c.Run()
+
+	summaries, _ := c.HostSummaries()
+	if len(summaries) != 1 || summaries[0].Pages != 1 || summaries[0].Images != 1 || summaries[0].FaviconURL != "" {
+		t.Errorf("summaries = %+v, want the host tallied without a favicon", summaries)
+	}
+	if fetched.Load() {
+		t.Error("favicon fetched without FingerprintFavicons")
+	}
+}
+
+func TestFingerprintFaviconsFallsBackToFaviconICO(t *testing.T) {
+	icon := []byte("an icon")
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			w.Write(icon)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png">`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.FingerprintFavicons = true
+	c.Seed(site.URL)
+	c.Run()
+
+	summaries, _ := c.HostSummaries()
+	if len(summaries) != 1 || summaries[0].FaviconURL != site.URL+"/favicon.ico" || summaries[0].FaviconMMH3 != shodanFaviconHash(icon) {
+		t.Errorf("summaries = %+v, want /favicon.ico fingerprinted", summaries)
+	}
+}
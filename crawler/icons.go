@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// Icon kinds, by the <link rel> declaring them or the conventional location
+const (
+	IconLink       = "icon"             // <link rel="icon"> or "shortcut icon"
+	IconTouch      = "apple-touch-icon" // <link rel="apple-touch-icon">
+	IconFaviconICO = "favicon.ico"      // /favicon.ico, found without being declared
+)
+
+// IconRecord is a favicon or touch icon of a host, found by CollectIcons
+type IconRecord struct {
+	URL  string `json:"url"`
+	Host string `json:"host"` // of the pages using it, which may not serve it
+	Kind string `json:"kind"` // IconLink, IconTouch or IconFaviconICO
+	// PageURL is the first page found declaring the icon, empty for
+	// IconFaviconICO
+	PageURL string `json:"pageURL,omitempty"`
+}
+
+func (c *Crawler) iconHostsKey() string {
+	return c.KeyIcons + ":hosts"
+}
+
+// recordIcons adds the page's icons to the batch, and the first time the
+// page's host is seen checks for its /favicon.ico
+func (c *Crawler) recordIcons(conn redis.Conn, b *batch, pageURL string, page *scrapeResult, logger *slog.Logger) error {
+	u, err := neturl.Parse(pageURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+
+	add := func(url, kind, pageURL string) {
+		if data, err := json.Marshal(IconRecord{URL: url, Host: host, Kind: kind, PageURL: pageURL}); err == nil {
+			b.add("HSETNX", c.KeyIcons, url, data)
+		}
+	}
+	for _, url := range page.icons {
+		add(url, IconLink, pageURL)
+	}
+	for _, url := range page.touchIcons {
+		add(url, IconTouch, pageURL)
+	}
+
+	// claim the host so only the first worker to see it checks
+	claimed, err := redis.Int(conn.Do("SADD", c.iconHostsKey(), host))
+	if err != nil || claimed == 0 {
+		return err
+	}
+
+	faviconURL := u.Scheme + "://" + u.Host + "/favicon.ico"
+	if found, err := c.hasFavicon(faviconURL); err != nil {
+		logger.Warn("failed to check for favicon.ico", "host", host, "url", faviconURL, "err", err)
+	} else if found {
+		add(faviconURL, IconFaviconICO, "")
+	}
+	return nil
+}
+
+// hasFavicon reports whether the URL serves an icon, rather than a 404 or
+// the HTML page some sites without one answer with
+func (c *Crawler) hasFavicon(url string) (bool, error) {
+	resp, err := c.client().Get(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	return !c.isHTML(resp.Header.Get("Content-Type")), nil
+}
+
+// Icons returns every icon found by CollectIcons, by host
+func (c *Crawler) Icons() ([]IconRecord, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	records, err := redis.StringMap(conn.Do("HGETALL", c.KeyIcons))
+	if err != nil {
+		return nil, err
+	}
+
+	icons := []IconRecord{}
+	for url, data := range records {
+		icon := IconRecord{}
+		if err := json.Unmarshal([]byte(data), &icon); err != nil {
+			icon = IconRecord{URL: url}
+		}
+		icons = append(icons, icon)
+	}
+
+	sort.Slice(icons, func(i, j int) bool {
+		if icons[i].Host != icons[j].Host {
+			return icons[i].Host < icons[j].Host
+		}
+		if icons[i].Kind != icons[j].Kind {
+			return icons[i].Kind < icons[j].Kind
+		}
+		return icons[i].URL < icons[j].URL
+	})
+	return icons, nil
+}
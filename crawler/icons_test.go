@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCollectIcons(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<link rel="shortcut icon" href="/img/fav.png"><link rel="apple-touch-icon" sizes="180x180" href="/img/touch.png"><a href="/a">a</a>`))
+		case "/a":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<link rel="icon" href="/img/fav.png">`))
+		case "/favicon.ico":
+			w.Header().Set("Content-Type", "image/x-icon")
+			w.Write([]byte{0, 0, 1, 0})
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.CollectIcons = true
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	icons, err := c.Icons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []IconRecord{
+		{URL: site.URL + "/img/touch.png", Host: "127.0.0.1", Kind: IconTouch, PageURL: site.URL + "/"},
+		{URL: site.URL + "/favicon.ico", Host: "127.0.0.1", Kind: IconFaviconICO},
+		{URL: site.URL + "/img/fav.png", Host: "127.0.0.1", Kind: IconLink},
+	}
+	if len(icons) != len(want) {
+		t.Fatalf("icons = %+v, want %+v", icons, want)
+	}
+	for i, icon := range icons {
+		if icon.URL != want[i].URL || icon.Host != want[i].Host || icon.Kind != want[i].Kind {
+			t.Errorf("icon %d = %+v, want %+v", i, icon, want[i])
+		}
+	}
+	if icons[0].PageURL != want[0].PageURL {
+		t.Errorf("touch icon found on %q, want %q", icons[0].PageURL, want[0].PageURL)
+	}
+}
+
+func TestCollectIconsSoft404(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`no icons here`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.CollectIcons = true
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	if icons, _ := c.Icons(); len(icons) != 0 {
+		t.Errorf("icons = %+v, want none", icons)
+	}
+}
@@ -0,0 +1,35 @@
+package crawler
+
+import (
+	"sort"
+	"time"
+)
+
+// PageInFlight is a page being crawled by a worker of this process
+type PageInFlight struct {
+	URL     string    `json:"url"`
+	Worker  string    `json:"worker"`
+	Started time.Time `json:"started"`
+}
+
+// InFlight returns the pages the workers of this process are crawling, the
+// longest running first, to find what a slow or stuck crawl is held up on
+func (c *Crawler) InFlight() []PageInFlight {
+	pages := []PageInFlight{}
+	c.inFlight.Range(func(key, _ any) bool {
+		pages = append(pages, *key.(*PageInFlight))
+		return true
+	})
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Started.Before(pages[j].Started)
+	})
+	return pages
+}
+
+// trackPage notes the entry's page as being crawled by the worker, until
+// the func returned is called
+func (c *Crawler) trackPage(w *worker, entry Entry) func() {
+	p := &PageInFlight{URL: entry.URL, Worker: w.id, Started: time.Now().UTC()}
+	c.inFlight.Store(p, struct{}{})
+	return func() { c.inFlight.Delete(p) }
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInFlight(t *testing.T) {
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Write([]byte(`<a href="/slow">Slow</a>`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	done := make(chan struct{})
+	go func() {
+		c.RunN(2)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var pages []PageInFlight
+	for time.Now().Before(deadline) {
+		if pages = c.InFlight(); len(pages) == 1 && pages[0].URL == site.URL+"/slow" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(pages) != 1 || pages[0].URL != site.URL+"/slow" || pages[0].Worker == "" || pages[0].Started.IsZero() {
+		t.Errorf("in flight = %+v, want /slow", pages)
+	}
+
+	close(release)
+	<-done
+	if pages := c.InFlight(); len(pages) != 0 {
+		t.Errorf("in flight after the crawl = %+v, want none", pages)
+	}
+}
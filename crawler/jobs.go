@@ -0,0 +1,171 @@
+package crawler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// KeyJobs is the set of every job ID that has been started
+const KeyJobs = "crawl:jobs"
+
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// NewJob allocates a Crawler whose keys are all namespaced under the job ID,
+// e.g. crawl:{id}:queue, so that concurrent crawls don't interfere
+func NewJob(p *redis.Pool, id string) *Crawler {
+	c := New(p)
+	c.JobID = id
+
+	prefix := jobPrefix(id)
+	c.KeyActiveWorkers = prefix + "active"
+	c.KeyCrawlQ = prefix + "queue"
+	c.KeyVisitedHREFs = prefix + "visited"
+	c.KeyVisitedBloom = prefix + "visitedBloom"
+	c.KeyImageSrcs = prefix + "images"
+	c.KeyImageMeta = prefix + "imageMeta"
+	c.KeyImagePages = prefix + "imagePages"
+	c.KeyImageHashes = prefix + "imageHashes"
+	c.KeyDownloads = prefix + "downloads"
+	c.KeyHosts = prefix + "hosts"
+	c.KeyPages = prefix + "pages"
+	c.KeyPageCache = prefix + "pageCache"
+	c.KeyAssets = prefix + "assets"
+	c.KeyPaused = prefix + "paused"
+	c.KeySinks = prefix + "sinks"
+	c.KeyLock = prefix + "lock"
+	c.KeyRenders = prefix + "renders"
+	c.KeyImageSizes = prefix + "imageSizes"
+	c.KeyHostConns = prefix + "hostConns"
+	c.KeyHostWorkers = prefix + "hostWorkers"
+	c.KeyThroughput = prefix + "throughput"
+	c.KeyHeartbeats = prefix + "heartbeats"
+	c.KeyEpoch = prefix + "epoch"
+	c.KeyMedia = prefix + "media"
+	c.KeyIcons = prefix + "icons"
+	c.KeyDataImages = prefix + "dataImages"
+	c.KeySimhashes = prefix + "simhashes"
+	c.KeyFailed = prefix + "failed"
+
+	return c
+}
+
+// NewJobID generates a unique, time-ordered job ID
+func NewJobID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// ValidateJobID checks that a job ID is safe to embed in key names and
+// key patterns
+func ValidateJobID(id string) error {
+	if !jobIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid job ID %q: only letters, digits, '_', '.' and '-' are allowed", id)
+	}
+	return nil
+}
+
+func jobPrefix(id string) string {
+	return "crawl:{" + id + "}:"
+}
+
+// register records the job in the job list, a no-op for un-namespaced crawls
+func (c *Crawler) register(conn redis.Conn) error {
+	if c.JobID == "" {
+		return nil
+	}
+
+	_, err := conn.Do("SADD", KeyJobs, c.JobID)
+	return err
+}
+
+// JobInfo describes the state of a stored crawl job
+type JobInfo struct {
+	ID            string
+	Queued        int
+	Visited       int
+	Images        int
+	ActiveWorkers int
+	Paused        bool
+}
+
+// ListJobs returns the IDs of every job that has been started
+func ListJobs(p *redis.Pool) ([]string, error) {
+	conn := p.Get()
+	defer conn.Close()
+
+	return redis.Strings(conn.Do("SMEMBERS", KeyJobs))
+}
+
+// InspectJob reports the current state of a job
+func InspectJob(p *redis.Pool, id string) (JobInfo, error) {
+	return NewJob(p, id).Info()
+}
+
+// Info reports the current state of the crawl
+func (c *Crawler) Info() (JobInfo, error) {
+	info := JobInfo{ID: c.JobID}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	conn.Send("ZCARD", c.KeyCrawlQ)
+	conn.Send("SCARD", c.KeyImageSrcs)
+	conn.Send("EXISTS", c.KeyPaused)
+	reply, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return info, err
+	}
+
+	_, err = redis.Scan(reply, &info.Queued, &info.Images, &info.Paused)
+	if err != nil {
+		return info, err
+	}
+	if info.Visited, err = c.visitedCount(conn); err != nil {
+		return info, err
+	}
+
+	info.ActiveWorkers, err = c.activeWorkers(conn)
+	return info, err
+}
+
+// DeleteJob removes every key belonging to a job, and the job itself
+func DeleteJob(p *redis.Pool, id string) error {
+	if err := ValidateJobID(id); err != nil {
+		return err
+	}
+
+	conn := p.Get()
+	defer conn.Close()
+
+	cursor := "0"
+	for {
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", jobPrefix(id)+"*", "COUNT", 1000))
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		if _, err := redis.Scan(reply, &cursor, &keys); err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if _, err := conn.Do("DEL", redis.Args{}.AddFlat(keys)...); err != nil {
+				return err
+			}
+		}
+
+		if cursor == "0" {
+			break
+		}
+	}
+
+	_, err := conn.Do("SREM", KeyJobs, id)
+	return err
+}
@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewJobNamespacesEveryKey(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	job := NewJob(c.RedisPool, "a")
+
+	v := reflect.ValueOf(job).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if !strings.HasPrefix(name, "Key") || v.Field(i).Kind() != reflect.String {
+			continue
+		}
+		if key := v.Field(i).String(); !strings.HasPrefix(key, "crawl:{a}:") {
+			t.Errorf("%s = %q, want it namespaced under the job", name, key)
+		}
+	}
+}
+
+func TestValidateJobID(t *testing.T) {
+	for _, id := range []string{"a", "nightly-2024.01_02", NewJobID()} {
+		if err := ValidateJobID(id); err != nil {
+			t.Errorf("ValidateJobID(%q) = %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "a b", "a*", "a}:b", "a/b", "?"} {
+		if err := ValidateJobID(id); err == nil {
+			t.Errorf("ValidateJobID(%q) = nil, want an error", id)
+		}
+	}
+	if NewJobID() == NewJobID() {
+		t.Error("NewJobID returned the same ID twice")
+	}
+}
+
+func TestJobs(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png"><img src="/b.png"><a href="/next">next</a>`))
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	for _, id := range []string{"b", "a"} {
+		job := NewJob(c.RedisPool, id)
+		job.Logger, job.TerminationGrace = c.Logger, c.TerminationGrace
+		job.Seed(site.URL)
+		job.Run()
+	}
+	NewJob(c.RedisPool, "b").Pause()
+
+	ids, err := ListJobs(c.RedisPool)
+	slices.Sort(ids)
+	if err != nil || !slices.Equal(ids, []string{"a", "b"}) {
+		t.Fatalf("ListJobs = %v, %v, want both jobs", ids, err)
+	}
+
+	info, err := InspectJob(c.RedisPool, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (JobInfo{ID: "a", Visited: 2, Images: 2}) {
+		t.Errorf("InspectJob(a) = %+v, want its 2 pages and images", info)
+	}
+	if info, _ := InspectJob(c.RedisPool, "b"); !info.Paused || info.Visited != 2 {
+		t.Errorf("InspectJob(b) = %+v, want it paused", info)
+	}
+
+	if err := DeleteJob(c.RedisPool, "a"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, "crawl:{a}:") {
+			t.Errorf("%s left after deleting the job", key)
+		}
+	}
+	if ids, _ := ListJobs(c.RedisPool); !slices.Equal(ids, []string{"b"}) {
+		t.Errorf("ListJobs = %v after deleting a, want just b", ids)
+	}
+	if info, _ := InspectJob(c.RedisPool, "b"); info.Visited != 2 || info.Images != 2 {
+		t.Errorf("InspectJob(b) = %+v after deleting a, want it untouched", info)
+	}
+
+	// a pattern can't be passed off as an ID
+	if err := DeleteJob(c.RedisPool, "*"); err == nil {
+		t.Error("DeleteJob(*) succeeded")
+	}
+	if info, _ := InspectJob(c.RedisPool, "b"); info.Images != 2 {
+		t.Error("DeleteJob(*) deleted another job's keys")
+	}
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink publishes every image found to a Kafka topic, as an ImageRecord
+// in JSON keyed by its ID, so each image always lands on the same partition.
+// Added to Crawler.PageSinks too, with PageTopic set, it publishes the
+// record of every page crawled, keyed by URL.
+type KafkaSink struct {
+	Topic     string
+	PageTopic string
+
+	writer *kafka.Writer
+}
+
+// NewKafkaSink creates a sink publishing to the topic through the brokers,
+// each message acknowledged by every in-sync replica
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		Topic: topic,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// the sink is handed whole batches, so there's no point waiting
+			// for more
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}
+}
+
+// Deliver implements Sink
+func (s *KafkaSink) Deliver(ctx context.Context, records []ImageRecord) error {
+	msgs := make([]kafka.Message, 0, len(records))
+	for _, r := range records {
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{Topic: s.Topic, Key: []byte(r.ID), Value: value})
+	}
+	return s.writer.WriteMessages(ctx, msgs...)
+}
+
+// DeliverPages implements PageSink, publishing nothing without a PageTopic
+func (s *KafkaSink) DeliverPages(ctx context.Context, records []PageRecord) error {
+	if s.PageTopic == "" {
+		return nil
+	}
+
+	msgs := make([]kafka.Message, 0, len(records))
+	for _, r := range records {
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{Topic: s.PageTopic, Key: []byte(r.URL), Value: value})
+	}
+	return s.writer.WriteMessages(ctx, msgs...)
+}
+
+// Close flushes and closes the connections to the brokers
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"slices"
+	"strings"
+)
+
+// LangAlternate is a variant of a page in another language, as declared by
+// <link rel="alternate" hreflang>
+type LangAlternate struct {
+	Lang string `json:"lang"` // e.g. "en-GB", or "x-default"
+	URL  string `json:"url"`
+}
+
+// alternate is a <link rel="alternate" hreflang> as found in a page
+type alternate struct {
+	lang string
+	href string
+}
+
+// pageLanguages are the languages a Content-Language header, or an <html
+// lang>, lists
+func pageLanguages(lang string) []string {
+	langs := []string{}
+	for _, l := range strings.Split(lang, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
+// languageMatches reports whether the language tag is one of the wanted,
+// or a variant of one, e.g. en-GB of en. Tags are compared ignoring case,
+// and "_" taken as "-".
+func languageMatches(lang string, wanted []string) bool {
+	lang = strings.ReplaceAll(strings.ToLower(lang), "_", "-")
+	for _, w := range wanted {
+		w = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(w)), "_", "-")
+		if lang == w || strings.HasPrefix(lang, w+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLanguage applies the Languages filter to the page. Pages in none
+// of the Languages keep just their links to alternates in them, so a crawl
+// starting from another language still finds its way, and return true.
+// Others drop their links to alternates in other languages. Pages and
+// alternates whose language isn't declared are crawled regardless.
+func (c *Crawler) filterLanguage(url string, page *scrapeResult) (offLanguage bool) {
+	if len(c.Languages) == 0 {
+		return false
+	}
+
+	wanted, unwanted := []string{}, []string{}
+	for _, alt := range page.alternates {
+		switch {
+		case strings.EqualFold(alt.Lang, "x-default"):
+		case languageMatches(alt.Lang, c.Languages):
+			wanted = append(wanted, alt.URL)
+		default:
+			unwanted = append(unwanted, alt.URL)
+		}
+	}
+
+	langs := pageLanguages(page.language)
+	offLanguage = len(langs) > 0 && !slices.ContainsFunc(langs, func(l string) bool { return languageMatches(l, c.Languages) })
+
+	hrefs, anchors := []string{}, []LinkAnchor{}
+	if offLanguage {
+		page.imgSrcs, page.media, page.dataImages, page.frames = []string{}, []string{}, nil, nil
+	} else {
+		for i, href := range page.hrefs {
+			if slices.Contains(unwanted, href) && !slices.Contains(wanted, href) {
+				continue
+			}
+			hrefs = append(hrefs, href)
+			if i < len(page.anchors) {
+				anchors = append(anchors, page.anchors[i])
+			}
+		}
+	}
+
+	// follow the alternates in the languages wanted, on the page's host
+	for _, href := range sameHost(url, wanted) {
+		if !slices.Contains(hrefs, href) {
+			hrefs = append(hrefs, href)
+			anchors = append(anchors, LinkAnchor{URL: href})
+		}
+	}
+	page.hrefs, page.anchors = hrefs, anchors
+	return offLanguage
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestLanguageMatches(t *testing.T) {
+	tests := []struct {
+		lang   string
+		wanted []string
+		want   bool
+	}{
+		{"en", []string{"en"}, true},
+		{"en-GB", []string{"en"}, true},
+		{"en_gb", []string{"EN-gb"}, true},
+		{"en", []string{"en-GB"}, false},
+		{"eng", []string{"en"}, false},
+		{"fr", []string{"en", "de"}, false},
+	}
+	for _, tt := range tests {
+		if got := languageMatches(tt.lang, tt.wanted); got != tt.want {
+			t.Errorf("languageMatches(%q, %v) = %v, want %v", tt.lang, tt.wanted, got, tt.want)
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html lang="fr"><link rel="alternate" hreflang="en" href="/en"><link rel="alternate" hreflang="x-default" href="/">
+				<img src="/fr.jpg"><a href="/fr/other">autre</a>`))
+		case "/en":
+			w.Header().Set("Content-Language", "en-GB")
+			w.Write([]byte(`<html lang="fr"><link rel="alternate" hreflang="fr" href="/fr/page">
+				<img src="/en.jpg"><a href="/fr/page">French</a><a href="/en/a">a</a>`))
+		case "/en/a":
+			w.Write([]byte(`<img src="/a.jpg">`))
+		default:
+			t.Errorf("crawled %s, in another language", r.URL.Path)
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.Languages = []string{"en"}
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	images, _ := mr.Members(c.KeyImageSrcs)
+	if want := []string{site.URL + "/a.jpg", site.URL + "/en.jpg"}; !slices.Equal(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+
+	rec, _, _ := c.LookupPage(site.URL + "/")
+	if !rec.Skipped || rec.Language != "fr" || len(rec.Alternates) != 2 {
+		t.Errorf("seed record = %+v, want skipped as fr with 2 alternates", rec)
+	}
+	if rec, _, _ := c.LookupPage(site.URL + "/en"); rec.Skipped || rec.Language != "en-GB" {
+		t.Errorf("English record skipped %v as %q, want crawled as en-GB", rec.Skipped, rec.Language)
+	}
+}
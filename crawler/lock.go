@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// lockScript takes or renews a holder's share of the start lock, a hash of
+// the crawl's config and owner along with each holder's lease expiry. The
+// lock is free once every holder's lease has lapsed, otherwise it's shared
+// with those of the same config only.
+//
+//	KEYS[1] the lock
+//	ARGV[1] the holder, ARGV[2] the config, ARGV[3] the owner, ARGV[4] now
+//	and ARGV[5] the lease in milliseconds
+var lockScript = redis.NewScript(1, `
+local now = tonumber(ARGV[4])
+local fields = redis.call("HGETALL", KEYS[1])
+local live = 0
+for i = 1, #fields, 2 do
+	if string.sub(fields[i], 1, 7) == "holder:" and fields[i] ~= "holder:" .. ARGV[1] then
+		if tonumber(fields[i + 1]) < now then
+			redis.call("HDEL", KEYS[1], fields[i])
+		else
+			live = live + 1
+		end
+	end
+end
+
+if live == 0 and redis.call("HEXISTS", KEYS[1], "holder:" .. ARGV[1]) == 0 then
+	redis.call("DEL", KEYS[1])
+	redis.call("HSET", KEYS[1], "config", ARGV[2], "owner", ARGV[3])
+elseif redis.call("HGET", KEYS[1], "config") ~= ARGV[2] then
+	return {0, redis.call("HGET", KEYS[1], "owner")}
+end
+
+redis.call("HSET", KEYS[1], "holder:" .. ARGV[1], now + tonumber(ARGV[5]))
+redis.call("PEXPIRE", KEYS[1], ARGV[5])
+return {1, live}
+`)
+
+// unlockScript drops a holder's share of the lock, freeing it once nobody
+// holds it
+//
+//	KEYS[1] the lock
+//	ARGV[1] the holder
+var unlockScript = redis.NewScript(1, `
+redis.call("HDEL", KEYS[1], "holder:" .. ARGV[1])
+for _, field in ipairs(redis.call("HKEYS", KEYS[1])) do
+	if string.sub(field, 1, 7) == "holder:" then
+		return 0
+	end
+end
+redis.call("DEL", KEYS[1])
+return 1
+`)
+
+// JobLockedError is returned by Lock when the crawl is already running with
+// a different config
+type JobLockedError struct {
+	JobID string
+	Owner string // who started the crawl holding the lock
+}
+
+func (e *JobLockedError) Error() string {
+	job := "the crawl"
+	if e.JobID != "" {
+		job = fmt.Sprintf("job %q", e.JobID)
+	}
+	return fmt.Sprintf("%s is already running with a different config, started by %s", job, e.Owner)
+}
+
+// jobLock is this process's share of the start lock
+type jobLock struct {
+	holder string
+	config string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// Lock takes the crawl's start lock, so that the same crawl can't be started
+// twice with conflicting configs. config is any fingerprint of how the crawl
+// is configured: processes locking with the same config share the lock, each
+// joining the crawl as more workers, while the rest get a *JobLockedError.
+// The lock is renewed in the background until Unlock, and lapses soon after
+// a process holding it dies.
+func (c *Crawler) Lock(config string) error {
+	c.lockMu.Lock()
+	defer c.lockMu.Unlock()
+
+	if c.lock != nil {
+		return fmt.Errorf("already locked")
+	}
+
+	l := &jobLock{
+		holder: NewJobID(),
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	conn := c.RedisPool.Get()
+	joined, err := c.takeLock(conn, l)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+	if joined > 0 {
+		c.Logger.Info("joining running crawl", "job", c.JobID, "processes", joined)
+	}
+
+	c.lock = l
+	go c.renewLock(l)
+	return nil
+}
+
+// Unlock releases the start lock taken by Lock
+func (c *Crawler) Unlock() error {
+	c.lockMu.Lock()
+	l := c.lock
+	c.lock = nil
+	c.lockMu.Unlock()
+
+	if l == nil {
+		return nil
+	}
+	close(l.stop)
+	<-l.done
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	_, err := unlockScript.Do(conn, c.KeyLock, l.holder)
+	return err
+}
+
+// takeLock takes or renews the lock, returning how many other processes
+// share it
+func (c *Crawler) takeLock(conn redis.Conn, l *jobLock) (int, error) {
+	owner := "an unknown host"
+	if host, err := os.Hostname(); err == nil {
+		owner = host
+	}
+	owner = fmt.Sprintf("%s (pid %d) at %s", owner, os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+
+	reply, err := redis.Values(lockScript.Do(conn, c.KeyLock, l.holder, l.config, owner, time.Now().UnixMilli(), workerLease.Milliseconds()))
+	if err != nil {
+		return 0, err
+	}
+	if len(reply) != 2 {
+		return 0, fmt.Errorf("unexpected lock reply %v", reply)
+	}
+
+	if taken, _ := redis.Int(reply[0], nil); taken == 0 {
+		holder, _ := redis.String(reply[1], nil)
+		return 0, &JobLockedError{JobID: c.JobID, Owner: holder}
+	}
+	return redis.Int(reply[1], nil)
+}
+
+// renewLock renews the lock's lease until it's released
+func (c *Crawler) renewLock(l *jobLock) {
+	defer close(l.done)
+
+	for {
+		select {
+		case <-time.After(workerLease / 3):
+		case <-l.stop:
+			return
+		}
+
+		conn := c.RedisPool.Get()
+		if _, err := c.takeLock(conn, l); err != nil {
+			c.Logger.Error("failed to renew the start lock", "job", c.JobID, "err", err)
+		}
+		conn.Close()
+	}
+}
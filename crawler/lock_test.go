@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLock(t *testing.T) {
+	first, mr := newTestCrawler(t)
+	second := New(first.RedisPool)
+	second.Logger = first.Logger
+	third := New(first.RedisPool)
+	third.Logger = first.Logger
+
+	if err := first.Lock("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same config joins the crawl
+	if err := second.Lock("a"); err != nil {
+		t.Fatalf("Lock with the same config = %v, want to join", err)
+	}
+
+	// a different config is refused until everyone has unlocked
+	locked := &JobLockedError{}
+	if err := third.Lock("b"); !errors.As(err, &locked) {
+		t.Fatalf("Lock with a different config = %v, want a JobLockedError", err)
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := third.Lock("b"); !errors.As(err, &locked) {
+		t.Fatalf("Lock with a different config = %v, want a JobLockedError", err)
+	}
+	if err := second.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(first.KeyLock) {
+		t.Errorf("the lock outlived its holders")
+	}
+	if err := third.Lock("b"); err != nil {
+		t.Fatalf("Lock once unlocked = %v", err)
+	}
+	third.Unlock()
+}
+
+func TestLockLapses(t *testing.T) {
+	c, mr := newTestCrawler(t)
+
+	// a process that died holding the lock, long lapsed
+	mr.HSet(c.KeyLock, "config", "a", "owner", "dead", "holder:dead", "1")
+
+	if err := c.Lock("b"); err != nil {
+		t.Fatalf("Lock = %v, want the dead holder's lease to have lapsed", err)
+	}
+	c.Unlock()
+}
@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"mime"
+	"path"
+	"sort"
+	"strings"
+
+	neturl "net/url"
+)
+
+// mediaSource is a video or audio file a page embeds
+type mediaSource struct {
+	url string
+	typ string // the <source type>, if any
+}
+
+// mediaExtensions type the common video and audio files, which the mime
+// package's own table lacks
+var mediaExtensions = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mov":  "video/quicktime",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".aac":  "audio/aac",
+}
+
+// mediaType is the media type of a media source, by its type attribute or
+// else its file extension, "" if neither says
+func mediaType(m mediaSource) string {
+	if m.typ != "" {
+		if t, _, err := mime.ParseMediaType(m.typ); err == nil {
+			return t
+		}
+	}
+
+	u, err := neturl.Parse(m.url)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if t, ok := mediaExtensions[ext]; ok {
+		return t
+	}
+	t, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	return t
+}
+
+// wantsMedia reports whether the media type is one of the MediaTypes,
+// either exactly or by a wildcard such as video/*
+func (c *Crawler) wantsMedia(typ string) bool {
+	if typ == "" {
+		return false
+	}
+	for _, want := range c.MediaTypes {
+		if strings.EqualFold(want, typ) || want == "*/*" {
+			return true
+		}
+		if major, ok := strings.CutSuffix(want, "/*"); ok && strings.HasPrefix(strings.ToLower(typ), strings.ToLower(major)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMedia makes the URLs of the media sources of the MediaTypes
+// absolute, dropping any others
+func (c *Crawler) resolveMedia(base string, sources []mediaSource) []string {
+	media := []string{}
+	for _, m := range sources {
+		if !c.wantsMedia(mediaType(m)) {
+			continue
+		}
+		media = append(media, resolveURLs(base, []string{m.url})...)
+	}
+	return media
+}
+
+// Media returns every video and audio file found of the MediaTypes, sorted
+func (c *Crawler) Media() ([]string, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	media := []string{}
+	err := scanSet(conn, c.KeyMedia, func(members []string) error {
+		media = append(media, members...)
+		return nil
+	})
+	sort.Strings(media)
+	return media, err
+}
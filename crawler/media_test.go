@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractMedia(t *testing.T) {
+	body := `<video poster="/poster.jpg" src="/intro.webm">
+		<source src="/clip.mp4" type="video/mp4; codecs=avc1">
+		<source src="/clip.stream">
+	</video>
+	<audio><source src="/song.mp3"></audio>
+	<picture><source srcset="/pic.webp"><img src="/pic.jpg"></picture>`
+
+	tests := []struct {
+		types []string
+		want  []string
+	}{
+		{nil, []string{}},
+		{[]string{"video/mp4"}, []string{"https://example.com/clip.mp4"}},
+		{[]string{"video/*"}, []string{"https://example.com/intro.webm", "https://example.com/clip.mp4"}},
+		{[]string{"video/*", "audio/mpeg"}, []string{"https://example.com/intro.webm", "https://example.com/clip.mp4", "https://example.com/song.mp3"}},
+	}
+	for _, tt := range tests {
+		c, _ := newTestCrawler(t)
+		c.MediaTypes = tt.types
+		page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+		if !slices.Equal(page.media, tt.want) {
+			t.Errorf("%v: media = %v, want %v", tt.types, page.media, tt.want)
+		}
+		// posters are found regardless
+		if want := []string{"https://example.com/poster.jpg", "https://example.com/pic.jpg"}; !slices.Equal(page.imgSrcs, want) {
+			t.Errorf("%v: images = %v, want %v", tt.types, page.imgSrcs, want)
+		}
+	}
+}
+
+func TestMedia(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<video><source src="/b.mp4"><source src="/a.webm"></video>`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.MediaTypes = []string{"video/*"}
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	media, err := c.Media()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{site.URL + "/a.webm", site.URL + "/b.mp4"}; !slices.Equal(media, want) {
+		t.Errorf("media = %v, want %v", media, want)
+	}
+	if rec, _, _ := c.LookupPage(site.URL + "/"); len(rec.Media) != 2 {
+		t.Errorf("page media = %v, want both", rec.Media)
+	}
+}
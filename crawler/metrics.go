@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics holds the Prometheus collectors for a Crawler, a nil *metrics is
+// valid and records nothing so instrumentation is free when disabled
+type metrics struct {
+	pagesFetched  prometheus.Counter
+	fetchDuration prometheus.Histogram
+	imagesFound   prometheus.Counter
+	httpResponses *prometheus.CounterVec
+	retries       prometheus.Counter
+	dnsLookups    *prometheus.CounterVec
+}
+
+// RegisterMetrics instruments the crawler and registers its collectors
+func (c *Crawler) RegisterMetrics(reg prometheus.Registerer) error {
+	m := &metrics{
+		pagesFetched: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "imgcrawler_pages_fetched_total",
+			Help: "Number of page fetches attempted, including retries.",
+		}),
+		fetchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "imgcrawler_fetch_duration_seconds",
+			Help:    "Latency of page fetches.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		imagesFound: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "imgcrawler_images_found_total",
+			Help: "Number of <img> sources found, including duplicates.",
+		}),
+		httpResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "imgcrawler_http_responses_total",
+			Help: "Page fetch outcomes by HTTP status class (1xx-5xx, or error).",
+		}, []string{"class"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "imgcrawler_fetch_retries_total",
+			Help: "Number of page fetches retried after a transient failure.",
+		}),
+		dnsLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "imgcrawler_dns_lookups_total",
+			Help: "DNS lookups through the DNS cache, by whether they hit or missed it.",
+		}, []string{"result"}),
+	}
+
+	queueDepth := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "imgcrawler_queue_depth",
+		Help: "Number of URLs waiting in the crawl queue.",
+	}, func() float64 {
+		conn := c.RedisPool.Get()
+		defer conn.Close()
+		n, _ := c.queueLen(conn)
+		return float64(n)
+	})
+
+	collectors := []prometheus.Collector{
+		m.pagesFetched,
+		m.fetchDuration,
+		m.imagesFound,
+		m.httpResponses,
+		m.retries,
+		m.dnsLookups,
+		queueDepth,
+	}
+	for _, col := range collectors {
+		if err := reg.Register(col); err != nil {
+			return err
+		}
+	}
+
+	c.metrics = m
+	return nil
+}
+
+func (m *metrics) observeFetch(resp *http.Response, err error, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.pagesFetched.Inc()
+	m.fetchDuration.Observe(elapsed.Seconds())
+
+	class := "error"
+	if err == nil {
+		class = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+	m.httpResponses.WithLabelValues(class).Inc()
+}
+
+func (m *metrics) observeImages(n int) {
+	if m == nil {
+		return
+	}
+	m.imagesFound.Add(float64(n))
+}
+
+func (m *metrics) observeRetry() {
+	if m == nil {
+		return
+	}
+	m.retries.Inc()
+}
+
+func (m *metrics) observeDNS(hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.dnsLookups.WithLabelValues(result).Inc()
+}
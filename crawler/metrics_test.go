@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	flaky := atomic.Int32{}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<img src="/a.png"><img src="/b.png"><a href="/missing">x</a><a href="/flaky">y</a>`))
+		case "/flaky":
+			// fails the first time only
+			if flaky.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<img src="/a.png">`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.RetryBackoff = time.Millisecond
+	reg := prometheus.NewRegistry()
+	if err := c.RegisterMetrics(reg); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RegisterMetrics(reg); err == nil {
+		t.Error("registering the same metrics twice succeeded")
+	}
+
+	c.Seed(site.URL)
+	if depth := gaugeValue(t, reg, "imgcrawler_queue_depth"); depth != 1 {
+		t.Errorf("queue depth = %v, want the seed", depth)
+	}
+	c.Run()
+
+	m := c.metrics
+	if n := testutil.ToFloat64(m.pagesFetched); n != 4 {
+		t.Errorf("pages fetched = %v, want 4 with the retry", n)
+	}
+	if n := testutil.ToFloat64(m.retries); n != 1 {
+		t.Errorf("retries = %v, want 1", n)
+	}
+	if n := testutil.ToFloat64(m.imagesFound); n != 3 {
+		t.Errorf("images found = %v, want 3 counting duplicates", n)
+	}
+	for class, want := range map[string]float64{"2xx": 2, "4xx": 1, "5xx": 1} {
+		if n := testutil.ToFloat64(m.httpResponses.WithLabelValues(class)); n != want {
+			t.Errorf("%s responses = %v, want %v", class, n, want)
+		}
+	}
+	if n := testutil.CollectAndCount(m.fetchDuration); n != 1 {
+		t.Errorf("fetch duration collected %d metrics, want 1", n)
+	}
+	if depth := gaugeValue(t, reg, "imgcrawler_queue_depth"); depth != 0 {
+		t.Errorf("queue depth = %v once done, want 0", depth)
+	}
+}
+
+// gaugeValue gathers the value of the named gauge
+func gaugeValue(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("no %s gathered", name)
+	return 0
+}
@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// LegacyKeys names the flat sets of a v1 crawl, whose queue held bare URL
+// strings rather than encoded Entries
+type LegacyKeys struct {
+	CrawlQ       string
+	VisitedHREFs string
+	ImageSrcs    string
+}
+
+// DefaultLegacyKeys are the key names v1 crawls used by default
+var DefaultLegacyKeys = LegacyKeys{
+	CrawlQ:       "crawlQ",
+	VisitedHREFs: "visitedHREFs",
+	ImageSrcs:    "imageSrcs",
+}
+
+// MigrateStats counts what a migration moved
+type MigrateStats struct {
+	Entries int // bare queue URLs converted to entries
+	Visited int // visited URLs copied
+	Images  int // image URLs copied
+}
+
+// Migrate converts a v1 crawl into this crawler's key layout, so it can be
+// resumed or inspected with the current code. Bare URLs in the legacy queue
+// are re-encoded as Entries and the visited and image sets are copied across.
+// Migrating in place (when the key names match) is supported and members
+// already in the current format are left alone, so Migrate is safe to re-run.
+func (c *Crawler) Migrate(legacy LegacyKeys) (MigrateStats, error) {
+	stats := MigrateStats{}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	var err error
+	if legacy.CrawlQ == c.KeyCrawlQ {
+		// in place, the legacy set becomes the priority queue
+		stats.Entries, err = c.upgradeQueue(conn)
+	} else {
+		err = scanSet(conn, legacy.CrawlQ, func(members []string) error {
+			entries := []Entry{}
+			for _, m := range members {
+				e := Entry{}
+				if err := c.Codec.Unmarshal([]byte(m), &e); err == nil && e.URL != "" {
+					continue // already migrated
+				}
+				entries = append(entries, Entry{URL: m})
+			}
+
+			if len(entries) == 0 {
+				return nil
+			}
+			if err := c.push(conn, entries...); err != nil {
+				return err
+			}
+
+			stats.Entries += len(entries)
+			return nil
+		})
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	if stats.Visited, err = copySet(conn, legacy.VisitedHREFs, c.KeyVisitedHREFs); err != nil {
+		return stats, err
+	}
+	if stats.Images, err = copySet(conn, legacy.ImageSrcs, c.KeyImageSrcs); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// copySet unions src into dest, returning the number of members copied
+func copySet(conn redis.Conn, src string, dest string) (int, error) {
+	if src == dest {
+		return 0, nil
+	}
+
+	before, err := redis.Int(conn.Do("SCARD", dest))
+	if err != nil {
+		return 0, err
+	}
+	after, err := redis.Int(conn.Do("SUNIONSTORE", dest, dest, src))
+	return after - before, err
+}
+
+// scanSet walks a set in batches with SSCAN, so huge sets are never loaded
+// in a single reply. Members may be seen more than once if the set changes.
+func scanSet(conn redis.Conn, key string, fn func(members []string) error) error {
+	cursor := "0"
+	for {
+		members, next, err := scanPage(conn, "SSCAN", key, cursor, DefaultScanCount)
+		if err != nil {
+			return err
+		}
+		cursor = next
+
+		if err := fn(members); err != nil {
+			return err
+		}
+
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// legacyKey is the flat set Compat mirrors one of the crawl's sets to, or ""
+// if it isn't mirrored or is the same set already
+func (c *Crawler) legacyKey(key string) string {
+	if c.Compat == nil {
+		return ""
+	}
+
+	legacy := ""
+	switch key {
+	case c.KeyVisitedHREFs:
+		legacy = c.Compat.VisitedHREFs
+	case c.KeyImageSrcs:
+		legacy = c.Compat.ImageSrcs
+	}
+	if legacy == key {
+		return ""
+	}
+	return legacy
+}
+
+// mirrorLegacy adds the write mirroring a change to one of the crawl's sets
+// to its legacy flat set to the batch, if Compat is set
+func (c *Crawler) mirrorLegacy(b *batch, cmd string, key string, member string) {
+	if legacy := c.legacyKey(key); legacy != "" {
+		b.add(cmd, legacy, member)
+	}
+}
+
+// warnCompat logs that the run is writing the legacy sets, which downstream
+// scripts should stop relying on
+func (c *Crawler) warnCompat() {
+	if c.legacyKey(c.KeyVisitedHREFs) == "" && c.legacyKey(c.KeyImageSrcs) == "" {
+		return
+	}
+	c.Logger.Warn("also writing the legacy flat sets, a compatibility mode that will be removed; read the crawl's own keys instead",
+		"visited", c.Compat.VisitedHREFs, "images", c.Compat.ImageSrcs)
+}
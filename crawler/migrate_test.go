@@ -0,0 +1,111 @@
+package crawler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCompatLegacyKeys(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png"><a href="/next">next</a>`))
+	}))
+	defer site.Close()
+
+	mr := miniredis.RunT(t)
+	c := NewJob(NewPool("tcp", mr.Addr()), "compat")
+	c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	c.Compat = &DefaultLegacyKeys
+
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	mirrored := map[string]string{
+		c.KeyVisitedHREFs: DefaultLegacyKeys.VisitedHREFs,
+		c.KeyImageSrcs:    DefaultLegacyKeys.ImageSrcs,
+	}
+	for key, legacyKey := range mirrored {
+		current, _ := mr.Members(key)
+		legacy, _ := mr.Members(legacyKey)
+		if len(legacy) == 0 || !slices.Equal(legacy, current) {
+			t.Errorf("%s = %v, want the same as %s = %v", legacyKey, legacy, key, current)
+		}
+	}
+}
+
+// seedLegacy fills the flat sets of a v1 crawl, its queue holding bare URLs
+func seedLegacy(mr *miniredis.Miniredis, queued []string, visited []string, images []string) {
+	mr.SAdd(DefaultLegacyKeys.CrawlQ, queued...)
+	mr.SAdd(DefaultLegacyKeys.VisitedHREFs, visited...)
+	mr.SAdd(DefaultLegacyKeys.ImageSrcs, images...)
+}
+
+func TestMigrateIntoJob(t *testing.T) {
+	mr := miniredis.RunT(t)
+	seedLegacy(mr, []string{"https://example.com/a", "https://example.com/b"}, []string{"https://example.com/"}, []string{"https://example.com/x.png"})
+
+	c := NewJob(NewPool("tcp", mr.Addr()), "migrated")
+	stats, err := c.Migrate(DefaultLegacyKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (MigrateStats{Entries: 2, Visited: 1, Images: 1}) {
+		t.Errorf("stats = %+v, want everything moved", stats)
+	}
+
+	status, _ := c.Status()
+	if status.Queued != 2 || status.Visited != 1 || status.Images != 1 {
+		t.Errorf("status = %+v, want the v1 crawl's", status)
+	}
+	if legacy, _ := mr.Members(DefaultLegacyKeys.VisitedHREFs); len(legacy) != 1 {
+		t.Errorf("legacy visited = %v, want it left in place", legacy)
+	}
+
+	// re-running adds nothing new
+	if stats, err := c.Migrate(DefaultLegacyKeys); err != nil || stats.Visited != 0 || stats.Images != 0 {
+		t.Errorf("Migrate again = %+v, %v, want nothing more copied", stats, err)
+	}
+	if status, _ := c.Status(); status.Queued != 2 {
+		t.Errorf("queued %d after migrating again, want 2", status.Queued)
+	}
+}
+
+func TestMigrateInPlace(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="` + r.URL.Path + `.png"><a href="/">home</a>`))
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	seedLegacy(mr, []string{site.URL + "/a"}, []string{site.URL}, []string{site.URL + "/old.png"})
+
+	stats, err := c.Migrate(DefaultLegacyKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 1 || stats.Visited != 0 || stats.Images != 0 {
+		t.Errorf("stats = %+v, want the queue converted and the sets left as they are", stats)
+	}
+	if stats, err := c.Migrate(DefaultLegacyKeys); err != nil || stats.Entries != 0 {
+		t.Errorf("Migrate again = %+v, %v, want nothing left to convert", stats, err)
+	}
+
+	// the crawl carries on from where v1 left it
+	c.Run()
+	images := []string{}
+	it := c.ImageIterator()
+	for it.Next() {
+		images = append(images, it.Member())
+	}
+	slices.Sort(images)
+	if want := []string{site.URL + "/a.png", site.URL + "/old.png"}; !slices.Equal(images, want) {
+		t.Errorf("images = %v, want %v, the visited home page not crawled again", images, want)
+	}
+}
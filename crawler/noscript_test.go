@@ -0,0 +1,27 @@
+package crawler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractNoscript(t *testing.T) {
+	body := `<img class="lazy" src="/placeholder.gif" data-src="/photo.jpg">
+	<noscript><img src="/photo.jpg"><img src="/placeholder.gif"></noscript>
+	<noscript><picture><img src="/other.jpg"></picture></noscript>`
+
+	c, _ := newTestCrawler(t)
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+	if want := []string{"https://example.com/placeholder.gif"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("without ParseNoscript images = %v, want %v", page.imgSrcs, want)
+	}
+
+	c.ParseNoscript = true
+	page = c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+	want := []string{"https://example.com/placeholder.gif", "https://example.com/photo.jpg", "https://example.com/other.jpg"}
+	if !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("images = %v, want %v", page.imgSrcs, want)
+	}
+}
@@ -0,0 +1,284 @@
+package crawler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// PageRecord is everything known about a visited page
+type PageRecord struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"` // 0 if the fetch failed
+	Error  string `json:"error,omitempty"`
+	Depth  int    `json:"depth"`
+	Parent string `json:"parent,omitempty"`
+	// DiscoveredAt is when the page was first queued, zero for pages queued
+	// before this was recorded
+	DiscoveredAt time.Time    `json:"discoveredAt,omitzero"`
+	FetchedAt    time.Time    `json:"fetchedAt"`
+	Bytes        int64        `json:"bytes,omitempty"`   // size of the body downloaded
+	Links        []string     `json:"links"`             // links followed, after robots rules
+	Anchors      []LinkAnchor `json:"anchors,omitempty"` // the anchor of each link followed
+	Images       []string     `json:"images"`            // images found, after robots rules
+	Skipped      bool         `json:"skipped,omitempty"` // by BeforeFetch, OnPageCrawled or Languages
+	// ExternalRedirect is RedirectFollowed, RedirectRecorded or
+	// RedirectSkipped if the page redirected to another host, and
+	// RedirectTarget where to, unless skipped
+	ExternalRedirect string `json:"externalRedirect,omitempty"`
+	RedirectTarget   string `json:"redirectTarget,omitempty"`
+	// Redirects is the chain of redirects followed to FinalURL, where the
+	// page was crawled unless another page redirecting there got to it
+	// first, in which case DuplicateOf is set and the page skipped
+	Redirects   []Redirect `json:"redirects,omitempty"`
+	FinalURL    string     `json:"finalURL,omitempty"`
+	DuplicateOf string     `json:"duplicateOf,omitempty"`
+	// Canonical is the page's <link rel="canonical"> URL, if on its host
+	Canonical string `json:"canonical,omitempty"`
+	// Latency is how long the response took to start arriving, zero for
+	// pages that failed to fetch or were recorded before this was
+	Latency time.Duration `json:"latency,omitempty"`
+	// Frames are the <iframe> and <frame> pages followed, with FollowFrames
+	Frames []string `json:"frames,omitempty"`
+	// Media are the video and audio files found of the MediaTypes
+	Media []string `json:"media,omitempty"`
+	// DataImages are the IDs of the images embedded as data: URIs, see
+	// DataImageRecord
+	DataImages []string `json:"dataImages,omitempty"`
+	// AMP is the page's <link rel="amphtml"> variant, if on its host, and
+	// AMPMerged set if it was crawled as part of the page, with FollowAMP
+	AMP       string `json:"amp,omitempty"`
+	AMPMerged bool   `json:"ampMerged,omitempty"`
+	// InlineSVGs are synthetic references to the <svg> graphics inline in
+	// the page, the page's URL with the fragment #svg-1, #svg-2 and so on
+	InlineSVGs []string `json:"inlineSVGs,omitempty"`
+	// Language is the page's Content-Language, or else <html lang>, and
+	// Alternates its variants in other languages
+	Language   string          `json:"language,omitempty"`
+	Alternates []LangAlternate `json:"alternates,omitempty"`
+	// Simhash is the simhash of the page's text, as 16 hex digits, and
+	// NearDuplicateOf the page crawled it's nearly identical to, with
+	// SkipNearDuplicates
+	Simhash         string `json:"simhash,omitempty"`
+	NearDuplicateOf string `json:"nearDuplicateOf,omitempty"`
+	// Title is the page's <title>, Duration how long it took to fetch and
+	// extract, ImageCount how many Images it has, and Outlinks how many
+	// distinct pages it links to, on any host, whether followed or not
+	Title      string        `json:"title,omitempty"`
+	Duration   time.Duration `json:"duration,omitempty"`
+	ImageCount int           `json:"imageCount"`
+	Outlinks   int           `json:"outlinks"`
+}
+
+// LinkAnchor is the anchor a page links to another through
+type LinkAnchor struct {
+	URL     string `json:"url"`
+	Text    string `json:"text,omitempty"`    // the anchor text, or alt text of images within it
+	Heading string `json:"heading,omitempty"` // the closest heading before the link
+}
+
+// recordPageMeta adds a write storing the page's record to the batch,
+// returning the record
+func (c *Crawler) recordPageMeta(b *batch, entry Entry, page *scrapeResult, skipped bool) PageRecord {
+	rec := PageRecord{
+		URL:          entry.URL,
+		Status:       page.status,
+		Depth:        entry.Depth,
+		Parent:       entry.Parent,
+		DiscoveredAt: entry.Discovered,
+		FetchedAt:    page.fetchedAt,
+		Latency:      page.latency,
+		Bytes:        page.bytes,
+		Links:        page.hrefs,
+		Anchors:      page.anchors,
+		Frames:       page.frames,
+		Media:        page.media,
+		DataImages:   dataImageIDs(page.dataImages),
+		AMP:          page.amp,
+		AMPMerged:    page.ampMerged,
+		InlineSVGs:   inlineSVGRefs(entry.URL, page.inlineSVGs),
+		Language:     page.language,
+		Alternates:   page.alternates,
+		Simhash:      formatSimhash(page.simhash),
+		Title:        page.title,
+		Duration:     page.duration,
+		ImageCount:   len(page.imgSrcs),
+		Outlinks:     page.outlinks,
+		Images:       page.imgSrcs,
+		Skipped:      skipped,
+
+		ExternalRedirect: page.externalRedirect,
+		RedirectTarget:   page.redirectTarget,
+		Redirects:        page.redirects,
+		DuplicateOf:      page.duplicateOf,
+		Canonical:        page.canonical,
+		NearDuplicateOf:  page.nearDuplicateOf,
+	}
+	if len(page.redirects) > 0 {
+		rec.FinalURL = page.finalURL
+	}
+	if page.err != nil {
+		rec.Error = page.err.Error()
+	}
+
+	if data, err := json.Marshal(rec); err == nil {
+		b.add("HSET", c.KeyPages, entry.URL, data)
+		c.queueForPageSinks(b, entry.URL, data)
+	}
+	c.countPage(b, rec.Error != "" || rec.Status >= 400)
+	c.recordFailure(b, rec)
+	return rec
+}
+
+// LookupPage returns the record of a visited page. The URL is normalized the
+// same way crawled URLs are. found is false if the page has no record, which
+// includes pages visited before records were kept, see Visited.
+func (c *Crawler) LookupPage(url string) (rec PageRecord, found bool, err error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	for _, u := range pageURLVariants(url) {
+		data, err := redis.Bytes(conn.Do("HGET", c.KeyPages, u))
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			return rec, false, err
+		}
+
+		err = json.Unmarshal(data, &rec)
+		return rec, err == nil, err
+	}
+
+	return rec, false, nil
+}
+
+// Visited reports whether the page has been visited, the URL is normalized
+// the same way crawled URLs are. With VisitedBloom, unless it keeps the
+// exact set, a page never visited is occasionally reported as visited.
+func (c *Crawler) Visited(url string) (bool, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	for _, u := range pageURLVariants(url) {
+		visited, err := c.hasVisited(conn, u)
+		if err != nil || visited {
+			return visited, err
+		}
+	}
+	return false, nil
+}
+
+// pageURLVariants is the URL as given and as the crawler would have stored it
+func pageURLVariants(url string) []string {
+	variants := []string{url}
+	if u, err := neturl.Parse(url); err == nil {
+		if sanitized := toSanitizedString(u); sanitized != url {
+			variants = append(variants, sanitized)
+		}
+	}
+	return variants
+}
+
+// InboundLink is a link to a page from another page
+type InboundLink struct {
+	From string // the linking page
+	LinkAnchor
+}
+
+// LinksTo returns every recorded link to the page, with its anchor, by
+// scanning the records of every visited page
+func (c *Crawler) LinksTo(url string) ([]InboundLink, error) {
+	targets := map[string]bool{}
+	for _, u := range pageURLVariants(url) {
+		targets[u] = true
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	links := []InboundLink{}
+	cursor := "0"
+	for {
+		pairs, next, err := scanPage(conn, "HSCAN", c.KeyPages, cursor, DefaultScanCount)
+		if err != nil {
+			return nil, err
+		}
+		cursor = next
+
+		for i := 0; i+1 < len(pairs); i += 2 {
+			rec := PageRecord{}
+			if json.Unmarshal([]byte(pairs[i+1]), &rec) != nil {
+				continue
+			}
+			for _, a := range rec.Anchors {
+				if targets[a.URL] {
+					links = append(links, InboundLink{From: rec.URL, LinkAnchor: a})
+				}
+			}
+		}
+
+		if cursor == "0" {
+			break
+		}
+	}
+	return links, nil
+}
+
+// PageIterator walks the record of every page visited, see Iterator
+type PageIterator struct {
+	c      *Crawler
+	cursor string
+	buf    []PageRecord
+	done   bool
+	err    error
+
+	// Count is the batch size hint, DefaultScanCount unless changed before
+	// the first call to Next
+	Count int
+}
+
+// PageIterator iterates over the records of every page visited, in no
+// particular order
+func (c *Crawler) PageIterator() *PageIterator {
+	return &PageIterator{c: c, cursor: "0", Count: DefaultScanCount}
+}
+
+// Next advances to the next record, returning false when the iteration is
+// over or an error occurred
+func (it *PageIterator) Next() bool {
+	if len(it.buf) > 0 {
+		it.buf = it.buf[1:]
+	}
+
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		var pairs []string
+		pairs, it.cursor, it.err = it.c.scanPage("HSCAN", it.c.KeyPages, it.cursor, it.Count)
+		for i := 0; i+1 < len(pairs); i += 2 {
+			rec := PageRecord{}
+			if json.Unmarshal([]byte(pairs[i+1]), &rec) == nil {
+				it.buf = append(it.buf, rec)
+			}
+		}
+		it.done = it.cursor == "0"
+	}
+
+	return true
+}
+
+// Record is the current page record
+func (it *PageIterator) Record() PageRecord {
+	return it.buf[0]
+}
+
+// Err is the error that ended the iteration, if any
+func (it *PageIterator) Err() error {
+	return it.err
+}
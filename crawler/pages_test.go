@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageAudit(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><head><title> Home &amp;
+				Garden </title></head><body><svg><title>Logo</title></svg>
+				<img src="/a.jpg"><img src="/b.jpg">
+				<a href="/about">About</a><a href="/about#team">Team</a>
+				<a href="https://elsewhere.example/">Elsewhere</a><a href="mailto:hi@example.com">Mail</a>`))
+		case "/about":
+			w.Write([]byte(`<svg><title>Logo</title></svg><p>About</p>`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	rec, _, _ := c.LookupPage(site.URL + "/")
+	if rec.Title != "Home & Garden" || rec.ImageCount != 2 || rec.Outlinks != 2 || rec.Duration <= 0 {
+		t.Errorf("record = title %q, %d images, %d outlinks, duration %v, want \"Home & Garden\", 2, 2 and some time",
+			rec.Title, rec.ImageCount, rec.Outlinks, rec.Duration)
+	}
+	if rec, _, _ := c.LookupPage(site.URL + "/about"); rec.Title != "" {
+		t.Errorf("title of page without one = %q, want none", rec.Title)
+	}
+
+	urls := []string{}
+	for it := c.PageIterator(); it.Next(); {
+		urls = append(urls, it.Record().URL)
+	}
+	slices.Sort(urls)
+	if want := []string{site.URL + "/", site.URL + "/about"}; !slices.Equal(urls, want) {
+		t.Errorf("pages = %v, want %v", urls, want)
+	}
+}
+
+func TestLookupPage(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<a href="/next">next</a><a href="/missing">missing</a>`))
+		case "/next":
+			w.Write([]byte(`<img src="/a.png">`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.RetryBackoff = time.Millisecond
+	c.Seed(site.URL, "http://127.0.0.1:1/unreachable")
+	c.Run()
+
+	rec, found, err := c.LookupPage(site.URL + "/next")
+	if err != nil || !found {
+		t.Fatalf("LookupPage(/next) = %v, %v, want the page", found, err)
+	}
+	if rec.URL != site.URL+"/next" || rec.Status != 200 || rec.Depth != 1 || rec.Parent != site.URL || rec.FetchedAt.IsZero() || rec.DiscoveredAt.IsZero() {
+		t.Errorf("record = %+v, want a 200 one link deep from the seed, with its times", rec)
+	}
+	if !slices.Equal(rec.Images, []string{site.URL + "/a.png"}) || rec.Bytes == 0 {
+		t.Errorf("record images %v and %d bytes, want a.png and its size", rec.Images, rec.Bytes)
+	}
+	if rec, _, _ := c.LookupPage(site.URL); !slices.Equal(rec.Links, []string{site.URL + "/missing", site.URL + "/next"}) && !slices.Equal(rec.Links, []string{site.URL + "/next", site.URL + "/missing"}) {
+		t.Errorf("seed links = %v, want both pages", rec.Links)
+	}
+
+	// looked up as the crawler would have stored it
+	if rec, found, _ := c.LookupPage(strings.ToUpper(site.URL) + "/next#top"); !found || rec.URL != site.URL+"/next" {
+		t.Errorf("LookupPage of an unnormalized URL = %q, %v, want /next", rec.URL, found)
+	}
+
+	if rec, _, _ := c.LookupPage(site.URL + "/missing"); rec.Status != 404 {
+		t.Errorf("missing page status = %d, want 404", rec.Status)
+	}
+	if rec, found, _ := c.LookupPage("http://127.0.0.1:1/unreachable"); !found || rec.Status != 0 || rec.Error == "" {
+		t.Errorf("unreachable page = %+v, want no status and the error", rec)
+	}
+
+	// pages never visited, or visited before records were kept
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	c.markVisited(conn, site.URL+"/legacy")
+	for _, tt := range []struct {
+		url     string
+		visited bool
+	}{
+		{site.URL + "/nope", false},
+		{site.URL + "/legacy", true},
+	} {
+		if _, found, err := c.LookupPage(tt.url); found || err != nil {
+			t.Errorf("LookupPage(%s) = %v, %v, want not found", tt.url, found, err)
+		}
+		if visited, err := c.Visited(tt.url); visited != tt.visited || err != nil {
+			t.Errorf("Visited(%s) = %v, %v, want %v", tt.url, visited, err, tt.visited)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Pause asks every worker sharing this crawl to stop after the page in hand,
+// until Resume is called. Paused workers still count as active so the crawl
+// doesn't mistake a pause for completion.
+func (c *Crawler) Pause() error {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", c.KeyPaused, 1)
+	return err
+}
+
+// Resume lets paused workers carry on crawling
+func (c *Crawler) Resume() error {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", c.KeyPaused)
+	return err
+}
+
+// Paused reports whether the crawl is currently paused
+func (c *Crawler) Paused() (bool, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", c.KeyPaused))
+}
+
+// waitWhilePaused blocks while the pause flag is set, returning false if ctx
+// was cancelled in the meantime
+func (c *Crawler) waitWhilePaused(ctx context.Context, w *worker) bool {
+	logger := w.logger
+
+	logged := false
+	for {
+		paused, err := redis.Bool(c.doRetry(ctx, w, "EXISTS", c.KeyPaused))
+		if err != nil {
+			logger.Error("failed to check pause flag", "err", err)
+		}
+		if !paused {
+			if logged {
+				logger.Info("resumed")
+			}
+			return true
+		}
+
+		if !logged {
+			logger.Info("paused")
+			logged = true
+		}
+
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
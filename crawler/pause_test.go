@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	other := NewJob(c.RedisPool, "other")
+
+	if paused, err := c.Paused(); err != nil || paused {
+		t.Fatalf("Paused = %v, %v before pausing", paused, err)
+	}
+	if err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if paused, _ := c.Paused(); !paused {
+		t.Error("not paused after Pause")
+	}
+	if paused, _ := other.Paused(); paused {
+		t.Error("pausing one crawl paused another")
+	}
+
+	if err := c.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if paused, _ := c.Paused(); paused {
+		t.Error("still paused after Resume")
+	}
+	if err := c.Resume(); err != nil {
+		t.Errorf("Resume of a crawl not paused = %v", err)
+	}
+}
+
+func TestWaitWhilePaused(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+
+	if !c.waitWhilePaused(context.Background(), w) {
+		t.Error("waitWhilePaused = false for a crawl not paused")
+	}
+
+	c.Pause()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.Resume()
+	}()
+	start := time.Now()
+	if !c.waitWhilePaused(context.Background(), w) {
+		t.Error("waitWhilePaused = false once resumed")
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("waitWhilePaused returned while still paused")
+	}
+
+	c.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if c.waitWhilePaused(ctx, w) {
+		t.Error("waitWhilePaused = true when cancelled while paused")
+	}
+}
+
+func TestPausedCrawlWaits(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/a.png">`))
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL)
+	c.Pause()
+
+	done := make(chan struct{})
+	go func() {
+		c.Run()
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if status, _ := c.Status(); status.Visited != 0 || status.Queued != 1 {
+		t.Errorf("status = %+v while paused, want the seed still queued", status)
+	}
+
+	c.Resume()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("crawl still paused after Resume")
+	}
+	if status, _ := c.Status(); status.Visited != 1 || status.Images != 1 {
+		t.Errorf("status = %+v, want the seed crawled once resumed", status)
+	}
+}
@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// poolJob is a page passing through a worker's fetch pool
+type poolJob struct {
+	entry   Entry
+	slot    *hostSlot
+	page    *scrapeResult
+	skipped bool
+
+	// ctx carries span, the page's, from fetcher to worker
+	ctx     context.Context
+	span    trace.Span
+	untrack func() // see trackPage
+}
+
+// runPool is run for FetchConcurrency, claiming pages and handing them to a
+// pool of fetchers, then recording the pages as they come back, all Redis
+// work staying on the worker's goroutine and connection. It stops as run
+// does, finishing the pages in flight first.
+func (c *Crawler) runPool(ctx context.Context, fetchCtx context.Context, w *worker) error {
+	w.conn = c.RedisPool.Get()
+	defer func() {
+		c.release(w)
+		w.conn.Close()
+	}()
+
+	jobs := make(chan poolJob)
+	results := make(chan poolJob)
+	fetchers := sync.WaitGroup{}
+	for range c.FetchConcurrency {
+		fetchers.Go(func() {
+			for job := range jobs {
+				job.page, job.skipped = c.fetchPage(job.ctx, w.logger, job.entry)
+				results <- job
+			}
+		})
+	}
+
+	finish := func(job poolJob) bool {
+		w.inFlight--
+		ok := c.finish(job.ctx, w, job.entry, job.page, job.skipped)
+		endPageSpan(job.span, job.page)
+		job.untrack()
+		job.slot.release()
+		return ok
+	}
+	defer func() {
+		close(jobs)
+		for w.inFlight > 0 {
+			finish(<-results)
+		}
+		fetchers.Wait()
+	}()
+
+	for {
+		// top up the pool, as run claims, but with pages in flight don't
+		// wait out a pause or closed crawl window, just stop claiming
+		empty, active := false, 0
+		for w.inFlight < c.FetchConcurrency {
+			if ctx.Err() != nil || c.budgetSpent(w) {
+				return nil
+			}
+			if w.inFlight == 0 {
+				if !c.waitWhilePaused(ctx, w) || !c.waitForCrawlWindow(ctx, w) {
+					return nil
+				}
+			} else if paused, _ := redis.Bool(w.conn.Do("EXISTS", c.KeyPaused)); paused || !c.inCrawlWindow(time.Now()) {
+				break
+			}
+
+			entry, n, err := c.claim(ctx, w)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("worker %s: claiming from the crawl queue: %w", w.id, err)
+			}
+			if entry == nil {
+				empty, active = true, n
+				break
+			}
+
+			var slot *hostSlot
+			if c.MaxConcurrentPerHost > 0 {
+				var ok bool
+				if slot, ok = c.claimHost(w, *entry); !ok {
+					continue
+				}
+			}
+
+			fetch, ok := c.begin(ctx, w, *entry)
+			if !fetch {
+				slot.release()
+				if !ok {
+					return nil
+				}
+				continue
+			}
+			w.inFlight++
+			untrack := c.trackPage(w, *entry)
+			spanCtx, span := c.startPageSpan(fetchCtx, *entry)
+			jobs <- poolJob{entry: *entry, slot: slot, ctx: spanCtx, span: span, untrack: untrack}
+		}
+
+		if w.inFlight == 0 {
+			if empty && c.complete(w, active) {
+				return nil
+			}
+
+			// others may yet queue more, wait and see
+			if !c.waitToClaim(ctx, active) {
+				return nil
+			}
+			continue
+		}
+
+		if !finish(<-results) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchConcurrency(t *testing.T) {
+	var inFlight, most atomic.Int32
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<img src="%s.png">`, r.URL.Path)
+		if r.URL.Path == "/" {
+			for i := range 12 {
+				fmt.Fprintf(w, `<a href="/%d">page</a>`, i)
+			}
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.FetchConcurrency = 4
+	c.Seed(site.URL + "/")
+	c.RunN(1)
+
+	if n := most.Load(); n != 4 {
+		t.Errorf("at most %d pages fetched at once, want 4", n)
+	}
+	if visited, _ := mr.Members(c.KeyVisitedHREFs); len(visited) != 13 {
+		t.Errorf("visited %d pages, want all 13", len(visited))
+	}
+	if images, _ := mr.Members(c.KeyImageSrcs); len(images) != 13 {
+		t.Errorf("found %d images, want all 13", len(images))
+	}
+	if mr.Exists(c.KeyActiveWorkers) {
+		t.Error("worker lease left behind")
+	}
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// probeImages records the size, format and dimensions of any images not yet
+// probed, each image is claimed first so that only one worker probes it
+func (c *Crawler) probeImages(conn redis.Conn, b *batch, imgSrcs []string, logger *slog.Logger) {
+	for _, src := range imgSrcs {
+		claimed, err := redis.Int(conn.Do("HSETNX", c.KeyImageSizes, src, ""))
+		if err != nil {
+			logger.Error("failed to claim image for probing", "url", src, "err", err)
+			return
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		size, err := c.probeImage(src)
+		if err != nil {
+			logger.Warn("failed to probe image", "url", src, "err", err)
+			continue
+		}
+		c.recordImageSize(b, src, size)
+	}
+}
+
+// probeImage fetches just the first ProbeBytes of an image with a Range
+// request, which for most formats covers the header giving its dimensions.
+// The image's full size comes from the Content-Range, or the Content-Length
+// of servers ignoring the Range, in which case only as much is read anyway.
+func (c *Crawler) probeImage(url string) (imageSize, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return imageSize{}, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", c.ProbeBytes-1))
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return imageSize{}, err
+	}
+	defer resp.Body.Close()
+
+	size := imageSize{ContentType: resp.Header.Get("content-type")}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		size.Bytes = contentRangeTotal(resp.Header.Get("content-range"))
+	case http.StatusOK:
+		size.Bytes = max(resp.ContentLength, 0)
+	default:
+		return imageSize{}, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	// formats without a registered decoder, e.g. SVG, or whose header lies
+	// beyond the probe are recorded without dimensions
+	if cfg, _, err := image.DecodeConfig(io.LimitReader(resp.Body, c.ProbeBytes)); err == nil {
+		size.Width, size.Height = cfg.Width, cfg.Height
+	}
+	return size, nil
+}
+
+// contentRangeTotal is the complete length given by a Content-Range, e.g.
+// 48213 for "bytes 0-16383/48213", or 0 if unknown
+func contentRangeTotal(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProbeImage(t *testing.T) {
+	// noise doesn't compress, so the image is far larger than the probe
+	img := image.NewGray(image.Rect(0, 0, 400, 300))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	buf := bytes.Buffer{}
+	png.Encode(&buf, img)
+	data := buf.Bytes()
+
+	served := make(chan int, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ranged.png", func(w http.ResponseWriter, r *http.Request) {
+		rw := &countingResponseWriter{ResponseWriter: w}
+		http.ServeContent(rw, r, "ranged.png", time.Time{}, bytes.NewReader(data))
+		served <- rw.n
+	})
+	mux.HandleFunc("/whole.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.ProbeBytes = 1024
+
+	want := imageSize{ContentType: "image/png", Bytes: int64(len(data)), Width: 400, Height: 300}
+	size, err := c.probeImage(site.URL + "/ranged.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != want {
+		t.Errorf("ranged: probeImage = %+v, want %+v", size, want)
+	}
+	if n := <-served; n != 1024 {
+		t.Errorf("ranged: served %d bytes, want 1024", n)
+	}
+
+	// servers ignoring the Range are only read as far as needed
+	size, err = c.probeImage(site.URL + "/whole.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != want {
+		t.Errorf("whole: probeImage = %+v, want %+v", size, want)
+	}
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += n
+	return n, err
+}
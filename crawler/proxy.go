@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	neturl "net/url"
+)
+
+// client is the HTTP client for every request made while crawling, over
+// connections tuned by Connections, routed through the Proxies if any are
+// set, sending the extra Header and HostHeaders, keeping to
+// MaxHostConnections and RequestTimeout, keeping Cookies in their jar and
+// applying the redirect policy. It's built on first use, so all must be set
+// before crawling starts.
+func (c *Crawler) client() *http.Client {
+	c.clientOnce.Do(func() {
+		base := c.newTransport()
+		if len(c.Proxies) > 0 {
+			base.Proxy = rotateProxies(c.Proxies)
+		}
+		var transport http.RoundTripper = base
+		transport = &decompressTransport{base: transport}
+		if c.WARC != nil {
+			transport = &warcTransport{base: transport, c: c}
+		}
+		if len(c.Header) > 0 || len(c.HostHeaders) > 0 {
+			transport = &headerTransport{
+				base:        transport,
+				header:      canonicalHeader(c.Header),
+				hostHeaders: canonicalHostHeaders(c.HostHeaders),
+			}
+		}
+		if c.MaxHostConnections > 0 {
+			transport = &hostLimitTransport{base: transport, c: c}
+		}
+		c.httpClient = &http.Client{
+			Transport:     transport,
+			Jar:           c.Cookies,
+			CheckRedirect: c.checkRedirect,
+			Timeout:       c.RequestTimeout,
+		}
+	})
+	return c.httpClient
+}
+
+// rotateProxies picks the next proxy for each request, round-robin
+func rotateProxies(proxies []*neturl.URL) func(*http.Request) (*neturl.URL, error) {
+	next := atomic.Uint64{}
+	return func(*http.Request) (*neturl.URL, error) {
+		i := next.Add(1) - 1
+		return proxies[i%uint64(len(proxies))], nil
+	}
+}
@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+
+	neturl "net/url"
+)
+
+// QueryRule drops or rewrites a query parameter of the links found before
+// they're queued, so that URLs differing only in tracking or session
+// parameters are crawled once
+type QueryRule struct {
+	// Host limits the rule to links to this host, or any host if empty
+	Host string
+	// Param is the parameter's name, matched case-insensitively, where a
+	// trailing * matches any suffix, e.g. utm_*
+	Param string
+	// Rewrite sets the parameter to Value, if present, instead of dropping
+	// it
+	Rewrite bool
+	Value   string
+}
+
+// DefaultQueryRules drop the common tracking and session parameters
+var DefaultQueryRules = []QueryRule{
+	{Param: "utm_*"},
+	{Param: "fbclid"},
+	{Param: "gclid"},
+	{Param: "dclid"},
+	{Param: "msclkid"},
+	{Param: "yclid"},
+	{Param: "igshid"},
+	{Param: "mc_cid"},
+	{Param: "mc_eid"},
+	{Param: "_ga"},
+	{Param: "sessionid"},
+	{Param: "jsessionid"},
+	{Param: "phpsessid"},
+}
+
+// ParseQueryRule parses a rule given as "param" to drop the parameter or
+// "param=value" to rewrite it, either prefixed with "host:" to only apply
+// to links to that host, e.g. "utm_*", "shop.example.com:sort=price"
+func ParseQueryRule(s string) (QueryRule, error) {
+	r, rule := QueryRule{}, s
+	if host, rest, ok := strings.Cut(rule, ":"); ok {
+		r.Host, rule = strings.ToLower(host), rest
+	}
+	r.Param, r.Value, r.Rewrite = strings.Cut(rule, "=")
+	if r.Param == "" || strings.Contains(strings.TrimSuffix(r.Param, "*"), "*") {
+		return QueryRule{}, fmt.Errorf("invalid query rule %q, want [host:]param or [host:]param=value", s)
+	}
+	return r, nil
+}
+
+func (r QueryRule) String() string {
+	s := r.Param
+	if r.Rewrite {
+		s += "=" + r.Value
+	}
+	if r.Host != "" {
+		s = r.Host + ":" + s
+	}
+	return s
+}
+
+// matches reports whether the rule applies to the parameter of a link to the
+// host
+func (r QueryRule) matches(host string, param string) bool {
+	if r.Host != "" && !strings.EqualFold(r.Host, host) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.Param, "*"); ok {
+		return len(param) >= len(prefix) && strings.EqualFold(param[:len(prefix)], prefix)
+	}
+	return strings.EqualFold(param, r.Param)
+}
+
+// applyQueryRules drops and rewrites the link's query parameters by the
+// QueryRules, the first rule matching each parameter winning
+func (c *Crawler) applyQueryRules(url string) string {
+	if len(c.QueryRules) == 0 {
+		return url
+	}
+
+	u, err := neturl.Parse(url)
+	if err != nil || u.RawQuery == "" {
+		return url
+	}
+
+	query := u.Query()
+	changed := false
+	for param, values := range query {
+		for _, r := range c.QueryRules {
+			if !r.matches(u.Hostname(), param) {
+				continue
+			}
+			if r.Rewrite {
+				for i := range values {
+					values[i] = r.Value
+				}
+			} else {
+				delete(query, param)
+			}
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return url
+	}
+
+	u.RawQuery = query.Encode()
+	return toSanitizedString(u)
+}
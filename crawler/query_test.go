@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQueryRules(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	for _, s := range []string{"example.com:sort=price", "sessionid"} {
+		r, err := ParseQueryRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.QueryRules = append(c.QueryRules, r)
+	}
+	c.QueryRules = append(c.QueryRules, DefaultQueryRules...)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/a?id=1&utm_source=x&UTM_Medium=y&fbclid=z", "https://example.com/a?id=1"},
+		{"https://example.com/a?utm_source=x", "https://example.com/a"},
+		{"https://example.com/a?sort=name&SessionID=abc", "https://example.com/a?sort=price"},
+		{"https://shop.example.com/a?sort=name", "https://shop.example.com/a?sort=name"},
+		{"https://example.com/a?utmost=1", "https://example.com/a?utmost=1"},
+	}
+	for _, tt := range tests {
+		if got := c.applyQueryRules(tt.url); got != tt.want {
+			t.Errorf("applyQueryRules(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	// links are rewritten before they're queued
+	body := `<a href="/a?utm_campaign=1">one</a><a href="/a?utm_campaign=2">two</a>`
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+	if want := []string{"https://example.com/a", "https://example.com/a"}; !slices.Equal(page.hrefs, want) {
+		t.Errorf("hrefs = %v, want %v", page.hrefs, want)
+	}
+}
+
+func TestParseQueryRuleInvalid(t *testing.T) {
+	for _, s := range []string{"", "example.com:", "=value", "utm_*_id"} {
+		if _, err := ParseQueryRule(s); err == nil {
+			t.Errorf("ParseQueryRule(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// RecrawlDiff is what changed between the last two runs of a recurring crawl
+type RecrawlDiff struct {
+	NewPages      []string
+	RemovedPages  []string
+	NewImages     []string
+	RemovedImages []string
+}
+
+func prevKey(key string) string {
+	return key + ":prev"
+}
+
+// Rotate readies the crawl to run again from scratch, for recurring crawls.
+// The pages visited and images found by the last run are set aside for Diff,
+// anything left in the queue by an interrupted run is dropped, and the
+// render budgets, the order images were found in and any VisitedBloom
+// filter are reset. Combine
+// with ConditionalGet so unchanged pages aren't downloaded again.
+func (c *Crawler) Rotate() error {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	for _, key := range []string{c.KeyVisitedHREFs, c.KeyImageSrcs} {
+		exists, err := redis.Bool(conn.Do("EXISTS", key))
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			_, err = conn.Do("RENAME", key, prevKey(key))
+		} else {
+			_, err = conn.Do("DEL", prevKey(key))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// each run gets the full render budget, and starts with an empty filter
+	_, err := conn.Do("DEL", c.KeyCrawlQ, c.queueEntriesKey(), c.queueDeferredKey(), c.KeyRenders, c.KeyVisitedBloom, c.imageOrderKey())
+	return err
+}
+
+// Diff compares the pages and images of the current run with those of the
+// run before the last Rotate
+func (c *Crawler) Diff() (RecrawlDiff, error) {
+	diff := RecrawlDiff{}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	conn.Send("SDIFF", c.KeyVisitedHREFs, prevKey(c.KeyVisitedHREFs))
+	conn.Send("SDIFF", prevKey(c.KeyVisitedHREFs), c.KeyVisitedHREFs)
+	conn.Send("SDIFF", c.KeyImageSrcs, prevKey(c.KeyImageSrcs))
+	conn.Send("SDIFF", prevKey(c.KeyImageSrcs), c.KeyImageSrcs)
+	reply, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return diff, err
+	}
+
+	_, err = redis.Scan(reply, &diff.NewPages, &diff.RemovedPages, &diff.NewImages, &diff.RemovedImages)
+	return diff, err
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestRotateAndDiff(t *testing.T) {
+	mu := sync.Mutex{}
+	home := `<a href="/a">a</a><a href="/b">b</a><img src="/x.png"><img src="/y.png">`
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Write([]byte(home))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+
+	// rotating before the first run leaves nothing to compare with
+	if err := c.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	c.Seed(site.URL)
+	c.Run()
+	diff, err := c.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.NewPages) != 3 || len(diff.NewImages) != 2 || len(diff.RemovedPages) != 0 || len(diff.RemovedImages) != 0 {
+		t.Errorf("diff of the first run = %+v, want everything new", diff)
+	}
+
+	mu.Lock()
+	home = `<a href="/a">a</a><a href="/c">c</a><img src="/y.png"><img src="/z.png">`
+	mu.Unlock()
+
+	// an interrupted run's queue is dropped, as are the image numbers
+	c.Seed(site.URL + "/left-over")
+	if err := c.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := c.Info(); info.Visited != 0 || info.Queued != 0 || info.Images != 0 {
+		t.Errorf("after Rotate = %+v, want a crawl started afresh", info)
+	}
+	if urls, _, _ := c.ImagesFoundAfter(0, 10); len(urls) != 0 {
+		t.Errorf("images numbered after Rotate = %v", urls)
+	}
+
+	c.Seed(site.URL)
+	c.Run()
+	diff, err = c.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RecrawlDiff{
+		NewPages:      []string{site.URL + "/c"},
+		RemovedPages:  []string{site.URL + "/b"},
+		NewImages:     []string{site.URL + "/z.png"},
+		RemovedImages: []string{site.URL + "/x.png"},
+	}
+	if !slices.Equal(diff.NewPages, want.NewPages) || !slices.Equal(diff.RemovedPages, want.RemovedPages) ||
+		!slices.Equal(diff.NewImages, want.NewImages) || !slices.Equal(diff.RemovedImages, want.RemovedImages) {
+		t.Errorf("diff = %+v, want %+v", diff, want)
+	}
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// RedirectPolicy is what's done when a page redirects to another host
+type RedirectPolicy string
+
+const (
+	// RedirectFollow follows the redirect, recording the images of the page
+	// it leads to but not following that page's links, nor any further
+	// redirects
+	RedirectFollow RedirectPolicy = "follow"
+	// RedirectRecord doesn't follow the redirect, but records where it led
+	RedirectRecord RedirectPolicy = "record"
+	// RedirectSkip neither follows nor records the redirect
+	RedirectSkip RedirectPolicy = "skip"
+)
+
+// Outcomes of an external redirect, as recorded in PageRecord
+const (
+	RedirectFollowed = "followed"
+	RedirectRecorded = "recorded"
+	RedirectSkipped  = "skipped"
+)
+
+// Redirect is a hop of the redirect chain a page was reached through
+type Redirect struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// redirectChain is the redirects followed on the way to the response
+func redirectChain(resp *http.Response) []Redirect {
+	chain := []Redirect{}
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append(chain, Redirect{URL: req.Response.Request.URL.String(), Status: req.Response.StatusCode})
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// redirectState tracks a page fetch's redirects, to apply the policy to any
+// that lead off the page's host
+type redirectState struct {
+	policy  RedirectPolicy
+	outcome string // set once a redirect has led off the page's host
+	target  string // the external URL redirected to
+}
+
+type redirectStateKey struct{}
+
+// withRedirectState marks the context's requests as page fetches, whose
+// redirects are tracked by the state
+func withRedirectState(ctx context.Context, state *redirectState) context.Context {
+	return context.WithValue(ctx, redirectStateKey{}, state)
+}
+
+// checkRedirect is the client's CheckRedirect, following at most
+// MaxRedirects and applying the external redirect policy to page fetches.
+// Redirects that aren't followed leave the redirect as the response.
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > c.MaxRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	state, ok := req.Context().Value(redirectStateKey{}).(*redirectState)
+	if !ok {
+		return nil
+	}
+
+	// already off the page's host, and only allowed the one hop
+	if state.outcome != "" {
+		return http.ErrUseLastResponse
+	}
+	if req.URL.Hostname() == via[0].URL.Hostname() {
+		return nil
+	}
+
+	state.target = req.URL.String()
+	switch state.policy {
+	case RedirectRecord:
+		state.outcome = RedirectRecorded
+		return http.ErrUseLastResponse
+	case RedirectSkip:
+		state.outcome = RedirectSkipped
+		state.target = ""
+		return http.ErrUseLastResponse
+	}
+	state.outcome = RedirectFollowed
+	return nil
+}
+
+// visitAlias marks the URL a page is an alias of, where it redirected to or
+// its canonical URL, as visited, reporting false if it already was
+func (c *Crawler) visitAlias(w *worker, aliasOf string) bool {
+	inserted, err := c.markVisited(w.conn, aliasOf)
+	if err != nil {
+		// better to crawl it twice than not at all
+		w.logger.Error("failed to mark page as visited", "url", aliasOf, "err", err)
+		return true
+	}
+	return inserted
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExternalRedirects(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/img.png"><a href="/next">next</a>`))
+	}))
+	defer external.Close()
+	// the same server under another host name
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+
+	site := httptest.NewServer(http.RedirectHandler(externalURL+"/landing", http.StatusFound))
+	defer site.Close()
+
+	tests := []struct {
+		policy  RedirectPolicy
+		outcome string
+		target  string
+		images  []string
+	}{
+		{RedirectFollow, RedirectFollowed, externalURL + "/landing", []string{externalURL + "/img.png"}},
+		{RedirectRecord, RedirectRecorded, externalURL + "/landing", []string{}},
+		{RedirectSkip, RedirectSkipped, "", []string{}},
+	}
+	for _, tt := range tests {
+		c, _ := newTestCrawler(t)
+		c.ExternalRedirects = tt.policy
+
+		page := c.scrape(t.Context(), site.URL+"/", c.Logger)
+		if page.externalRedirect != tt.outcome || page.redirectTarget != tt.target {
+			t.Errorf("%s: redirect = %q to %q, want %q to %q", tt.policy, page.externalRedirect, page.redirectTarget, tt.outcome, tt.target)
+		}
+		if !slices.Equal(page.imgSrcs, tt.images) {
+			t.Errorf("%s: imgSrcs = %v, want %v", tt.policy, page.imgSrcs, tt.images)
+		}
+		// the external page's links are never followed
+		if len(page.hrefs) != 0 {
+			t.Errorf("%s: hrefs = %v, want none", tt.policy, page.hrefs)
+		}
+	}
+}
+
+func TestRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusMovedPermanently))
+	mux.Handle("/b", http.RedirectHandler("/c", http.StatusPermanentRedirect))
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="img.png">`))
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	page := c.scrape(t.Context(), site.URL+"/a", c.Logger)
+	if page.err != nil {
+		t.Fatal(page.err)
+	}
+	want := []Redirect{{URL: site.URL + "/a", Status: 301}, {URL: site.URL + "/b", Status: 308}}
+	if !slices.Equal(page.redirects, want) {
+		t.Errorf("redirects = %v, want %v", page.redirects, want)
+	}
+	if page.finalURL != site.URL+"/c" {
+		t.Errorf("finalURL = %q, want %q", page.finalURL, site.URL+"/c")
+	}
+	if want := []string{site.URL + "/img.png"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("imgSrcs = %v, want %v", page.imgSrcs, want)
+	}
+
+	c.MaxRedirects = 1
+	page = c.scrape(t.Context(), site.URL+"/a", c.Logger)
+	if page.err == nil || len(page.imgSrcs) != 0 {
+		t.Errorf("with MaxRedirects 1: err = %v, imgSrcs = %v, want an error and no images", page.err, page.imgSrcs)
+	}
+}
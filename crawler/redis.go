@@ -0,0 +1,613 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisOptions are the connection settings most managed Redis offerings
+// require
+type RedisOptions struct {
+	// Password authenticates with AUTH
+	Password string
+	// DB is the database to SELECT, Redis Cluster only has database 0
+	DB int
+	// TLS, if set, encrypts connections with this config
+	TLS *tls.Config
+}
+
+// DialOptions converts the options for redis.Dial, NewPool, NewSentinelPool
+// and NewClusterPool
+func (o RedisOptions) DialOptions() []redis.DialOption {
+	options := []redis.DialOption{
+		redis.DialPassword(o.Password),
+		redis.DialDatabase(o.DB),
+	}
+	if o.TLS != nil {
+		options = append(options, redis.DialUseTLS(true), redis.DialTLSConfig(o.TLS))
+	}
+	return options
+}
+
+// NewPool creates a connection pool for a single Redis server, to pass to
+// New or NewJob
+func NewPool(network string, addr string, options ...redis.DialOption) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial(network, addr, options...)
+		},
+	}
+}
+
+// NewSentinelPool creates a connection pool for the master of a Redis
+// Sentinel deployment. The sentinels are asked for the master's address each
+// time a connection is dialled, so the crawler's reconnects follow failovers.
+func NewSentinelPool(masterName string, sentinels []string, options ...redis.DialOption) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return dialSentinelMaster(masterName, sentinels, options)
+		},
+		// a failed over master carries on serving reads as a replica, so
+		// check idle connections are still to the master before reuse
+		TestOnBorrow: func(conn redis.Conn, lastUsed time.Time) error {
+			if time.Since(lastUsed) < time.Second {
+				return nil
+			}
+			return checkRole(conn, "master")
+		},
+	}
+}
+
+func dialSentinelMaster(masterName string, sentinels []string, options []redis.DialOption) (redis.Conn, error) {
+	var lastErr error
+	for _, sentinel := range sentinels {
+		addr, err := askSentinel(sentinel, masterName)
+		if err != nil {
+			lastErr = fmt.Errorf("sentinel %s: %v", sentinel, err)
+			continue
+		}
+
+		conn, err := redis.Dial("tcp", addr, options...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := checkRole(conn, "master"); err != nil {
+			conn.Close()
+			lastErr = fmt.Errorf("%s: %v", addr, err)
+			continue
+		}
+		return conn, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no sentinels configured")
+	}
+	return nil, lastErr
+}
+
+func askSentinel(sentinel string, masterName string) (string, error) {
+	conn, err := redis.Dial("tcp", sentinel, redis.DialConnectTimeout(5*time.Second), redis.DialReadTimeout(5*time.Second))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", masterName))
+	if err == redis.ErrNil {
+		return "", fmt.Errorf("unknown master %q", masterName)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("unexpected reply %q", reply)
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+func checkRole(conn redis.Conn, want string) error {
+	reply, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	role, err := redis.String(reply[0], nil)
+	if err != nil {
+		return err
+	}
+	if role != want {
+		return fmt.Errorf("role is %s, not %s", role, want)
+	}
+	return nil
+}
+
+// NewClusterPool creates a connection pool for a Redis Cluster, discovering
+// the cluster from any of the given nodes. Each connection sends commands to
+// the node serving their key, following MOVED and ASK redirects as slots
+// migrate.
+//
+// A pipeline goes to a single node, so it must only touch keys in one hash
+// slot. That holds for a job's keys, which share the job ID as a hash tag,
+// but not for the un-namespaced keys of New, so use NewJob with a cluster.
+func NewClusterPool(nodes []string, options ...redis.DialOption) *redis.Pool {
+	cl := &cluster{nodes: nodes, options: options}
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			if err := cl.ensureSlots(); err != nil {
+				return nil, err
+			}
+			return &clusterConn{cluster: cl, conns: map[string]redis.Conn{}}, nil
+		},
+	}
+}
+
+const clusterSlots = 16384
+
+// maxRedirects is how many times a command follows MOVED, ASK and TRYAGAIN
+// replies before the last is returned
+const maxRedirects = 5
+
+// tryAgainDelay is how long a command waits before its first retry after a
+// TRYAGAIN, while the keys of its slot are part way through migrating
+const tryAgainDelay = 20 * time.Millisecond
+
+// cluster is the slot to node mapping shared by every connection
+type cluster struct {
+	nodes   []string
+	options []redis.DialOption
+
+	mu    sync.RWMutex
+	slots []string // the address of the node serving each slot
+	stale atomic.Bool
+}
+
+// ensureSlots loads the slot mapping if it's not loaded yet, or has gone
+// stale since a MOVED redirect
+func (cl *cluster) ensureSlots() error {
+	cl.mu.RLock()
+	loaded := cl.slots != nil
+	cl.mu.RUnlock()
+	if loaded && !cl.stale.Swap(false) {
+		return nil
+	}
+	return cl.refresh()
+}
+
+// refresh reloads the slot mapping from the first node to answer
+func (cl *cluster) refresh() error {
+	cl.mu.RLock()
+	nodes := append([]string{}, cl.nodes...)
+	for _, addr := range cl.slots {
+		if addr != "" && !contains(nodes, addr) {
+			nodes = append(nodes, addr)
+		}
+	}
+	cl.mu.RUnlock()
+
+	var lastErr error
+	for _, node := range nodes {
+		slots, err := cl.loadSlots(node)
+		if err != nil {
+			lastErr = fmt.Errorf("cluster node %s: %v", node, err)
+			continue
+		}
+
+		cl.mu.Lock()
+		cl.slots = slots
+		cl.mu.Unlock()
+		return nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no cluster nodes configured")
+	}
+	return lastErr
+}
+
+func (cl *cluster) loadSlots(node string) ([]string, error) {
+	conn, err := redis.Dial("tcp", node, cl.options...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ranges, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	// each range is [start, end, [master ip, port, ...], replicas...]
+	slots := make([]string, clusterSlots)
+	for _, r := range ranges {
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, fmt.Errorf("unexpected CLUSTER SLOTS reply")
+		}
+		start, _ := redis.Int(fields[0], nil)
+		end, _ := redis.Int(fields[1], nil)
+		master, err := redis.Values(fields[2], nil)
+		if err != nil || len(master) < 2 || start < 0 || end >= clusterSlots {
+			return nil, fmt.Errorf("unexpected CLUSTER SLOTS reply")
+		}
+		ip, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+		if ip == "" {
+			// an empty IP means the node we asked
+			ip, _, _ = net.SplitHostPort(node)
+		}
+
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = addr
+		}
+	}
+	return slots, nil
+}
+
+// nodeFor is the address of the node serving a key, or any node for keyless
+// commands
+func (cl *cluster) nodeFor(key string) string {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	if key != "" {
+		if addr := cl.slots[keySlot(key)]; addr != "" {
+			return addr
+		}
+	}
+	for _, addr := range cl.slots {
+		if addr != "" {
+			return addr
+		}
+	}
+	return cl.nodes[0]
+}
+
+// clusterConn is a connection to a whole cluster, holding a connection to
+// each node it has needed. Pipelined commands are buffered until flushed and
+// then sent to the node serving the first key among them.
+type clusterConn struct {
+	cluster *cluster
+	conns   map[string]redis.Conn
+	last    string // the node last used, for keyless commands
+	pending batch
+	err     error
+}
+
+func (cc *clusterConn) Close() error {
+	for _, conn := range cc.conns {
+		conn.Close()
+	}
+	cc.conns = nil
+	if cc.err == nil {
+		cc.err = errors.New("redis: closed")
+	}
+	return nil
+}
+
+func (cc *clusterConn) Err() error {
+	if cc.err != nil {
+		return cc.err
+	}
+	for _, conn := range cc.conns {
+		if err := conn.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cc *clusterConn) Send(cmd string, args ...interface{}) error {
+	cc.pending.add(cmd, args...)
+	return nil
+}
+
+// Flush is a no-op, buffered commands are sent by Do("")
+func (cc *clusterConn) Flush() error {
+	return nil
+}
+
+func (cc *clusterConn) Receive() (interface{}, error) {
+	return nil, errors.New("redis cluster: Receive is not supported, pipeline with Do(\"\")")
+}
+
+func (cc *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cc.err != nil {
+		return nil, cc.err
+	}
+	if cmd != "" && len(cc.pending) == 0 {
+		return cc.do(cmd, args)
+	}
+
+	if cmd != "" {
+		cc.pending.add(cmd, args...)
+	}
+	return cc.flush(cmd != "")
+}
+
+// do runs a single command, following the cluster's redirects
+func (cc *clusterConn) do(cmd string, args []interface{}) (interface{}, error) {
+	addr := cc.route(commandKey(cmd, args))
+	conn, err := cc.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.Do(cmd, args...)
+	if _, kind := redirectTo(err); kind == "" {
+		return reply, err
+	}
+	replies, err := cc.follow(addr, batch{{name: cmd, args: args}}, []interface{}{err})
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], asError(replies[0])
+}
+
+// flush sends the buffered pipeline, returning every reply or just the last
+// when it ended with a command given to Do
+func (cc *clusterConn) flush(lastOnly bool) (interface{}, error) {
+	pending := cc.pending
+	cc.pending = nil
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	key := ""
+	for _, cmd := range pending {
+		if key = commandKey(cmd.name, cmd.args); key != "" {
+			break
+		}
+	}
+	addr := cc.route(key)
+	conn, err := cc.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range pending {
+		if err := conn.Send(cmd.name, cmd.args...); err != nil {
+			return nil, err
+		}
+	}
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return nil, err
+	}
+	if replies, err = cc.followPipeline(addr, pending, replies); err != nil {
+		return nil, err
+	}
+
+	if !lastOnly {
+		return replies, nil
+	}
+
+	// like Do on a plain connection, the last reply with the first error
+	for _, reply := range replies {
+		if err := asError(reply); err != nil {
+			return replies[len(replies)-1], err
+		}
+	}
+	return replies[len(replies)-1], nil
+}
+
+// followPipeline runs the commands of a pipeline that were redirected again
+// where they were sent. A transaction with any command redirected was
+// aborted, so it's run again as a whole.
+func (cc *clusterConn) followPipeline(addr string, pending batch, replies []interface{}) ([]interface{}, error) {
+	if redirected(replies) == nil {
+		return replies, nil
+	}
+
+	for _, cmd := range pending {
+		if strings.EqualFold(cmd.name, "MULTI") {
+			return cc.follow(addr, pending, replies)
+		}
+	}
+
+	for i, reply := range replies {
+		if _, kind := redirectTo(asError(reply)); kind == "" {
+			continue
+		}
+		followed, err := cc.follow(addr, pending[i:i+1], replies[i:i+1])
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = followed[0]
+	}
+	return replies, nil
+}
+
+// follow runs commands again while they're answered with a redirect: at the
+// node a MOVED or ASK sent them to, or after a TRYAGAIN at the node that
+// answered. Following an ASK each command is preceded by ASKING, on the same
+// connection, for the node importing the slot to serve it.
+func (cc *clusterConn) follow(addr string, pending batch, replies []interface{}) ([]interface{}, error) {
+	ask := false
+	for attempt := 1; attempt <= maxRedirects; attempt++ {
+		target, kind := redirectTo(redirected(replies))
+		switch kind {
+		case "":
+			return replies, nil
+		case "MOVED":
+			// the slots have moved, later commands will go to the right node
+			cc.cluster.stale.Store(true)
+			addr, ask = target, false
+		case "ASK":
+			addr, ask = target, true
+		case "TRYAGAIN":
+			time.Sleep(time.Duration(attempt) * tryAgainDelay)
+		}
+
+		conn, err := cc.conn(addr)
+		if err != nil {
+			return nil, err
+		}
+		for _, cmd := range pending {
+			if ask {
+				if err := conn.Send("ASKING"); err != nil {
+					return nil, err
+				}
+			}
+			if err := conn.Send(cmd.name, cmd.args...); err != nil {
+				return nil, err
+			}
+		}
+		if replies, err = redis.Values(conn.Do("")); err != nil {
+			return nil, err
+		}
+		if ask {
+			// drop the replies to ASKING
+			for i := range pending {
+				replies[i] = replies[2*i+1]
+			}
+			replies = replies[:len(pending)]
+		}
+	}
+	return replies, nil
+}
+
+// redirected is the first of the replies that's a redirect, or nil
+func redirected(replies []interface{}) error {
+	for _, reply := range replies {
+		if err := asError(reply); err != nil {
+			if _, kind := redirectTo(err); kind != "" {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// route picks the node for a command's key, keyless commands stay on the
+// node last used so that MULTI, PING etc. reach the same node as their
+// neighbours
+func (cc *clusterConn) route(key string) string {
+	if key == "" && cc.last != "" {
+		return cc.last
+	}
+	// failing to reload the slots, the old mapping may still serve
+	cc.cluster.ensureSlots()
+	return cc.cluster.nodeFor(key)
+}
+
+func (cc *clusterConn) conn(addr string) (redis.Conn, error) {
+	cc.last = addr
+	if conn, ok := cc.conns[addr]; ok && conn.Err() == nil {
+		return conn, nil
+	}
+
+	conn, err := redis.Dial("tcp", addr, cc.cluster.options...)
+	if err != nil {
+		return nil, err
+	}
+	cc.conns[addr] = conn
+	return conn, nil
+}
+
+func asError(reply interface{}) error {
+	if err, ok := reply.(redis.Error); ok {
+		return err
+	}
+	return nil
+}
+
+// redirectTo parses a MOVED or ASK error into the address to retry at, and
+// which it was. A TRYAGAIN error has no address, the command is retried
+// where it was sent. Other errors are no redirect, "".
+func redirectTo(err error) (addr string, kind string) {
+	rerr, ok := err.(redis.Error)
+	if !ok {
+		return "", ""
+	}
+	fields := strings.Fields(string(rerr))
+	switch {
+	case len(fields) == 3 && (fields[0] == "MOVED" || fields[0] == "ASK"):
+		return fields[2], fields[0]
+	case len(fields) > 0 && fields[0] == "TRYAGAIN":
+		return "", "TRYAGAIN"
+	}
+	return "", ""
+}
+
+// keylessCommands are those without a key to route by
+var keylessCommands = map[string]bool{
+	"PING": true, "MULTI": true, "EXEC": true, "DISCARD": true,
+	"UNWATCH": true, "ROLE": true, "INFO": true, "CLUSTER": true,
+	"SCRIPT": true, "TIME": true, "ASKING": true,
+}
+
+// commandKey is the key a command should be routed by, or "" if it has none
+func commandKey(cmd string, args []interface{}) string {
+	cmd = strings.ToUpper(cmd)
+	switch {
+	case keylessCommands[cmd] || len(args) == 0:
+		return ""
+	case cmd == "EVAL" || cmd == "EVALSHA":
+		if len(args) < 3 || fmt.Sprint(args[1]) == "0" {
+			return ""
+		}
+		return fmt.Sprint(args[2])
+	case cmd == "SCAN":
+		// route by the hash tag of the pattern, if it has one
+		for i := 1; i+1 < len(args); i++ {
+			if strings.EqualFold(fmt.Sprint(args[i]), "MATCH") {
+				return fmt.Sprint(args[i+1])
+			}
+		}
+		return ""
+	}
+
+	switch key := args[0].(type) {
+	case string:
+		return key
+	case []byte:
+		return string(key)
+	}
+	return fmt.Sprint(args[0])
+}
+
+// keySlot is the cluster hash slot of a key, only hashing the {hash tag} if
+// it has one
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum Redis Cluster hashes keys with
+func crc16(s string) uint16 {
+	crc := uint16(0)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package crawler
+
+import "testing"
+
+func TestCRC16(t *testing.T) {
+	// the check value of CRC16-CCITT (XMODEM), as given in the cluster spec
+	if got := crc16("123456789"); got != 0x31c3 {
+		t.Errorf("crc16(123456789) = %#x, want 0x31c3", got)
+	}
+}
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"foo", 12182},
+		{"bar", 5061},
+		{"hello", 866},
+		{"", 0},
+		// only the hash tag is hashed
+		{"{foo}", 12182},
+		{"{foo}:queue", 12182},
+		{"crawl:{foo}:visited", 12182},
+		// the first tag counts, up to the first } after it
+		{"{bar}{foo}", 5061},
+		{"x{bar}}", 5061},
+		// an empty or unclosed tag is no tag, the whole key is hashed
+		{"foo{}{bar}", int(crc16("foo{}{bar}") % clusterSlots)},
+		{"foo{bar", int(crc16("foo{bar") % clusterSlots)},
+		{"foo}bar{", int(crc16("foo}bar{") % clusterSlots)},
+		// { opens the tag, so it may itself be hashed
+		{"foo{{bar}}zap", int(crc16("{bar") % clusterSlots)},
+	}
+	for _, tt := range tests {
+		if got := keySlot(tt.key); got != tt.want {
+			t.Errorf("keySlot(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeySlotSharedTag(t *testing.T) {
+	// a job's keys share its tag, so they're kept together on one node for
+	// the scripts that touch several of them
+	prefix := jobPrefix("job1")
+	keys := []string{prefix + "queue", prefix + "visited", prefix + "active", prefix + "active:lapsed"}
+	want := keySlot(keys[0])
+	for _, key := range keys[1:] {
+		if got := keySlot(key); got != want {
+			t.Errorf("keySlot(%q) = %d, want %d as for %q", key, got, want, keys[0])
+		}
+	}
+}
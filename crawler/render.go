@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// Renderer loads pages in a browser, so that images added by scripts are
+// found too. The response's body is the rendered DOM serialized as HTML, and
+// its status and header those of the page's document.
+type Renderer interface {
+	Render(ctx context.Context, url string) (*http.Response, error)
+}
+
+// renderBudgetScript spends one of the crawl's renders, and one of the
+// host's, unless either budget is already spent
+//
+//	KEYS[1] the renders spent, by "total" and "host:" + the host
+//	ARGV[1] the host, ARGV[2] the crawl's budget and ARGV[3] each host's, 0
+//	for no limit
+var renderBudgetScript = redis.NewScript(1, `
+local budget, hostBudget = tonumber(ARGV[2]), tonumber(ARGV[3])
+local host = "host:" .. ARGV[1]
+if budget > 0 and tonumber(redis.call("HGET", KEYS[1], "total") or 0) >= budget then
+	return 0
+end
+if hostBudget > 0 and tonumber(redis.call("HGET", KEYS[1], host) or 0) >= hostBudget then
+	return 0
+end
+redis.call("HINCRBY", KEYS[1], "total", 1)
+redis.call("HINCRBY", KEYS[1], host, 1)
+return 1
+`)
+
+// render loads the page with the Renderer if the render budgets allow,
+// returning a nil response to fall back to fetching it plainly
+func (c *Crawler) render(ctx context.Context, url string, logger *slog.Logger) *http.Response {
+	if c.Renderer == nil || !c.spendRender(url, logger) {
+		return nil
+	}
+
+	resp, err := c.Renderer.Render(ctx, url)
+	if err != nil {
+		logger.Warn("failed to render page, fetching it instead", "url", url, "err", err)
+		c.reportError(url, err)
+		return nil
+	}
+	return resp
+}
+
+// spendRender reports whether the budgets allow rendering another page of
+// the url's host, spending the render if so
+func (c *Crawler) spendRender(url string, logger *slog.Logger) bool {
+	if c.RenderBudget <= 0 && c.RenderHostBudget <= 0 {
+		return true
+	}
+
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return false
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	spent, err := redis.Bool(renderBudgetScript.Do(conn, c.KeyRenders, u.Hostname(), c.RenderBudget, c.RenderHostBudget))
+	if err != nil {
+		logger.Warn("failed to check render budget, fetching instead", "url", url, "err", err)
+		return false
+	}
+	if !spent {
+		logger.Debug("render budget spent, fetching instead", "url", url)
+	}
+	return spent
+}
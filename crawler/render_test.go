@@ -0,0 +1,23 @@
+package crawler
+
+import "testing"
+
+func TestRenderBudget(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	c.RenderBudget = 3
+	c.RenderHostBudget = 2
+
+	urls := []string{
+		"https://a.example/1",
+		"https://a.example/2",
+		"https://a.example/3", // over a.example's budget
+		"https://b.example/1",
+		"https://b.example/2", // over the crawl's budget
+	}
+	want := []bool{true, true, false, true, false}
+	for i, url := range urls {
+		if got := c.spendRender(url, c.Logger); got != want[i] {
+			t.Errorf("spendRender(%s) = %v, want %v", url, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	neturl "net/url"
+)
+
+// reputationClient looks URLs up when the ReputationService has no Client,
+// not waiting on a hung service for ever
+var reputationClient = &http.Client{Timeout: 10 * time.Second}
+
+// ReputationService checks URLs against an HTTP blocklist service, for use as
+// BeforeFetch. Each URL is looked up with GET <Endpoint>?url=<url>, along
+// with any query the Endpoint has, and the service answers with JSON
+// {"flagged": true|false}.
+type ReputationService struct {
+	Endpoint string
+	// Client defaults to one timing out after 10s
+	Client *http.Client
+}
+
+// Check returns ErrSkipPage if the service flags the URL
+func (s *ReputationService) Check(ctx context.Context, url string) error {
+	endpoint, err := neturl.Parse(s.Endpoint)
+	if err != nil {
+		return err
+	}
+	query := endpoint.Query()
+	query.Set("url", url)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = reputationClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("checking %s: %s", url, resp.Status)
+	}
+
+	verdict := struct {
+		Flagged bool `json:"flagged"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return fmt.Errorf("checking %s: %v", url, err)
+	}
+
+	if verdict.Flagged {
+		return ErrSkipPage
+	}
+	return nil
+}
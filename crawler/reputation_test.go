@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReputationService(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "k" {
+			http.Error(w, "no key", http.StatusForbidden)
+			return
+		}
+		flagged := r.URL.Query().Get("url") == "https://bad.example/?a=1&b=2"
+		fmt.Fprintf(w, `{"flagged": %v}`, flagged)
+	}))
+	defer service.Close()
+
+	s := &ReputationService{Endpoint: service.URL + "/check?key=k"}
+	if err := s.Check(t.Context(), "https://bad.example/?a=1&b=2"); err != ErrSkipPage {
+		t.Errorf("Check(flagged) = %v, want ErrSkipPage", err)
+	}
+	if err := s.Check(t.Context(), "https://good.example/"); err != nil {
+		t.Errorf("Check(unflagged) = %v, want nil", err)
+	}
+}
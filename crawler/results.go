@@ -0,0 +1,365 @@
+package crawler
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DefaultScanCount is the batch size hint used when iterating result sets
+const DefaultScanCount = 1000
+
+// ScanImages returns one page of image URLs. Start with cursor "0" and pass
+// back the returned cursor until it is "0" again. Pages may be smaller or
+// larger than count, and URLs may repeat if the set changes mid-iteration.
+func (c *Crawler) ScanImages(cursor string, count int) (urls []string, next string, err error) {
+	return c.scanPage("SSCAN", c.KeyImageSrcs, cursor, count)
+}
+
+// ImagesFoundAfter returns up to count image URLs in the order they were
+// first found, starting after cursor, and the cursor to pass back for the
+// next. Start with cursor 0, a cursor equal to the one passed means there
+// are none yet. Unlike ScanImages, images are never repeated or missed
+// however many are found meanwhile, so it suits following a crawl as it
+// runs. Images found by versions before this one aren't included.
+func (c *Crawler) ImagesFoundAfter(cursor int64, count int) (urls []string, next int64, err error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do("ZRANGEBYSCORE", c.imageOrderKey(), "("+strconv.FormatInt(cursor, 10), "+inf", "WITHSCORES", "LIMIT", 0, count))
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	next = cursor
+	for i := 0; i+1 < len(reply); i += 2 {
+		url, _ := redis.String(reply[i], nil)
+		if next, err = redis.Int64(reply[i+1], nil); err != nil {
+			return nil, cursor, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, next, nil
+}
+
+// ScanVisited returns one page of visited URLs, see ScanImages
+func (c *Crawler) ScanVisited(cursor string, count int) (urls []string, next string, err error) {
+	return c.scanPage("SSCAN", c.KeyVisitedHREFs, cursor, count)
+}
+
+// ScanDownloads returns one page of image URL to local file mappings, see
+// ScanImages
+func (c *Crawler) ScanDownloads(cursor string, count int) (files map[string]string, next string, err error) {
+	pairs, next, err := c.scanPage("HSCAN", c.KeyDownloads, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+
+	files = map[string]string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		files[pairs[i]] = pairs[i+1]
+	}
+	return files, next, nil
+}
+
+func (c *Crawler) scanPage(cmd string, key string, cursor string, count int) ([]string, string, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	return scanPage(conn, cmd, key, cursor, count)
+}
+
+func scanPage(conn redis.Conn, cmd string, key string, cursor string, count int) ([]string, string, error) {
+	reply, err := redis.Values(conn.Do(cmd, key, cursor, "COUNT", count))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []string
+	if _, err := redis.Scan(reply, &cursor, &items); err != nil {
+		return nil, "", err
+	}
+	return items, cursor, nil
+}
+
+// Iterator walks a result set or hash in batches, so that millions of results
+// can be read without a single huge reply:
+//
+//	it := c.ImageIterator()
+//	for it.Next() {
+//		fmt.Println(it.Member())
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator struct {
+	c      *Crawler
+	cmd    string
+	key    string
+	stride int // 1 for sets, 2 for hashes
+
+	cursor string
+	buf    []string
+	done   bool
+	err    error
+
+	// Count is the batch size hint, DefaultScanCount unless changed before
+	// the first call to Next
+	Count int
+}
+
+// ImageIterator iterates over every image URL found
+func (c *Crawler) ImageIterator() *Iterator {
+	return c.newIterator("SSCAN", c.KeyImageSrcs, 1)
+}
+
+// VisitedIterator iterates over every visited page URL
+func (c *Crawler) VisitedIterator() *Iterator {
+	return c.newIterator("SSCAN", c.KeyVisitedHREFs, 1)
+}
+
+// DownloadIterator iterates over downloaded images, Member is the image URL
+// and Value the local file path
+func (c *Crawler) DownloadIterator() *Iterator {
+	return c.newIterator("HSCAN", c.KeyDownloads, 2)
+}
+
+func (c *Crawler) newIterator(cmd string, key string, stride int) *Iterator {
+	return &Iterator{
+		c:      c,
+		cmd:    cmd,
+		key:    key,
+		stride: stride,
+		cursor: "0",
+		Count:  DefaultScanCount,
+	}
+}
+
+// Next advances to the next item, returning false when the iteration is over
+// or an error occurred
+func (it *Iterator) Next() bool {
+	if len(it.buf) > 0 {
+		it.buf = it.buf[it.stride:]
+	}
+
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.buf, it.cursor, it.err = it.c.scanPage(it.cmd, it.key, it.cursor, it.Count)
+		it.done = it.cursor == "0"
+	}
+
+	return true
+}
+
+// Member is the current set member, or hash field
+func (it *Iterator) Member() string {
+	return it.buf[0]
+}
+
+// Value is the current hash value, always empty when iterating a set
+func (it *Iterator) Value() string {
+	if it.stride < 2 {
+		return ""
+	}
+	return it.buf[1]
+}
+
+// Err is the error that ended the iteration, if any
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// ImageRecord is an image found during the crawl along with where and when
+// it was first found
+type ImageRecord struct {
+	// ID is stable for an image URL, sinks should use it to deduplicate
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	PageURL string    `json:"pageURL"`
+	FoundAt time.Time `json:"foundAt"`
+	// ContentType is guessed from the URL's file extension, unless the
+	// image was fetched, as were its size and dimensions, which are only
+	// populated by RecordIterator
+	ContentType string `json:"contentType,omitempty"`
+	Bytes       int64  `json:"bytes,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// Pages is every page the image was found on, up to MaxImagePages, only
+	// populated by RecordIterator
+	Pages []string `json:"pages,omitempty"`
+}
+
+func newImageRecord(imgURL string, pageURL string, foundAt time.Time) ImageRecord {
+	return ImageRecord{
+		ID:          urlHash(imgURL),
+		URL:         imgURL,
+		PageURL:     pageURL,
+		FoundAt:     foundAt,
+		ContentType: guessContentType(imgURL),
+	}
+}
+
+// decodeImageRecord decodes a stored record, images recorded without one
+// get a record of just their URL
+func decodeImageRecord(imgURL string, data []byte) ImageRecord {
+	r := ImageRecord{}
+	if err := json.Unmarshal(data, &r); err != nil || r.URL == "" {
+		r = ImageRecord{ID: urlHash(imgURL), URL: imgURL}
+	}
+	return r
+}
+
+// RecordIterator walks every image found along with its ImageRecord, see
+// Iterator
+type RecordIterator struct {
+	c      *Crawler
+	cursor string
+	buf    []ImageRecord
+	done   bool
+	err    error
+
+	// Count is the batch size hint, DefaultScanCount unless changed before
+	// the first call to Next
+	Count int
+}
+
+// RecordIterator iterates over the records of every image found
+func (c *Crawler) RecordIterator() *RecordIterator {
+	return &RecordIterator{c: c, cursor: "0", Count: DefaultScanCount}
+}
+
+// Next advances to the next record, returning false when the iteration is
+// over or an error occurred
+func (it *RecordIterator) Next() bool {
+	if len(it.buf) > 0 {
+		it.buf = it.buf[1:]
+	}
+
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		var urls []string
+		urls, it.cursor, it.err = it.c.ScanImages(it.cursor, it.Count)
+		if it.err == nil {
+			it.buf, it.err = it.c.imageRecords(urls)
+		}
+		it.done = it.cursor == "0"
+	}
+
+	return true
+}
+
+// Record is the current image record
+func (it *RecordIterator) Record() ImageRecord {
+	return it.buf[0]
+}
+
+// Err is the error that ended the iteration, if any
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+// imageRecords looks up the records of a batch of images
+func (c *Crawler) imageRecords(urls []string) ([]ImageRecord, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	conn.Send("HMGET", redis.Args{c.KeyImageMeta}.AddFlat(urls)...)
+	conn.Send("HMGET", redis.Args{c.KeyImageSizes}.AddFlat(urls)...)
+	for _, url := range urls {
+		conn.Send("SMEMBERS", c.imagePagesKey(url))
+	}
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return nil, err
+	}
+
+	metas, err := redis.ByteSlices(replies[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := redis.ByteSlices(replies[1], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]ImageRecord, len(urls))
+	for i, url := range urls {
+		records[i] = decodeImageRecord(url, metas[i])
+		if records[i].Pages, err = redis.Strings(replies[i+2], nil); err != nil {
+			return nil, err
+		}
+
+		// what was learnt fetching the image beats guessing
+		size := imageSize{}
+		if sizes[i] != nil && json.Unmarshal(sizes[i], &size) == nil {
+			if size.ContentType != "" {
+				records[i].ContentType = size.ContentType
+			}
+			records[i].Bytes, records[i].Width, records[i].Height = size.Bytes, size.Width, size.Height
+		}
+	}
+	return records, nil
+}
+
+// Occurrences splits a record into one per page the image was found on, the
+// first page first, for consumers that want an image recorded per page
+func (r ImageRecord) Occurrences() []ImageRecord {
+	occurrences := []ImageRecord{r}
+	occurrences[0].Pages = nil
+	for _, page := range r.Pages {
+		if page == r.PageURL {
+			continue
+		}
+		occurrence := r
+		occurrence.PageURL = page
+		occurrence.Pages = nil
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
+
+// addCappedScript adds a member to a set unless it has reached a size limit
+var addCappedScript = redis.NewScript(1, `
+if redis.call("SCARD", KEYS[1]) < tonumber(ARGV[2]) then
+	return redis.call("SADD", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// recordOrderScript numbers each image not seen before, in the order found,
+// so the numbers are never reused and only ever grow
+var recordOrderScript = redis.NewScript(1, `
+for _, src in ipairs(ARGV) do
+	if not redis.call("ZSCORE", KEYS[1], src) then
+		redis.call("ZADD", KEYS[1], redis.call("ZCARD", KEYS[1]) + 1, src)
+	end
+end
+return 0
+`)
+
+// imageOrderKey is the key of the sorted set of images by the order found
+func (c *Crawler) imageOrderKey() string {
+	return c.KeyImageSrcs + ":order"
+}
+
+func (c *Crawler) imagePagesKey(imgURL string) string {
+	return c.KeyImagePages + ":" + imgURL
+}
+
+// ImagePages returns every page an image was found on
+func (c *Crawler) ImagePages(imgURL string) ([]string, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	return redis.Strings(conn.Do("SMEMBERS", c.imagePagesKey(imgURL)))
+}
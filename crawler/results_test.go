@@ -0,0 +1,264 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// addMembers adds n numbered members to a set, returning them sorted
+func addMembers(t *testing.T, c *Crawler, key string, n int) []string {
+	t.Helper()
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	members := make([]string, n)
+	for i := range members {
+		members[i] = fmt.Sprintf("https://example.com/%04d.png", i)
+		if _, err := conn.Do("SADD", key, members[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return members
+}
+
+func TestScanImages(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	want := addMembers(t, c, c.KeyImageSrcs, 250)
+
+	got := []string{}
+	pages := 0
+	cursor := "0"
+	for {
+		urls, next, err := c.ScanImages(cursor, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, urls...)
+		pages++
+		if cursor = next; cursor == "0" {
+			break
+		}
+	}
+
+	slices.Sort(got)
+	got = slices.Compact(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("scanned %d images, want all %d", len(got), len(want))
+	}
+	if pages < 3 {
+		t.Errorf("scanned in %d pages, want at least 3 of 100", pages)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	images := addMembers(t, c, c.KeyImageSrcs, 250)
+	visited := addMembers(t, c, c.KeyVisitedHREFs, 3)
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	conn.Do("HSET", c.KeyDownloads, images[0], "/tmp/0.png", images[1], "/tmp/1.png")
+
+	collect := func(it *Iterator) []string {
+		members := []string{}
+		for it.Next() {
+			members = append(members, it.Member()+it.Value())
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(members)
+		return slices.Compact(members)
+	}
+
+	it := c.ImageIterator()
+	it.Count = 10
+	if got := collect(it); !slices.Equal(got, images) {
+		t.Errorf("ImageIterator walked %d images, want %d", len(got), len(images))
+	}
+	if got := collect(c.VisitedIterator()); !slices.Equal(got, visited) {
+		t.Errorf("VisitedIterator = %v, want %v", got, visited)
+	}
+	if got, want := collect(c.DownloadIterator()), []string{images[0] + "/tmp/0.png", images[1] + "/tmp/1.png"}; !slices.Equal(got, want) {
+		t.Errorf("DownloadIterator = %v, want %v", got, want)
+	}
+
+	files, next, err := c.ScanDownloads("0", 10)
+	if err != nil || next != "0" || len(files) != 2 || files[images[1]] != "/tmp/1.png" {
+		t.Errorf("ScanDownloads = %v, %q, %v", files, next, err)
+	}
+
+	// nothing to walk
+	if it := c.newIterator("SSCAN", "empty", 1); it.Next() || it.Err() != nil {
+		t.Errorf("iterating an empty set: Next = true or Err = %v", it.Err())
+	}
+}
+
+func TestIteratorError(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	addMembers(t, c, c.KeyImageSrcs, 3)
+	mr.Close()
+
+	it := c.ImageIterator()
+	if it.Next() {
+		t.Error("Next = true with Redis gone")
+	}
+	if it.Err() == nil {
+		t.Error("Err = nil with Redis gone")
+	}
+}
+
+func TestImagesFoundAfter(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><img src="/b.png"><a href="/next">next</a>`))
+		case "/next":
+			w.Write([]byte(`<img src="/b.png"><img src="/c.png">`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL)
+	c.Run()
+
+	found := []string{}
+	cursor := int64(0)
+	for {
+		urls, next, err := c.ImagesFoundAfter(cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(urls) == 0 {
+			if next != cursor {
+				t.Errorf("cursor moved from %d to %d with nothing found", cursor, next)
+			}
+			break
+		}
+		if next <= cursor {
+			t.Fatalf("cursor went from %d to %d", cursor, next)
+		}
+		found = append(found, urls...)
+		cursor = next
+	}
+
+	want := []string{site.URL + "/a.png", site.URL + "/b.png", site.URL + "/c.png"}
+	if !slices.Equal(found, want) {
+		t.Errorf("images found = %v, want each once in the order found %v", found, want)
+	}
+
+	// a run after Rotate numbers its images afresh
+	if err := c.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if urls, _, _ := c.ImagesFoundAfter(0, 10); len(urls) != 0 {
+		t.Errorf("images found after Rotate = %v, want none", urls)
+	}
+}
+
+func TestRecordIterator(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><img src="/b.jpg"><a href="/next">next</a>`))
+		case "/next":
+			w.Write([]byte(`<img src="/b.jpg">`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL)
+	c.Run()
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	// what was learnt fetching it replaces the guessed type, and images from
+	// before records were kept get one of just their URL
+	conn.Do("HSET", c.KeyImageSizes, site.URL+"/b.jpg", `{"contentType": "image/webp", "bytes": 512, "width": 4, "height": 2}`)
+	conn.Do("SADD", c.KeyImageSrcs, site.URL+"/legacy.gif")
+
+	records := map[string]ImageRecord{}
+	it := c.RecordIterator()
+	it.Count = 1
+	for it.Next() {
+		records[it.Record().URL] = it.Record()
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %v, want 3", records)
+	}
+
+	a := records[site.URL+"/a.png"]
+	if a.ID != urlHash(a.URL) || a.PageURL != site.URL || a.FoundAt.IsZero() || a.ContentType != "image/png" || len(a.Pages) != 1 {
+		t.Errorf("a.png = %+v, want it found on the first page, its type guessed", a)
+	}
+	b := records[site.URL+"/b.jpg"]
+	if b.ContentType != "image/webp" || b.Bytes != 512 || b.Width != 4 || b.Height != 2 {
+		t.Errorf("b.jpg = %+v, want its fetched type and size", b)
+	}
+	if legacy := records[site.URL+"/legacy.gif"]; legacy.ID != urlHash(legacy.URL) || legacy.PageURL != "" {
+		t.Errorf("legacy.gif = %+v, want a record of just its URL", legacy)
+	}
+
+	occurrences := b.Occurrences()
+	if len(occurrences) != 2 || occurrences[0].PageURL != site.URL || occurrences[1].PageURL != site.URL+"/next" {
+		t.Fatalf("b.jpg occurs %+v, want the first page first then /next", occurrences)
+	}
+	for _, o := range occurrences {
+		if o.Pages != nil || o.URL != b.URL || o.Bytes != b.Bytes {
+			t.Errorf("occurrence = %+v, want a copy of the record without its pages", o)
+		}
+	}
+}
+
+func TestImagePages(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<img src="/shared.png"><a href="/1">1</a><a href="/2">2</a><a href="/3">3</a>`))
+			return
+		}
+		w.Write([]byte(`<img src="/shared.png"><img src="/` + r.URL.Path[1:] + `.png">`))
+	}))
+	defer site.Close()
+
+	for _, max := range []int{0, 2} {
+		c, _ := newTestCrawler(t)
+		c.MaxImagePages = max
+		c.Seed(site.URL)
+		c.Run()
+
+		pages, err := c.ImagePages(site.URL + "/shared.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 4
+		if max > 0 {
+			want = max
+		}
+		if len(pages) != want {
+			t.Errorf("MaxImagePages %d: shared image found on %v, want %d pages", max, pages, want)
+		}
+		for _, page := range pages {
+			if !strings.HasPrefix(page, site.URL) {
+				t.Errorf("shared image found on %q, not a page crawled", page)
+			}
+		}
+
+		if pages, _ := c.ImagePages(site.URL + "/2.png"); !slices.Equal(pages, []string{site.URL + "/2"}) {
+			t.Errorf("MaxImagePages %d: 2.png found on %v, want just /2", max, pages)
+		}
+		if pages, err := c.ImagePages(site.URL + "/nope.png"); len(pages) != 0 || err != nil {
+			t.Errorf("ImagePages of an image not found = %v, %v", pages, err)
+		}
+	}
+}
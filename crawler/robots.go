@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"strings"
+)
+
+// Politeness configures which robots directives the crawler obeys
+type Politeness struct {
+	// Sources of directives
+	MetaRobots  bool // <meta name="robots" content="...">
+	XRobotsTag  bool // X-Robots-Tag response header
+	RelNoFollow bool // rel="nofollow" on individual anchors
+
+	// Effects of page-level directives
+	NoFollow bool // don't follow links from nofollow pages
+	NoIndex  bool // don't collect images from noindex/noimageindex pages
+}
+
+// robotsDirectives are the indexing directives a page declares about itself
+type robotsDirectives struct {
+	noIndex      bool
+	noFollow     bool
+	noImageIndex bool
+}
+
+func (d *robotsDirectives) merge(other robotsDirectives) {
+	d.noIndex = d.noIndex || other.noIndex
+	d.noFollow = d.noFollow || other.noFollow
+	d.noImageIndex = d.noImageIndex || other.noImageIndex
+}
+
+// parseRobotsDirectives parses a comma-separated directive list as found in
+// both <meta name="robots"> content and X-Robots-Tag values
+func parseRobotsDirectives(content string) robotsDirectives {
+	d := robotsDirectives{}
+	for _, directive := range strings.Split(content, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "none":
+			d.noIndex = true
+			d.noFollow = true
+		case "noindex":
+			d.noIndex = true
+		case "nofollow":
+			d.noFollow = true
+		case "noimageindex":
+			d.noImageIndex = true
+		}
+	}
+	return d
+}
+
+// parseXRobotsTag merges all X-Robots-Tag header values, ignoring those
+// scoped to a specific user-agent (e.g. "googlebot: noindex")
+func parseXRobotsTag(values []string) robotsDirectives {
+	d := robotsDirectives{}
+	for _, v := range values {
+		first := strings.SplitN(v, ",", 2)[0]
+		if i := strings.Index(first, ":"); i >= 0 && !isRobotsDirectiveWithValue(first[:i]) {
+			continue
+		}
+		d.merge(parseRobotsDirectives(v))
+	}
+	return d
+}
+
+// isRobotsDirectiveWithValue reports whether name is a directive that takes a
+// "name: value" form, as opposed to a user-agent prefix
+func isRobotsDirectiveWithValue(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+// hasToken reports whether a space-separated attribute value (such as rel)
+// contains the given token
+func hasToken(attrVal string, token string) bool {
+	for _, t := range strings.Fields(attrVal) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
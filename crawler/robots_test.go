@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseRobotsDirectives(t *testing.T) {
+	tests := []struct {
+		content string
+		want    robotsDirectives
+	}{
+		{"", robotsDirectives{}},
+		{"index, follow", robotsDirectives{}},
+		{"noindex", robotsDirectives{noIndex: true}},
+		{"NOFOLLOW", robotsDirectives{noFollow: true}},
+		{" noindex ,  nofollow ", robotsDirectives{noIndex: true, noFollow: true}},
+		{"none", robotsDirectives{noIndex: true, noFollow: true}},
+		{"noimageindex", robotsDirectives{noImageIndex: true}},
+		{"max-snippet:0, noarchive", robotsDirectives{}},
+		{"noindexes", robotsDirectives{}},
+	}
+	for _, tt := range tests {
+		if got := parseRobotsDirectives(tt.content); got != tt.want {
+			t.Errorf("parseRobotsDirectives(%q) = %+v, want %+v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestParseXRobotsTag(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   robotsDirectives
+	}{
+		{nil, robotsDirectives{}},
+		{[]string{"noindex"}, robotsDirectives{noIndex: true}},
+		{[]string{"noindex", "nofollow"}, robotsDirectives{noIndex: true, noFollow: true}},
+		{[]string{"googlebot: noindex"}, robotsDirectives{}},
+		{[]string{"googlebot: noindex, nofollow", "noimageindex"}, robotsDirectives{noImageIndex: true}},
+		{[]string{"unavailable_after: 25 Jun 2010 15:00:00 PST, noindex"}, robotsDirectives{noIndex: true}},
+		{[]string{"max-image-preview: none, nofollow"}, robotsDirectives{noFollow: true}},
+		{[]string{"nofollow, max-snippet: 10"}, robotsDirectives{noFollow: true}},
+	}
+	for _, tt := range tests {
+		if got := parseXRobotsTag(tt.values); got != tt.want {
+			t.Errorf("parseXRobotsTag(%q) = %+v, want %+v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestHasToken(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"nofollow", true},
+		{"noopener NoFollow", true},
+		{"  ugc   nofollow  ", true},
+		{"nofollower", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasToken(tt.rel, "nofollow"); got != tt.want {
+			t.Errorf("hasToken(%q, nofollow) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestPoliteness(t *testing.T) {
+	const (
+		metaNone     = `<meta name="robots" content="noindex, nofollow"><img src="/a.png"><a href="/b">b</a>`
+		metaNoImages = `<meta name="ROBOTS" content="noimageindex"><img src="/a.png"><a href="/b">b</a>`
+		plain        = `<img src="/a.png"><a href="/b">b</a>`
+		relNoFollow  = `<a href="/b" rel="ugc nofollow">b</a><a href="/c">c</a>`
+	)
+	noneHeader := http.Header{"X-Robots-Tag": {"noindex, nofollow"}}
+	scopedHeader := http.Header{"X-Robots-Tag": {"otherbot: noindex, nofollow"}}
+
+	tests := []struct {
+		name       string
+		politeness func(p *Politeness)
+		header     http.Header
+		body       string
+		images     int
+		links      int
+	}{
+		{"meta obeyed", nil, nil, metaNone, 0, 0},
+		{"meta ignored", func(p *Politeness) { p.MetaRobots = false }, nil, metaNone, 1, 1},
+		{"meta noindex not obeyed", func(p *Politeness) { p.NoIndex = false }, nil, metaNone, 1, 0},
+		{"meta nofollow not obeyed", func(p *Politeness) { p.NoFollow = false }, nil, metaNone, 0, 1},
+		{"noimageindex obeyed", nil, nil, metaNoImages, 0, 1},
+		{"noimageindex not obeyed", func(p *Politeness) { p.NoIndex = false }, nil, metaNoImages, 1, 1},
+		{"header obeyed", nil, noneHeader, plain, 0, 0},
+		{"header ignored", func(p *Politeness) { p.XRobotsTag = false }, noneHeader, plain, 1, 1},
+		{"header for another bot", nil, scopedHeader, plain, 1, 1},
+		{"rel nofollow obeyed", nil, nil, relNoFollow, 0, 1},
+		{"rel nofollow ignored", func(p *Politeness) { p.RelNoFollow = false }, nil, relNoFollow, 0, 2},
+		{"nothing obeyed", func(p *Politeness) { *p = Politeness{} }, noneHeader, metaNone, 1, 1},
+	}
+	for _, tt := range tests {
+		// every switch is on by default
+		c, _ := newTestCrawler(t)
+		if tt.politeness != nil {
+			tt.politeness(&c.Politeness)
+		}
+		header := tt.header
+		if header == nil {
+			header = http.Header{}
+		}
+
+		page := c.extract("https://example.com/", header, strings.NewReader(tt.body), c.Logger)
+		if len(page.imgSrcs) != tt.images || len(page.hrefs) != tt.links {
+			t.Errorf("%s: found images %v and links %v, want %d and %d", tt.name, page.imgSrcs, page.hrefs, tt.images, tt.links)
+		}
+	}
+}
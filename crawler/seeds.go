@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// seedBatchSize is how many seeds SeedFromReader queues per round trip
+const seedBatchSize = 1000
+
+// SeedFromReader adds the URLs listed in r, one per line, to the crawl
+// queue, a batch of them per round trip so that lists of millions of seeds
+// are neither held in memory nor queued one by one. Blank lines and lines
+// starting with # are skipped, as are URLs on the DenyHosts. It returns how
+// many URLs were queued.
+func (c *Crawler) SeedFromReader(r io.Reader) (int, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	if err := c.register(conn); err != nil {
+		return 0, err
+	}
+	if _, err := c.upgradeQueue(conn); err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	urls := make([]string, 0, seedBatchSize)
+	flush := func() error {
+		b := batch{}
+		entries := c.seedEntries(urls)
+		if err := c.enqueue(&b, entries...); err != nil {
+			return err
+		}
+		if err := b.exec(conn); err != nil {
+			return err
+		}
+		queued += len(entries)
+		urls = urls[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+		if len(urls) == seedBatchSize {
+			if err := flush(); err != nil {
+				return queued, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return queued, err
+	}
+	return queued, flush()
+}
+
+// seedEntries are the queue entries of seed URLs, less those on the
+// DenyHosts
+func (c *Crawler) seedEntries(urls []string) []Entry {
+	entries := make([]Entry, 0, len(urls))
+	for _, url := range c.allowed(urls) {
+		entries = append(entries, Entry{URL: url, Priority: c.priority(url, 0, nil)})
+	}
+	return entries
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSeedFromReader(t *testing.T) {
+	c, mr := newTestCrawler(t)
+
+	list := strings.Builder{}
+	list.WriteString("# seeds\n\n")
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&list, "  https://example.com/%d\n", i)
+	}
+
+	queued, err := c.SeedFromReader(strings.NewReader(list.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued != 2500 {
+		t.Errorf("queued %d seeds, want 2500", queued)
+	}
+	if members, _ := mr.ZMembers(c.KeyCrawlQ); len(members) != 2500 {
+		t.Errorf("queue holds %d entries, want 2500", len(members))
+	}
+}
+
+func TestSeedMany(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	c.Seed("https://example.com/a", "https://example.org/b")
+
+	members, _ := mr.ZMembers(c.KeyCrawlQ)
+	if len(members) != 2 {
+		t.Fatalf("queue = %v, want both seeds", members)
+	}
+	for _, m := range members {
+		e := Entry{}
+		if err := c.Codec.Unmarshal([]byte(mr.HGet(c.queueEntriesKey(), m)), &e); err != nil || e.URL != m || e.Depth != 0 || e.Parent != "" {
+			t.Errorf("queued %s, want a seed entry", m)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// shingleWords is how many consecutive words each shingle of a page's text
+// spans
+const shingleWords = 3
+
+// simhashBands is how many bands of bits simhashes are indexed by, pages
+// differing by fewer bits than that share at least one band
+const simhashBands = 4
+
+// simhasher computes the simhash of a text as it's written, from the
+// hashes of its shingles, so that texts with mostly the same shingles have
+// hashes that differ in only a few bits
+type simhasher struct {
+	weights [64]int
+	window  []string // the last shingleWords-1 words
+	words   int
+	hashed  int // shingles hashed
+}
+
+// write adds the words of the text
+func (s *simhasher) write(text []byte) {
+	for _, word := range strings.FieldsFunc(string(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		s.window = append(s.window, strings.ToLower(word))
+		s.words++
+		if len(s.window) == shingleWords {
+			s.shingle(s.window)
+			s.window = s.window[1:]
+		}
+	}
+}
+
+func (s *simhasher) shingle(words []string) {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(words, " ")))
+	sum := h.Sum64()
+	for i := range s.weights {
+		if sum&(1<<i) != 0 {
+			s.weights[i]++
+		} else {
+			s.weights[i]--
+		}
+	}
+	s.hashed++
+}
+
+// sum is the text's simhash, 0 if it has no words
+func (s simhasher) sum() uint64 {
+	if s.words == 0 {
+		return 0
+	}
+	// texts too short for a whole shingle are one
+	if s.hashed == 0 {
+		s.shingle(s.window)
+	}
+
+	hash := uint64(0)
+	for i, w := range s.weights {
+		if w > 0 {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// formatSimhash formats a simhash as 16 hex digits, or "" for none
+func formatSimhash(hash uint64) string {
+	if hash == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// simhashBandKey is the key of the set of simhashes sharing the band's
+// bits with the hash
+func (c *Crawler) simhashBandKey(hash uint64, band int) string {
+	width := 64 / simhashBands
+	return fmt.Sprintf("%s:%d:%x", c.KeySimhashes, band, (hash>>(band*width))&(1<<width-1))
+}
+
+// nearDuplicate returns the page already crawled whose simhash differs
+// from the hash by NearDuplicateBits bits or fewer, if any
+func (c *Crawler) nearDuplicate(conn redis.Conn, url string, hash uint64) (string, error) {
+	for band := 0; band < simhashBands; band++ {
+		members, err := redis.Strings(conn.Do("SMEMBERS", c.simhashBandKey(hash, band)))
+		if err != nil {
+			return "", err
+		}
+		for _, m := range members {
+			other, err := strconv.ParseUint(m, 16, 64)
+			if err != nil || bits.OnesCount64(other^hash) > c.NearDuplicateBits {
+				continue
+			}
+			original, err := redis.String(conn.Do("HGET", c.KeySimhashes, m))
+			if err == nil && original != url {
+				return original, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// indexSimhash adds writes indexing the page by its simhash to the batch,
+// the first page with each simhash standing for the rest
+func (c *Crawler) indexSimhash(b *batch, url string, hash uint64) {
+	b.add("HSETNX", c.KeySimhashes, formatSimhash(hash), url)
+	for band := 0; band < simhashBands; band++ {
+		b.add("SADD", c.simhashBandKey(hash, band), formatSimhash(hash))
+	}
+}
+
+// checkNearDuplicate marks the page as a near-duplicate of one already
+// crawled, not following its links, or otherwise indexes it for the pages
+// to come
+func (c *Crawler) checkNearDuplicate(w *worker, b *batch, url string, page *scrapeResult) {
+	original, err := c.nearDuplicate(w.conn, url, page.simhash)
+	if err != nil {
+		w.logger.Error("failed to look up near-duplicate pages", "url", url, "err", err)
+		return
+	}
+	if original == "" {
+		c.indexSimhash(b, url, page.simhash)
+		return
+	}
+
+	w.logger.Debug("not following links of near-duplicate page", "url", url, "original", original)
+	page.nearDuplicateOf = original
+	page.hrefs, page.anchors, page.frames = nil, nil, nil
+}
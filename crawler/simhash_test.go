@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// boilerplate is a page's worth of text, in which word i is varied
+func boilerplate(i int, variant string) string {
+	words := []string{}
+	for n := 0; n < 1000; n++ {
+		words = append(words, fmt.Sprintf("word%d", n*7919%1009))
+	}
+	words[i] = variant
+	return strings.Join(words, " ")
+}
+
+func TestSimhash(t *testing.T) {
+	hash := func(text string) uint64 {
+		s := simhasher{}
+		s.write([]byte(text))
+		return s.sum()
+	}
+
+	base := hash(boilerplate(150, "alpha"))
+	if near := hash(boilerplate(150, "beta")); bits.OnesCount64(base^near) > 3 {
+		t.Errorf("texts a word apart differ by %d bits, want 3 or fewer", bits.OnesCount64(base^near))
+	}
+	if far := hash("an entirely different page, about something else altogether"); bits.OnesCount64(base^far) <= 3 {
+		t.Errorf("different texts differ by %d bits, want more than 3", bits.OnesCount64(base^far))
+	}
+	if hash("  ") != 0 || hash("two words") == 0 {
+		t.Errorf("simhash of no words must be 0, and of a few words not")
+	}
+}
+
+func TestSkipNearDuplicates(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<p>Home</p><a href="/a">a</a><a href="/b">b</a>`))
+		case "/a", "/b":
+			fmt.Fprintf(w, `<script>var page = %q</script><p>%s</p><a href="%s/more">more</a>`,
+				r.URL.Path, boilerplate(10, r.URL.Path), r.URL.Path)
+		default:
+			w.Write([]byte(`<p>More</p>`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.SkipNearDuplicates = true
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	a, _, _ := c.LookupPage(site.URL + "/a")
+	b, _, _ := c.LookupPage(site.URL + "/b")
+	if a.Simhash == "" || b.Simhash == "" {
+		t.Fatalf("simhashes %q and %q, want both recorded", a.Simhash, b.Simhash)
+	}
+	original, duplicate := a, b
+	if a.NearDuplicateOf != "" {
+		original, duplicate = b, a
+	}
+	if original.NearDuplicateOf != "" || duplicate.NearDuplicateOf != original.URL {
+		t.Errorf("near-duplicates of %q and %q, want one of the other", a.NearDuplicateOf, b.NearDuplicateOf)
+	}
+
+	if _, found, _ := c.LookupPage(original.URL + "/more"); !found {
+		t.Errorf("%s/more not crawled, want the original's links followed", original.URL)
+	}
+	if _, found, _ := c.LookupPage(duplicate.URL + "/more"); found {
+		t.Errorf("%s/more crawled, want the near-duplicate's links not followed", duplicate.URL)
+	}
+}
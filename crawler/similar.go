@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+
+	// decoders for the formats perceptual hashing understands
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ImageHash is a 64-bit perceptual hash (dHash) of an image, visually similar
+// images have hashes that differ in only a few bits
+func ImageHash(r io.Reader) (uint64, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return 0, err
+	}
+	return imageHash(img)
+}
+
+func imageHash(img image.Image) (uint64, error) {
+	// shrink to a 9x8 grayscale thumbnail, then compare each pixel with its
+	// right neighbour
+	var gray [8][9]float64
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 0, fmt.Errorf("empty image")
+	}
+
+	for y := 0; y < 8; y++ {
+		y0, y1 := bounds.Min.Y+y*h/8, bounds.Min.Y+(y+1)*h/8
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < 9; x++ {
+			x0, x1 := bounds.Min.X+x*w/9, bounds.Min.X+(x+1)*w/9
+			if x1 == x0 {
+				x1++
+			}
+			gray[y][x] = averageLuma(img, x0, y0, x1, y1)
+		}
+	}
+
+	hash := uint64(0)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// averageLuma is the mean luminance over a rectangle of the image, sampling
+// at most 8x8 pixels so huge images stay cheap
+func averageLuma(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := (x1-x0)/8 + 1
+	stepY := (y1-y0)/8 + 1
+
+	sum, n := 0.0, 0
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+// SimilarImage is a crawled image whose perceptual hash is near another's
+type SimilarImage struct {
+	URL      string
+	Distance int // differing hash bits, 0 is a near-exact match
+}
+
+// FindSimilar returns every indexed image within maxDistance bits of hash,
+// closest first. Only images indexed with HashImages are searched.
+func (c *Crawler) FindSimilar(hash uint64, maxDistance int) ([]SimilarImage, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	matches := []SimilarImage{}
+	cursor := "0"
+	for {
+		pairs, next, err := scanPage(conn, "HSCAN", c.KeyImageHashes, cursor, DefaultScanCount)
+		if err != nil {
+			return nil, err
+		}
+		cursor = next
+
+		for i := 0; i+1 < len(pairs); i += 2 {
+			h, err := strconv.ParseUint(pairs[i+1], 16, 64)
+			if err != nil {
+				continue // claimed but not (or not successfully) hashed
+			}
+			if d := bits.OnesCount64(h ^ hash); d <= maxDistance {
+				matches = append(matches, SimilarImage{URL: pairs[i], Distance: d})
+			}
+		}
+
+		if cursor == "0" {
+			break
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].URL < matches[j].URL
+	})
+	return matches, nil
+}
+
+// hashImages adds the perceptual hashes of any images not yet indexed to the
+// batch, each image is claimed first so that only one worker fetches it. An
+// image that fails to hash is released again, for a later page to retry.
+func (c *Crawler) hashImages(conn redis.Conn, b *batch, imgSrcs []string, logger *slog.Logger) {
+	for _, src := range imgSrcs {
+		claimed, err := redis.Int(conn.Do("HSETNX", c.KeyImageHashes, src, ""))
+		if err != nil {
+			logger.Error("failed to claim image for hashing", "url", src, "err", err)
+			return
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		hash, size, err := c.fetchImageHash(src)
+		if err != nil {
+			logger.Warn("failed to hash image", "url", src, "err", err)
+			if _, err := conn.Do("HDEL", c.KeyImageHashes, src); err != nil {
+				logger.Warn("failed to release image for hashing", "url", src, "err", err)
+			}
+			continue
+		}
+		b.add("HSET", c.KeyImageHashes, src, strconv.FormatUint(hash, 16))
+		c.recordImageSize(b, src, size)
+	}
+}
+
+// fetchImageHash fetches an image and hashes it, along with its size,
+// dimensions and content type
+func (c *Crawler) fetchImageHash(url string) (uint64, imageSize, error) {
+	resp, err := c.client().Get(url)
+	if err != nil {
+		return 0, imageSize{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, imageSize{}, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	counter := &countingReader{r: resp.Body}
+	img, _, err := image.Decode(counter)
+	if err != nil {
+		return 0, imageSize{}, err
+	}
+	hash, err := imageHash(img)
+	if err != nil {
+		return 0, imageSize{}, err
+	}
+
+	// decoding needn't read the whole image, the rest still counts
+	io.Copy(io.Discard, counter)
+	bounds := img.Bounds()
+	return hash, imageSize{ContentType: resp.Header.Get("content-type"), Bytes: counter.n, Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHashImagesRetriesFailures(t *testing.T) {
+	broken := atomic.Bool{}
+	broken.Store(true)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		png.Encode(w, image.NewGray(image.Rect(0, 0, 16, 16)))
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	src := site.URL + "/a.png"
+
+	b := batch{}
+	c.hashImages(conn, &b, []string{src}, c.Logger)
+	if mr.Exists(c.KeyImageHashes) {
+		t.Errorf("image failing to hash left claimed: %v", mr.HGet(c.KeyImageHashes, src))
+	}
+
+	// found again on a later page, it's hashed then
+	broken.Store(false)
+	b = batch{}
+	c.hashImages(conn, &b, []string{src}, c.Logger)
+	if err := b.exec(conn); err != nil {
+		t.Fatal(err)
+	}
+	if hash := mr.HGet(c.KeyImageHashes, src); hash == "" {
+		t.Error("image not hashed once it could be")
+	}
+}
@@ -0,0 +1,287 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// sinkBatchSize is how many records are handed to a sink at once
+const sinkBatchSize = 100
+
+// Sink receives every distinct image found during the crawl. Records that
+// fail to deliver are retried, so a sink may see a record again if it failed
+// part way through a batch or the crawler died before recording the
+// delivery; idempotent sinks should deduplicate on ImageRecord.ID.
+type Sink interface {
+	Deliver(ctx context.Context, records []ImageRecord) error
+}
+
+// PageSink receives the record of every page crawled, see Crawler.PageSinks.
+// As with Sink, records that fail to deliver are retried, so a sink may see
+// a page again.
+type PageSink interface {
+	DeliverPages(ctx context.Context, records []PageRecord) error
+}
+
+// sinkKeys are the Redis keys tracking delivery to a single sink
+type sinkKeys struct {
+	pending      string // hash of image URL to encoded record, awaiting delivery
+	delivered    string // set of image URLs delivered
+	lock         string // held by the one process delivering to the sink
+	pendingPages string // hash of page URL to encoded record, for page sinks
+	pagesLock    string
+}
+
+func (c *Crawler) sinkKeys(name string) sinkKeys {
+	prefix := c.KeySinks + ":" + name + ":"
+	return sinkKeys{
+		pending:      prefix + "pending",
+		delivered:    prefix + "delivered",
+		lock:         prefix + "lock",
+		pendingPages: prefix + "pendingPages",
+		pagesLock:    prefix + "pagesLock",
+	}
+}
+
+// queueForSinks adds a write to the batch queueing an encoded image record
+// for delivery to every sink, images already pending keep their original
+// record
+func (c *Crawler) queueForSinks(b *batch, imgURL string, record []byte) {
+	for name := range c.Sinks {
+		b.add("HSETNX", c.sinkKeys(name).pending, imgURL, record)
+	}
+}
+
+// queueForPageSinks adds a write to the batch queueing an encoded page
+// record for delivery to every page sink
+func (c *Crawler) queueForPageSinks(b *batch, pageURL string, record []byte) {
+	for name := range c.PageSinks {
+		b.add("HSET", c.sinkKeys(name).pendingPages, pageURL, record)
+	}
+}
+
+// runSinks delivers to every sink in the background until stop is closed,
+// then makes a final attempt to deliver everything still pending
+func (c *Crawler) runSinks(ctx context.Context, stop <-chan struct{}) <-chan struct{} {
+	done := make(chan struct{})
+	if len(c.Sinks) == 0 && len(c.PageSinks) == 0 {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-time.After(1 * time.Second):
+			case <-stop:
+				if err := c.Deliver(ctx); err != nil {
+					c.Logger.Error("undelivered images left pending", "err", err)
+				}
+				return
+			}
+
+			c.Deliver(ctx)
+		}
+	}()
+
+	return done
+}
+
+// Deliver sends every pending image to every sink, and page to every page
+// sink, returning the first delivery error. Records that fail stay pending
+// and are retried by the next call, including from a later run of the crawl.
+func (c *Crawler) Deliver(ctx context.Context) error {
+	var firstErr error
+	for name, sink := range c.Sinks {
+		if err := c.deliverTo(ctx, name, sink); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for name, sink := range c.PageSinks {
+		if err := c.deliverPagesTo(ctx, name, sink); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (c *Crawler) deliverTo(ctx context.Context, name string, sink Sink) error {
+	keys := c.sinkKeys(name)
+	logger := c.Logger.With("sink", name)
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	// only one process delivers to a sink at a time, so concurrent crawlers
+	// never race to deliver the same record
+	const lockTTL = 30 * time.Second
+	token := NewJobID()
+	locked, err := redis.String(conn.Do("SET", keys.lock, token, "NX", "PX", lockTTL.Milliseconds()))
+	if err == redis.ErrNil {
+		return nil // someone else is delivering
+	}
+	if err != nil || locked != "OK" {
+		return err
+	}
+	defer releaseLock(conn, keys.lock, token)
+
+	for ctx.Err() == nil {
+		records, err := c.pendingRecords(conn, keys)
+		if err != nil || len(records) == 0 {
+			return err
+		}
+
+		if err := sink.Deliver(ctx, records); err != nil {
+			logger.Warn("failed to deliver images", "count", len(records), "err", err)
+			c.reportError(name, err)
+			return err
+		}
+
+		// record the delivery atomically, so a record is never both
+		// delivered and pending
+		b := batch{{name: "MULTI"}}
+		for _, r := range records {
+			b.add("SADD", keys.delivered, r.URL)
+			b.add("HDEL", keys.pending, r.URL)
+		}
+		b.add("PEXPIRE", keys.lock, lockTTL.Milliseconds())
+		b.add("EXEC")
+		if err := b.exec(conn); err != nil {
+			return err
+		}
+
+		logger.Debug("delivered images", "count", len(records))
+	}
+
+	return ctx.Err()
+}
+
+func (c *Crawler) deliverPagesTo(ctx context.Context, name string, sink PageSink) error {
+	keys := c.sinkKeys(name)
+	logger := c.Logger.With("sink", name)
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	const lockTTL = 30 * time.Second
+	token := NewJobID()
+	locked, err := redis.String(conn.Do("SET", keys.pagesLock, token, "NX", "PX", lockTTL.Milliseconds()))
+	if err == redis.ErrNil {
+		return nil // someone else is delivering
+	}
+	if err != nil || locked != "OK" {
+		return err
+	}
+	defer releaseLock(conn, keys.pagesLock, token)
+
+	for ctx.Err() == nil {
+		pairs, _, err := scanPage(conn, "HSCAN", keys.pendingPages, "0", sinkBatchSize)
+		if err != nil || len(pairs) == 0 {
+			return err
+		}
+
+		records := []PageRecord{}
+		b := batch{{name: "MULTI"}}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			rec := PageRecord{}
+			if err := json.Unmarshal([]byte(pairs[i+1]), &rec); err == nil {
+				records = append(records, rec)
+			}
+			b.add("HDEL", keys.pendingPages, pairs[i])
+		}
+
+		if err := sink.DeliverPages(ctx, records); err != nil {
+			logger.Warn("failed to deliver pages", "count", len(records), "err", err)
+			c.reportError(name, err)
+			return err
+		}
+
+		b.add("PEXPIRE", keys.pagesLock, lockTTL.Milliseconds())
+		b.add("EXEC")
+		if err := b.exec(conn); err != nil {
+			return err
+		}
+
+		logger.Debug("delivered pages", "count", len(records))
+	}
+
+	return ctx.Err()
+}
+
+// pendingRecords returns the next batch of records awaiting delivery,
+// discarding any already delivered. It scans on past pages of the hash that
+// come back empty, as HSCAN's may, or hold only records delivered already,
+// returning none only once the whole hash has been scanned.
+func (c *Crawler) pendingRecords(conn redis.Conn, keys sinkKeys) ([]ImageRecord, error) {
+	cursor := "0"
+	for {
+		pairs, next, err := scanPage(conn, "HSCAN", keys.pending, cursor, sinkBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		records := []ImageRecord{}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			records = append(records, decodeImageRecord(pairs[i], []byte(pairs[i+1])))
+		}
+		fresh, err := c.undelivered(conn, keys, records)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fresh) > 0 || next == "0" {
+			return fresh, nil
+		}
+		cursor = next
+	}
+}
+
+// undelivered drops the records delivered already from the pending ones
+func (c *Crawler) undelivered(conn redis.Conn, keys sinkKeys, records []ImageRecord) ([]ImageRecord, error) {
+	if len(records) == 0 {
+		return records, nil
+	}
+
+	for _, r := range records {
+		conn.Send("SISMEMBER", keys.delivered, r.URL)
+	}
+	delivered, err := redis.Ints(conn.Do(""))
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := records[:0]
+	for i, r := range records {
+		if delivered[i] == 1 {
+			conn.Send("HDEL", keys.pending, r.URL)
+			continue
+		}
+		fresh = append(fresh, r)
+	}
+	if len(fresh) < len(records) {
+		if _, err := conn.Do(""); err != nil {
+			return nil, err
+		}
+	}
+
+	return fresh, nil
+}
+
+// releaseScript deletes a lock only if it still holds the given token
+var releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// releaseLock releases a lock we hold, leaving it alone if it expired and
+// was taken by someone else
+func releaseLock(conn redis.Conn, key string, token string) {
+	releaseScript.Do(conn, key, token)
+}
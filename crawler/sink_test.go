@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// pageSink records the pages delivered, failing until fail is cleared
+type pageSink struct {
+	mu    sync.Mutex
+	fail  bool
+	pages map[string]PageRecord
+}
+
+func (s *pageSink) DeliverPages(ctx context.Context, records []PageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	for _, r := range records {
+		s.pages[r.URL] = r
+	}
+	return nil
+}
+
+func TestPageSinks(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			for i := range 3 {
+				fmt.Fprintf(w, `<a href="/%d">page</a>`, i)
+			}
+		}
+		fmt.Fprintf(w, `<img src="%s.png">`, r.URL.Path)
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	sink := &pageSink{fail: true, pages: map[string]PageRecord{}}
+	c.PageSinks = map[string]PageSink{"pages": sink}
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	// undelivered pages stay pending for the next attempt
+	pending := c.sinkKeys("pages").pendingPages
+	if n, _ := mr.HKeys(pending); len(n) != 4 {
+		t.Fatalf("%d pages pending, want 4", len(n))
+	}
+
+	sink.fail = false
+	if err := c.Deliver(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.pages) != 4 {
+		t.Errorf("delivered %d pages, want 4", len(sink.pages))
+	}
+	if rec := sink.pages[site.URL+"/"]; len(rec.Links) != 3 || len(rec.Images) != 1 {
+		t.Errorf("seed page delivered as %+v, want its 3 links and image", rec)
+	}
+	if mr.Exists(pending) {
+		t.Error("delivered pages left pending")
+	}
+}
+
+// pagingConn pages HSCAN replies COUNT fields at a time, as Redis may and
+// miniredis doesn't, the first page empty as Redis's can be
+type pagingConn struct {
+	redis.Conn
+}
+
+func (c pagingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "HSCAN" {
+		return c.Conn.Do(cmd, args...)
+	}
+	fields, err := redis.Strings(c.Conn.Do("HKEYS", args[0]))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(fields)
+	cursor, _ := strconv.Atoi(args[1].(string))
+	count := args[3].(int)
+
+	page := []interface{}{}
+	next := 0
+	if cursor > 0 {
+		end := min(cursor-1+count, len(fields))
+		for _, f := range fields[cursor-1 : end] {
+			value, _ := c.Conn.Do("HGET", args[0], f)
+			page = append(page, []byte(f), value)
+		}
+		if end < len(fields) {
+			next = end + 1
+		}
+	} else if len(fields) > 0 {
+		next = 1
+	}
+	return []interface{}{[]byte(strconv.Itoa(next)), page}, nil
+}
+
+func TestPendingRecordsScansPastDelivered(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	keys := c.sinkKeys("images")
+	for i := range 3 * sinkBatchSize {
+		url := fmt.Sprintf("https://example.com/%03d.png", i)
+		mr.HSet(keys.pending, url, "{}")
+		// all but the last few delivered already, but left pending
+		if i < 3*sinkBatchSize-5 {
+			mr.SAdd(keys.delivered, url)
+		}
+	}
+
+	conn := pagingConn{c.RedisPool.Get()}
+	defer conn.Close()
+	records, err := c.pendingRecords(conn, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Errorf("%d records pending, want the 5 past the pages delivered already", len(records))
+	}
+}
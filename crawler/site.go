@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Preset configures a Crawler for a common kind of crawl, presets can be
+// combined, later ones overriding earlier
+type Preset func(*Crawler)
+
+// Presets are the built-in presets by name, as accepted by crawlsvc -preset
+var Presets = map[string]Preset{
+	"polite":   PresetPolite,
+	"dedupe":   PresetDedupe,
+	"metadata": PresetMetadata,
+}
+
+// PresetPolite keeps the load on each site light, one request at a time per
+// host, across every process, backing off longer between retries
+func PresetPolite(c *Crawler) {
+	c.MaxHostConnections = 1
+	c.RetryBackoff = 5 * time.Second
+}
+
+// PresetDedupe avoids crawling the same page twice under different URLs,
+// dropping tracking parameters and keeping to canonical URLs
+func PresetDedupe(c *Crawler) {
+	c.QueryRules = append(c.QueryRules, DefaultQueryRules...)
+	c.DedupeCanonical = true
+}
+
+// PresetMetadata records the size, format and dimensions of every image,
+// fetching just the start of each
+func PresetMetadata(c *Crawler) {
+	c.ProbeImages = true
+}
+
+// ParsePresets looks up a comma-separated list of preset names
+func ParsePresets(names string) ([]Preset, error) {
+	presets := []Preset{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := Presets[name]
+		if !ok {
+			known := []string{}
+			for k := range Presets {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown preset %q, want one of %s", name, strings.Join(known, ", "))
+		}
+		presets = append(presets, p)
+	}
+	return presets, nil
+}
+
+// SiteOptions configure CrawlSite, the zero value crawls with 4 workers
+// using Redis at localhost:6379
+type SiteOptions struct {
+	// RedisAddr is the Redis to crawl through, localhost:6379 by default
+	RedisAddr string
+	// Workers is how many pages are crawled at once, 4 by default
+	Workers int
+	// MaxPages, if set, stops the crawl after this many pages
+	MaxPages int
+	// MaxDuration, if set, stops the crawl after it has run this long
+	MaxDuration time.Duration
+	// Presets are applied in order, before Configure
+	Presets []Preset
+	// Configure, if set, is called with the Crawler before it starts, for
+	// any settings the other options don't cover
+	Configure func(*Crawler)
+	// Keep leaves the crawl in Redis once done, as one of ListJobs, rather
+	// than deleting it
+	Keep bool
+}
+
+// CrawlSite crawls a site from the seed URL and returns every image found,
+// each with all the pages it was found on, taking care of the Redis pool,
+// job and result collection:
+//
+//	images, err := crawler.CrawlSite(ctx, "https://example.com", crawler.SiteOptions{})
+//	if err != nil { ... }
+//	for _, img := range images {
+//		fmt.Println(img.URL, img.PageURL)
+//	}
+//
+// The crawl runs in a job of its own, deleted afterwards unless Keep is set.
+// If ctx is cancelled the images found so far are returned along with
+// ctx's error.
+func CrawlSite(ctx context.Context, seed string, opts SiteOptions) ([]ImageRecord, error) {
+	if opts.RedisAddr == "" {
+		opts.RedisAddr = "localhost:6379"
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+
+	pool := NewPool("tcp", opts.RedisAddr)
+	defer pool.Close()
+
+	// Seed doesn't report errors, so check Redis is there first
+	conn := pool.Get()
+	_, err := conn.Do("PING")
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to redis at %s: %w", opts.RedisAddr, err)
+	}
+
+	c := NewJob(pool, NewJobID())
+	c.MaxPages = opts.MaxPages
+	c.MaxDuration = opts.MaxDuration
+	for _, p := range opts.Presets {
+		p(c)
+	}
+	if opts.Configure != nil {
+		opts.Configure(c)
+	}
+
+	if !opts.Keep {
+		defer func() {
+			if err := DeleteJob(pool, c.JobID); err != nil {
+				c.Logger.Warn("failed to delete crawl job", "job", c.JobID, "err", err)
+			}
+		}()
+	}
+
+	c.Seed(seed)
+	c.RunNContext(ctx, opts.Workers)
+
+	images := []ImageRecord{}
+	it := c.RecordIterator()
+	for it.Next() {
+		images = append(images, it.Record())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return images, ctx.Err()
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCrawlSite(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/logo.png"><a href="/about?utm_source=home">about</a>`))
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<img src="/logo.png"><img src="/team.jpg">`))
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	mr := miniredis.RunT(t)
+	images, err := CrawlSite(t.Context(), site.URL+"/", SiteOptions{
+		RedisAddr: mr.Addr(),
+		Presets:   []Preset{PresetDedupe},
+		Configure: func(c *Crawler) {
+			c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string][]string{}
+	for _, img := range images {
+		slices.Sort(img.Pages)
+		found[img.URL] = img.Pages
+	}
+	want := map[string][]string{
+		site.URL + "/logo.png": {site.URL + "/", site.URL + "/about"},
+		site.URL + "/team.jpg": {site.URL + "/about"},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("found %v, want %v", found, want)
+	}
+	for url, pages := range want {
+		if !slices.Equal(found[url], pages) {
+			t.Errorf("%s found on %v, want %v", url, found[url], pages)
+		}
+	}
+
+	// the job is cleaned up afterwards
+	if keys := mr.Keys(); len(keys) > 1 || (len(keys) == 1 && keys[0] != KeyJobs) {
+		t.Errorf("keys left behind: %v", keys)
+	}
+}
+
+func TestParsePresets(t *testing.T) {
+	presets, err := ParsePresets("polite, metadata")
+	if err != nil || len(presets) != 2 {
+		t.Fatalf("ParsePresets = %d presets, %v", len(presets), err)
+	}
+	if _, err := ParsePresets("polite,fast"); err == nil {
+		t.Error("ParsePresets accepted an unknown preset")
+	}
+}
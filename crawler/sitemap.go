@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxSitemapBytes is the most of a sitemap read, compressed or not, the
+// sitemaps protocol's 50MB limit, so a gzip bomb can't exhaust memory
+const maxSitemapBytes = 50 << 20
+
+// sitemapDoc covers both <urlset> sitemaps and <sitemapindex> index files,
+// only one of the two lists will be populated for any given document
+type sitemapDoc struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// SeedFromSitemap downloads a sitemap.xml (or sitemap index) and adds every
+// <loc> it lists to the crawl queue. Gzipped sitemaps are supported.
+func (c *Crawler) SeedFromSitemap(url string) error {
+	locs, err := c.fetchSitemap(url, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	if len(locs) == 0 {
+		return nil
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	if err := c.register(conn); err != nil {
+		return err
+	}
+
+	entries := make([]Entry, 0, len(locs))
+	for _, loc := range locs {
+		entries = append(entries, Entry{URL: loc, Parent: url, Priority: c.priority(loc, 0, nil)})
+	}
+
+	return c.push(conn, entries...)
+}
+
+func (c *Crawler) fetchSitemap(url string, seen map[string]bool) ([]string, error) {
+	// guard against index files that reference each other
+	if seen[url] {
+		return []string{}, nil
+	}
+	seen[url] = true
+
+	resp, err := c.client().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching sitemap %s: %s", url, resp.Status)
+	}
+
+	body, err := maybeGunzip(io.LimitReader(resp.Body, maxSitemapBytes))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing sitemap %s: %v", url, err)
+	}
+
+	// one cut short by the limit fails to parse
+	doc := sitemapDoc{}
+	if err := xml.NewDecoder(io.LimitReader(body, maxSitemapBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing sitemap %s: %v", url, err)
+	}
+
+	// plain sitemap, resolve and sanitize the listed pages
+	locs := []string{}
+	for _, u := range doc.URLs {
+		locs = append(locs, strings.TrimSpace(u.Loc))
+	}
+	locs = resolveURLs(url, locs)
+
+	// sitemap index, recurse into each child sitemap
+	for _, s := range doc.Sitemaps {
+		children := resolveURLs(url, []string{strings.TrimSpace(s.Loc)})
+		if len(children) == 0 {
+			continue
+		}
+
+		childLocs, err := c.fetchSitemap(children[0], seen)
+		if err != nil {
+			c.Logger.Warn("failed to fetch child sitemap", "url", children[0], "err", err)
+			continue
+		}
+		locs = append(locs, childLocs...)
+	}
+
+	return locs, nil
+}
+
+// maybeGunzip sniffs the gzip magic number rather than trusting the URL or
+// headers, since .xml.gz sitemaps are served with all manner of content-types
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+
+	return gzip.NewReader(br)
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// gzipped compresses s
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	b := bytes.Buffer{}
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(s))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestSeedFromSitemap(t *testing.T) {
+	var site *httptest.Server
+	site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap_index.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>/pages.xml</loc></sitemap>
+	<sitemap><loc>
+		` + site.URL + `/posts.xml.gz
+	</loc></sitemap>
+	<sitemap><loc>/sitemap_index.xml</loc></sitemap>
+	<sitemap><loc>/missing.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/pages.xml":
+			// locs padded with whitespace, as generators often do
+			w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>
+		` + site.URL + `/about
+	</loc></url>
+	<url><loc>/contact</loc></url>
+</urlset>`))
+		case "/posts.xml.gz":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(gzipped(t, `<urlset><url><loc>`+site.URL+`/posts/1</loc></url></urlset>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	if err := c.SeedFromSitemap(site.URL + "/sitemap_index.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	queued, _ := mr.ZMembers(c.KeyCrawlQ)
+	slices.Sort(queued)
+	want := []string{site.URL + "/about", site.URL + "/contact", site.URL + "/posts/1"}
+	if !slices.Equal(queued, want) {
+		t.Errorf("queued %v, want %v", queued, want)
+	}
+}
+
+func TestSitemapSizeLimit(t *testing.T) {
+	// a gzip bomb, tiny compressed but past the limit once decompressed
+	bomb := gzipped(t, "<urlset>"+strings.Repeat(" ", maxSitemapBytes+1)+"</urlset>")
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bomb)
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	if err := c.SeedFromSitemap(site.URL + "/sitemap.xml.gz"); err == nil {
+		t.Error("sitemap past the size limit read, want an error")
+	}
+	if mr.Exists(c.KeyCrawlQ) {
+		t.Error("sitemap past the size limit seeded the crawl")
+	}
+}
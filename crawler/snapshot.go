@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// snapshot is the metadata archived alongside each raw page body
+type snapshot struct {
+	URL       string      `json:"url"`
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	FetchedAt time.Time   `json:"fetchedAt"`
+	Body      string      `json:"body"` // gzipped body file, relative to the snapshot dir
+}
+
+// saveSnapshot archives a page as a gzipped body plus a JSON sidecar, one
+// pair of files per page so concurrent workers never share a file
+func (c *Crawler) saveSnapshot(url string, resp *http.Response, body []byte) error {
+	if err := os.MkdirAll(c.SnapshotDir, 0755); err != nil {
+		return err
+	}
+
+	name := urlHash(url)
+	meta := snapshot{
+		URL:       url,
+		Status:    resp.StatusCode,
+		Header:    resp.Header,
+		FetchedAt: time.Now().UTC(),
+		Body:      name + ".html.gz",
+	}
+
+	f, err := os.Create(filepath.Join(c.SnapshotDir, meta.Body))
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write(body)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// the sidecar is written last so replay never sees a missing body
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.SnapshotDir, name+".json"), data, 0644)
+}
+
+// Replay runs every page snapshot in dir back through the extraction pipeline,
+// recording the results as if the pages had just been crawled. Links are not
+// followed, so nothing is re-fetched.
+func (c *Crawler) Replay(dir string) error {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	metas, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	logger := c.Logger.With("replay", dir)
+
+	for _, metaPath := range metas {
+		data, err := os.ReadFile(metaPath)
+		if err != nil {
+			return err
+		}
+
+		meta := snapshot{}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			logger.Warn("skipping unreadable snapshot", "file", metaPath, "err", err)
+			continue
+		}
+
+		if _, err := c.markVisited(conn, meta.URL); err != nil {
+			return err
+		}
+
+		if err := c.replaySnapshot(conn, dir, meta, logger); err != nil {
+			logger.Warn("failed to replay snapshot", "url", meta.URL, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Crawler) replaySnapshot(conn redis.Conn, dir string, meta snapshot, logger *slog.Logger) error {
+	f, err := os.Open(filepath.Join(dir, meta.Body))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	body, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("replaying", "url", meta.URL)
+
+	page := c.extract(meta.URL, meta.Header, body, logger)
+	page.status = meta.Status
+	page.fetchedAt = meta.FetchedAt
+
+	b := batch{}
+	if !c.runPageHook(meta.URL, page) {
+		c.recordPageMeta(&b, Entry{URL: meta.URL}, page, true)
+		return b.exec(conn)
+	}
+
+	c.recordPageMeta(&b, Entry{URL: meta.URL}, page, false)
+	c.recordPage(context.Background(), conn, &b, meta.URL, page, logger)
+	return b.exec(conn)
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><a href="/next">next</a>`))
+		case "/next":
+			w.Header().Set("X-Robots-Tag", "noindex")
+			w.Write([]byte(`<img src="/hidden.png">`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	dir := t.TempDir()
+	c, _ := newTestCrawler(t)
+	c.SnapshotDir = dir
+	c.Seed(site.URL)
+	c.Run()
+	site.Close()
+
+	sidecars, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(sidecars) != 2 {
+		t.Fatalf("archived %v, want a snapshot of each page", sidecars)
+	}
+	// snapshots that can't be replayed are skipped
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644)
+	os.WriteFile(filepath.Join(dir, "missing.json"), []byte(`{"url": "https://example.com/", "body": "missing.html.gz"}`), 0644)
+
+	// replayed into a crawl of its own, with the site gone
+	replayed, _ := newTestCrawler(t)
+	if err := replayed.Replay(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := replayed.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Images != 1 || status.Queued != 0 {
+		t.Errorf("status = %+v, want the indexable image found and no links queued", status)
+	}
+	rec, found, _ := replayed.LookupPage(site.URL + "/")
+	if !found || rec.Status != http.StatusOK || !slices.Equal(rec.Images, []string{site.URL + "/a.png"}) {
+		t.Errorf("page record = %+v, found %v, want the snapshot's", rec, found)
+	}
+	if rec, _, _ := replayed.LookupPage(site.URL + "/next"); len(rec.Images) != 0 {
+		t.Errorf("noindex page's images = %v, want its header obeyed on replay", rec.Images)
+	}
+}
@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is how much of a body is sniffed, as much as
+// http.DetectContentType considers
+const sniffLen = 512
+
+// sniffableTypes are the content types servers send when they don't know
+// better, which are sniffed rather than trusted with SniffContentType
+var sniffableTypes = []string{"application/octet-stream", "binary/octet-stream", "text/plain", "application/unknown"}
+
+// sniffable reports whether the content type is missing, malformed or one
+// of the sniffableTypes
+func (c *Crawler) sniffable(contentType string) bool {
+	if !c.SniffContentType {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	for _, t := range sniffableTypes {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffedBody is a response body partly read to sniff its content type,
+// reading on from the start
+type sniffedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// sniffContentType detects the media type of the response's body from its
+// first bytes, which are kept to be read again
+func sniffContentType(resp *http.Response) string {
+	br := bufio.NewReaderSize(resp.Body, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	resp.Body = sniffedBody{Reader: br, Closer: resp.Body}
+
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	return mediaType
+}
@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestSniffContentType(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			// sent without a Content-Type, which net/http would otherwise add
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte(`<!DOCTYPE html><html><img src="/a.jpg"><a href="/plain">plain</a><a href="/data.bin">data</a></html>`))
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`<html><body><img src="/b.jpg"></body></html>`))
+		case "/data.bin":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("\x00\x01 <img src=\"/c.jpg\">"))
+		}
+	}))
+	defer site.Close()
+
+	c, mr := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	images, _ := mr.Members(c.KeyImageSrcs)
+	if want := []string{site.URL + "/a.jpg", site.URL + "/b.jpg"}; !slices.Equal(images, want) {
+		t.Errorf("images = %v, want %v", images, want)
+	}
+
+	c, mr = newTestCrawler(t)
+	c.SniffContentType = false
+	c.Seed(site.URL + "/")
+	c.Run()
+	if images, _ := mr.Members(c.KeyImageSrcs); len(images) != 0 {
+		t.Errorf("images = %v without sniffing, want none", images)
+	}
+}
@@ -0,0 +1,327 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	// sprite decoders
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	neturl "net/url"
+)
+
+// Sprite is an image referenced by CSS background rules at several offsets
+type Sprite struct {
+	PageURL  string
+	ImageURL string      // absolute URL, or the data URI itself for inline sprites
+	Image    image.Image // the decoded sprite sheet, nil if it couldn't be decoded
+	Rules    []SpriteRule
+}
+
+// SpriteRule is a single CSS rule that displays part of a sprite
+type SpriteRule struct {
+	Selector string // empty for style="" attributes
+	Position string // the declared background-position, verbatim
+	X, Y     int    // the position in pixels, where given in px
+	Width    int    // the declared width in pixels, 0 if undeclared
+	Height   int    // the declared height in pixels, 0 if undeclared
+}
+
+// maxSpriteBytes and maxSpritePixels bound the sprite sheets decoded, so
+// that a huge or hostile image can't stall or exhaust a worker
+const (
+	maxSpriteBytes  = 16 << 20
+	maxSpritePixels = 8192 * 8192
+)
+
+var (
+	cssURLPattern    = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+	cssLengthPattern = regexp.MustCompile(`^-?\d+(\.\d+)?(px|%|em|rem)?$`)
+)
+
+// detectSprites finds background images used as sprites in the page's inline
+// CSS and hands each to the OnSprite hook
+func (c *Crawler) detectSprites(ctx context.Context, pageURL string, doc *document, logger *slog.Logger) {
+	rules := []cssRule{}
+	for _, block := range doc.styles {
+		rules = append(rules, parseCSSRules(block)...)
+	}
+	for _, attr := range doc.inlineStyles {
+		rules = append(rules, cssRule{declarations: parseCSSDeclarations(attr)})
+	}
+
+	// group rules by the image they position
+	byImage := map[string][]SpriteRule{}
+	order := []string{}
+	for _, r := range rules {
+		imgURL, position := backgroundOf(r.declarations)
+		if imgURL == "" || position == "" {
+			continue
+		}
+
+		if _, ok := byImage[imgURL]; !ok {
+			order = append(order, imgURL)
+		}
+		byImage[imgURL] = append(byImage[imgURL], newSpriteRule(r, position))
+	}
+
+	for _, imgURL := range order {
+		spriteRules := byImage[imgURL]
+
+		// an image shown at a single offset is just a background, unless it
+		// was inlined, in which case there's nowhere else to find it
+		isDataURI := strings.HasPrefix(imgURL, "data:")
+		if !isDataURI && !hasDistinctPositions(spriteRules) {
+			continue
+		}
+
+		sprite := Sprite{
+			PageURL:  pageURL,
+			ImageURL: imgURL,
+			Rules:    spriteRules,
+		}
+
+		if !isDataURI {
+			resolved := resolveURLs(doc.baseURL(pageURL), []string{imgURL})
+			if len(resolved) == 0 {
+				continue
+			}
+			sprite.ImageURL = resolved[0]
+		}
+
+		img, err := c.loadSpriteImage(ctx, sprite.ImageURL)
+		if err != nil {
+			logger.Warn("failed to decode sprite", "url", sprite.ImageURL, "page", pageURL, "err", err)
+		}
+		sprite.Image = img
+
+		c.OnSprite(sprite)
+	}
+}
+
+func newSpriteRule(r cssRule, position string) SpriteRule {
+	rule := SpriteRule{
+		Selector: r.selector,
+		Position: position,
+		Width:    pixels(r.declarations["width"]),
+		Height:   pixels(r.declarations["height"]),
+	}
+
+	coords := strings.Fields(position)
+	if len(coords) > 0 {
+		rule.X = pixels(coords[0])
+	}
+	if len(coords) > 1 {
+		rule.Y = pixels(coords[1])
+	}
+
+	return rule
+}
+
+func hasDistinctPositions(rules []SpriteRule) bool {
+	for _, r := range rules[1:] {
+		if r.Position != rules[0].Position {
+			return true
+		}
+	}
+	return false
+}
+
+// backgroundOf extracts the background image and position from a rule's
+// declarations, looking through the background shorthand if necessary
+func backgroundOf(decls map[string]string) (imgURL string, position string) {
+	for _, prop := range []string{"background-image", "background"} {
+		if m := cssURLPattern.FindStringSubmatch(decls[prop]); m != nil {
+			imgURL = m[1]
+			break
+		}
+	}
+
+	position = strings.TrimSpace(decls["background-position"])
+	if position == "" {
+		// pick the position out of the shorthand, discarding the url() etc
+		shorthand := cssURLPattern.ReplaceAllString(decls["background"], "")
+		coords := []string{}
+		for _, tok := range strings.Fields(shorthand) {
+			if cssLengthPattern.MatchString(tok) {
+				coords = append(coords, tok)
+			}
+		}
+		position = strings.Join(coords, " ")
+	}
+
+	return imgURL, position
+}
+
+func pixels(length string) int {
+	length = strings.TrimSpace(length)
+	if length != "0" && !strings.HasSuffix(length, "px") {
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSuffix(length, "px"), 64)
+	if err != nil {
+		return 0
+	}
+	return int(f)
+}
+
+// loadSpriteImage fetches and decodes a sprite sheet, or decodes an inline
+// one, refusing sheets of more than maxSpriteBytes or maxSpritePixels
+func (c *Crawler) loadSpriteImage(ctx context.Context, url string) (image.Image, error) {
+	var data []byte
+	if strings.HasPrefix(url, "data:") {
+		var err error
+		if data, err = decodeDataURI(url); err != nil {
+			return nil, err
+		}
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, maxSpriteBytes+1)); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) > maxSpriteBytes {
+		return nil, fmt.Errorf("sprite is larger than the %d bytes allowed", maxSpriteBytes)
+	}
+
+	// check its dimensions before decoding it whole
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxSpritePixels {
+		return nil, fmt.Errorf("sprite of %dx%d is larger than the %d pixels allowed", cfg.Width, cfg.Height, maxSpritePixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// decodeDataURI returns the payload of a data: URI
+func decodeDataURI(uri string) ([]byte, error) {
+	comma := strings.Index(uri, ",")
+	if !strings.HasPrefix(uri, "data:") || comma < 0 {
+		return nil, fmt.Errorf("malformed data URI")
+	}
+
+	meta, payload := uri[len("data:"):comma], uri[comma+1:]
+	if strings.HasSuffix(meta, ";base64") {
+		// tolerate whitespace that crept in via CSS line wrapping
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+	}
+
+	unescaped, err := neturl.PathUnescape(payload)
+	return []byte(unescaped), err
+}
+
+// cssRule is a selector and its declarations, without any cascade semantics
+type cssRule struct {
+	selector     string
+	declarations map[string]string
+}
+
+// parseCSSRules is a forgiving CSS rule parser that's just good enough to pick
+// background declarations out of <style> blocks, at-rules are flattened away
+func parseCSSRules(css string) []cssRule {
+	css = stripCSSComments(css)
+	rules := []cssRule{}
+
+	for _, chunk := range splitOutsideParens(css, '}') {
+		open := strings.LastIndex(chunk, "{")
+		if open < 0 {
+			continue
+		}
+
+		selector := chunk[:open]
+		if i := strings.LastIndex(selector, "{"); i >= 0 {
+			selector = selector[i+1:]
+		}
+
+		rules = append(rules, cssRule{
+			selector:     strings.TrimSpace(selector),
+			declarations: parseCSSDeclarations(chunk[open+1:]),
+		})
+	}
+
+	return rules
+}
+
+func parseCSSDeclarations(decls string) map[string]string {
+	parsed := map[string]string{}
+	for _, decl := range splitOutsideParens(decls, ';') {
+		kv := strings.SplitN(decl, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(kv[1]), "!important"))
+		parsed[strings.ToLower(strings.TrimSpace(kv[0]))] = value
+	}
+	return parsed
+}
+
+func stripCSSComments(css string) string {
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			return css
+		}
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return css[:start]
+		}
+		css = css[:start] + css[start+2+end+2:]
+	}
+}
+
+// splitOutsideParens splits on sep, except inside parentheses or quotes so
+// that data URIs such as url(data:image/png;base64,...) survive intact
+func splitOutsideParens(s string, sep byte) []string {
+	parts := []string{}
+	depth := 0
+	quote := byte(0)
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')' && depth > 0:
+			depth--
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -0,0 +1,114 @@
+package crawler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseCSSRules(t *testing.T) {
+	css := `
+/* a comment { with braces } */
+.a { background: url("data:image/png;base64,AAAA") 0 0; width: 16px }
+@media (min-width: 600px) {
+	.b, .c { BACKGROUND-POSITION: -16px 0 !important; }
+}
+.empty {}`
+
+	rules := parseCSSRules(css)
+	want := []cssRule{
+		{".a", map[string]string{"background": `url("data:image/png;base64,AAAA") 0 0`, "width": "16px"}},
+		{".b, .c", map[string]string{"background-position": "-16px 0"}},
+		{".empty", map[string]string{}},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("parsed %d rules %v, want %d", len(rules), rules, len(want))
+	}
+	for i, r := range rules {
+		if r.selector != want[i].selector || fmt.Sprint(r.declarations) != fmt.Sprint(want[i].declarations) {
+			t.Errorf("rule %d = %q %v, want %q %v", i, r.selector, r.declarations, want[i].selector, want[i].declarations)
+		}
+	}
+}
+
+func TestBackgroundOf(t *testing.T) {
+	tests := []struct {
+		decls    map[string]string
+		img, pos string
+	}{
+		{map[string]string{"background-image": "url(/s.png)", "background-position": "-10px -20px"}, "/s.png", "-10px -20px"},
+		{map[string]string{"background": "url('/s.png') no-repeat -10px 0"}, "/s.png", "-10px 0"},
+		{map[string]string{"background": `url("/s.png") 50% 0 / 20px`, "background-position": " 0 -4px "}, "/s.png", "0 -4px"},
+		{map[string]string{"background": "red"}, "", ""},
+		{map[string]string{"background-image": "url(/s.png)"}, "/s.png", ""},
+	}
+	for _, tt := range tests {
+		img, pos := backgroundOf(tt.decls)
+		if img != tt.img || pos != tt.pos {
+			t.Errorf("backgroundOf(%v) = %q, %q, want %q, %q", tt.decls, img, pos, tt.img, tt.pos)
+		}
+	}
+}
+
+func TestOnSprite(t *testing.T) {
+	sheet := strings.Builder{}
+	png.Encode(&sheet, image.NewGray(image.Rect(0, 0, 32, 16)))
+	inline := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte(sheet.String()))
+
+	// a GIF claiming to be 65535x65535, which isn't decoded
+	huge := "GIF89a\xff\xff\xff\xff\x00\x00\x00"
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, `<style>
+				.home { background: url(/sprite.png) 0 0; width: 16px; height: 16px }
+				.search { background: url(/sprite.png) -16px 0; width: 16px; height: 16px }
+				.hero { background: url(/hero.jpg) 0 0 }
+				.huge1 { background: url(/huge.gif) 0 0 }
+				.huge2 { background: url(/huge.gif) -1px 0 }
+			</style>
+			<i style="background: url(%s) 0 0"></i>`, inline)
+		case "/sprite.png":
+			w.Write([]byte(sheet.String()))
+		case "/huge.gif":
+			w.Write([]byte(huge))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	mu := sync.Mutex{}
+	sprites := map[string]Sprite{}
+	c.OnSprite = func(s Sprite) {
+		mu.Lock()
+		defer mu.Unlock()
+		sprites[s.ImageURL] = s
+	}
+	c.Seed(site.URL)
+	c.Run()
+
+	if len(sprites) != 3 {
+		t.Fatalf("found sprites %v, want the sheet, the huge one and the inline one", sprites)
+	}
+	s := sprites[site.URL+"/sprite.png"]
+	if s.PageURL != site.URL || s.Image == nil || s.Image.Bounds().Dx() != 32 {
+		t.Errorf("sprite = %+v, want the decoded 32x16 sheet", s)
+	}
+	if len(s.Rules) != 2 || s.Rules[1].Selector != ".search" || s.Rules[1].X != -16 || s.Rules[1].Width != 16 {
+		t.Errorf("sprite rules = %+v, want .home and .search at -16px", s.Rules)
+	}
+	if s := sprites[site.URL+"/huge.gif"]; s.Image != nil {
+		t.Error("sprite past the pixel limit decoded")
+	}
+	if s := sprites[inline]; s.Image == nil || s.Rules[0].Selector != "" {
+		t.Errorf("inline sprite = %+v, want it decoded, from a style attribute", s)
+	}
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">A</a><a href="/b">B</a>`)
+		}
+		// every page shares the logo
+		fmt.Fprintf(w, `<img src="/logo.png"><img src="%s.png">`, r.URL.Path)
+	}))
+	defer site.Close()
+
+	path := filepath.Join(t.TempDir(), "crawl.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Sinks = map[string]Sink{"db": store}
+	c.PageSinks = map[string]PageSink{"db": store}
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	counts := map[string]int{}
+	for _, table := range []string{"pages", "links", "page_images", "images"} {
+		n := 0
+		if err := store.DB().QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		counts[table] = n
+	}
+	want := map[string]int{"pages": 3, "links": 2, "page_images": 6, "images": 4}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("row counts = %v, want %v", counts, want)
+	}
+
+	var pages int
+	var text string
+	err = store.DB().QueryRow(`SELECT COUNT(DISTINCT pi.page_url), MAX(l.text) FROM images i
+		JOIN page_images pi ON pi.image_url = i.url
+		JOIN links l ON l.link_url = pi.page_url
+		WHERE i.url = ?`, site.URL+"/logo.png").Scan(&pages, &text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 2 || text != "B" {
+		t.Errorf("logo on %d linked pages, anchor %q, want 2 and B", pages, text)
+	}
+
+	// redelivering records stores nothing twice
+	rec, _, _ := c.LookupPage(site.URL + "/")
+	if err := store.DeliverPages(t.Context(), []PageRecord{rec}); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	store.DB().QueryRow("SELECT COUNT(*) FROM links").Scan(&n)
+	if n != 2 {
+		t.Errorf("%d links after redelivery, want 2", n)
+	}
+}
+
+func TestNumberPlaceholders(t *testing.T) {
+	got := numberPlaceholders("INSERT INTO links (page_url, link_url) VALUES (?, ?)")
+	if want := "INSERT INTO links (page_url, link_url) VALUES ($1, $2)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
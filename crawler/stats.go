@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"encoding/json"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+
+	neturl "net/url"
+)
+
+// SizeBuckets are the upper bounds, in bytes, of the image size buckets
+// reported by Stats, the last bucket being everything larger
+var SizeBuckets = []int64{10 << 10, 100 << 10, 1 << 20}
+
+// Stats summarizes the images found by a crawl
+type Stats struct {
+	Pages  int
+	Images int
+	// Formats counts the images by format, e.g. "jpeg" or "svg", as served
+	// for images that were fetched and guessed from the URL otherwise.
+	// Images whose format couldn't be guessed are counted as "unknown".
+	Formats map[string]int
+
+	// Sized is how many images were fetched, by -downloadSigned,
+	// -hashImages or -probeImages, and so have a known size
+	Sized int
+	// MedianBytes is the median size of the fetched images
+	MedianBytes int64
+	// SizeCounts counts the fetched images in each of the SizeBuckets, with
+	// one extra count for those larger than the last bucket
+	SizeCounts []int
+}
+
+// imageSize records an image fetched during the crawl, its dimensions only
+// known if probed
+type imageSize struct {
+	ContentType string `json:"contentType"`
+	Bytes       int64  `json:"bytes"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+// recordImageSize adds the writes recording a fetched image's size and type
+// to the batch
+func (c *Crawler) recordImageSize(b *batch, url string, size imageSize) {
+	data, err := json.Marshal(size)
+	if err != nil {
+		return
+	}
+	b.add("HSET", c.KeyImageSizes, url, data)
+}
+
+// Stats breaks down the images found by format and size
+func (c *Crawler) Stats() (Stats, error) {
+	stats := Stats{Formats: map[string]int{}, SizeCounts: make([]int, len(SizeBuckets)+1)}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	var err error
+	if stats.Pages, err = c.visitedCount(conn); err != nil {
+		return stats, err
+	}
+
+	sizes := []int64{}
+	cursor := "0"
+	for {
+		var urls []string
+		urls, cursor, err = scanPage(conn, "SSCAN", c.KeyImageSrcs, cursor, DefaultScanCount)
+		if err != nil {
+			return stats, err
+		}
+
+		fetched := [][]byte{}
+		if len(urls) > 0 {
+			fetched, err = redis.ByteSlices(conn.Do("HMGET", redis.Args{c.KeyImageSizes}.AddFlat(urls)...))
+			if err != nil {
+				return stats, err
+			}
+		}
+
+		for i, url := range urls {
+			stats.Images++
+
+			size := imageSize{}
+			if fetched[i] == nil || json.Unmarshal(fetched[i], &size) != nil {
+				stats.Formats[imageFormat(guessContentType(url))]++
+				continue
+			}
+
+			stats.Formats[imageFormat(size.ContentType)]++
+			// probed from servers that didn't say how large the image is
+			if size.Bytes == 0 {
+				continue
+			}
+			sizes = append(sizes, size.Bytes)
+			bucket := sort.Search(len(SizeBuckets), func(b int) bool { return size.Bytes <= SizeBuckets[b] })
+			stats.SizeCounts[bucket]++
+		}
+
+		if cursor == "0" {
+			break
+		}
+	}
+
+	stats.Sized = len(sizes)
+	if len(sizes) > 0 {
+		sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+		stats.MedianBytes = sizes[len(sizes)/2]
+	}
+	return stats, nil
+}
+
+// guessContentType guesses an image's content type from its URL's extension
+func guessContentType(url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	return mime.TypeByExtension(path.Ext(u.Path))
+}
+
+// imageFormat names the format of a content type, e.g. "jpeg" for
+// image/jpeg and "svg" for image/svg+xml
+func imageFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "unknown"
+	}
+	_, format, _ := strings.Cut(mediaType, "/")
+	format, _, _ = strings.Cut(format, "+")
+	if format == "" {
+		return "unknown"
+	}
+	return format
+}
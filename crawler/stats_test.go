@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	mr.SAdd(c.KeyVisitedHREFs, "https://example.com/")
+	mr.SAdd(c.KeyImageSrcs, "https://example.com/a.jpg", "https://example.com/b.JPG", "https://example.com/c", "https://example.com/d.png")
+
+	// d.png turned out to be a webp once fetched
+	b := batch{}
+	c.recordImageSize(&b, "https://example.com/a.jpg", imageSize{ContentType: "image/jpeg", Bytes: 50 << 10})
+	c.recordImageSize(&b, "https://example.com/d.png", imageSize{ContentType: "image/webp", Bytes: 2 << 20})
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	if err := b.exec(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Pages != 1 || stats.Images != 4 {
+		t.Errorf("stats = %d pages, %d images, want 1 and 4", stats.Pages, stats.Images)
+	}
+	if stats.Formats["jpeg"] != 2 || stats.Formats["webp"] != 1 || stats.Formats["unknown"] != 1 {
+		t.Errorf("formats = %v, want 2 jpeg, 1 webp and 1 unknown", stats.Formats)
+	}
+	if stats.Sized != 2 || stats.MedianBytes != 2<<20 {
+		t.Errorf("sized = %d, median %d, want 2 with median 2MB", stats.Sized, stats.MedianBytes)
+	}
+	if want := []int{0, 1, 0, 1}; !slices.Equal(stats.SizeCounts, want) {
+		t.Errorf("size counts = %v, want %v", stats.SizeCounts, want)
+	}
+}
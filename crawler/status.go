@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// throughputWindow is how far back Status averages the pages crawled over
+const throughputWindow = 5 * time.Minute
+
+// throughputTTL is how long each minute's count is kept
+const throughputTTL = time.Hour
+
+// Status is a snapshot of a crawl's progress, across every process
+type Status struct {
+	JobInfo
+	// PagesPerMinute is how many pages were crawled a minute, on average
+	// over the last five minutes
+	PagesPerMinute float64
+	// ErrorRate is the fraction of the pages crawled over the last five
+	// minutes that failed, or that the server answered with an error
+	ErrorRate float64
+	// Failed counts the pages that failed by class, e.g. FailedClientError,
+	// see FailedPages
+	Failed map[string]int
+	// Workers are the heartbeats of the workers in every process, including
+	// those that stopped without clearing theirs, marked as stale
+	Workers []WorkerHeartbeat
+}
+
+// throughputKey is the key counting the pages crawled in the minute of t,
+// or with failed, those that failed
+func (c *Crawler) throughputKey(t time.Time, failed bool) string {
+	key := c.KeyThroughput + ":" + strconv.FormatInt(t.Unix()/60, 10)
+	if failed {
+		key += ":failed"
+	}
+	return key
+}
+
+// countPage adds the writes counting a page crawled to the batch
+func (c *Crawler) countPage(b *batch, failed bool) {
+	now := time.Now()
+	keys := []string{c.throughputKey(now, false)}
+	if failed {
+		keys = append(keys, c.throughputKey(now, true))
+	}
+	for _, key := range keys {
+		b.add("INCR", key)
+		b.add("EXPIRE", key, int(throughputTTL.Seconds()))
+	}
+}
+
+// Status reports the crawl's progress: its queue length, pages visited,
+// images found, active workers and their heartbeats, throughput, error rate
+// and failed pages
+func (c *Crawler) Status() (Status, error) {
+	info, err := c.Info()
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{JobInfo: info}
+	if status.Workers, err = c.Workers(); err != nil {
+		return status, err
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	if status.Failed, err = c.failedCounts(conn); err != nil {
+		return status, err
+	}
+
+	// the minutes of the window, the last of them still under way
+	now := time.Now()
+	keys := redis.Args{}
+	for t := now.Add(-throughputWindow + time.Minute); !t.After(now); t = t.Add(time.Minute) {
+		keys = keys.Add(c.throughputKey(t, false), c.throughputKey(t, true))
+	}
+	counts, err := redis.Ints(conn.Do("MGET", keys...))
+	if err != nil {
+		return status, err
+	}
+
+	pages, failed := 0, 0
+	for i := 0; i+1 < len(counts); i += 2 {
+		pages += counts[i]
+		failed += counts[i+1]
+	}
+	elapsed := throughputWindow - time.Minute + now.Sub(now.Truncate(time.Minute))
+	status.PagesPerMinute = float64(pages) / elapsed.Minutes()
+	if pages > 0 {
+		status.ErrorRate = float64(failed) / float64(pages)
+	}
+	return status, nil
+}
@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/b" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">A</a><a href="/b">B</a>`)
+		}
+		fmt.Fprintf(w, `<img src="%s.png">`, r.URL.Path)
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Queued != 0 || status.Visited != 3 || status.Images != 2 || status.ActiveWorkers != 0 {
+		t.Errorf("status = %+v, want 3 pages visited, 2 images and nothing left", status)
+	}
+	if status.ErrorRate != 1.0/3 {
+		t.Errorf("error rate = %v, want the 404 of 3 pages", status.ErrorRate)
+	}
+	// the 3 pages were crawled within the last minute of the window
+	if status.PagesPerMinute < 3.0/5 || status.PagesPerMinute > 3.0/4 {
+		t.Errorf("pages/min = %v, want 3 pages over the last four to five minutes", status.PagesPerMinute)
+	}
+}
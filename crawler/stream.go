@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+)
+
+// streamBuffer is how many results a stream holds before workers wait for
+// the consumer to catch up
+const streamBuffer = 100
+
+// streams are the live result channels of a single run
+type streams struct {
+	images chan ImageRecord
+	pages  chan PageRecord
+}
+
+// Images streams each image as it is recorded, for as long as the next run
+// of the crawl lasts, and is closed when the run ends. It must be called
+// before the run starts and then drained, as a full channel holds up the
+// workers. An image found on several pages is sent for each page.
+func (c *Crawler) Images() <-chan ImageRecord {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	if c.streams.images == nil {
+		c.streams.images = make(chan ImageRecord, streamBuffer)
+	}
+	return c.streams.images
+}
+
+// Pages streams the record of each page as it is visited, including pages
+// skipped by OnPageCrawled, see Images
+func (c *Crawler) Pages() <-chan PageRecord {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	if c.streams.pages == nil {
+		c.streams.pages = make(chan PageRecord, streamBuffer)
+	}
+	return c.streams.pages
+}
+
+// takeStreams hands the requested streams to a run, later calls to Images
+// and Pages get new streams for the next run
+func (c *Crawler) takeStreams() *streams {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	s := c.streams
+	c.streams = streams{}
+	return &s
+}
+
+// emit sends a page's results to whichever streams were requested, giving up
+// if ctx is cancelled while waiting on the consumer
+func (s *streams) emit(ctx context.Context, page PageRecord, images []ImageRecord) {
+	if s.pages != nil {
+		select {
+		case s.pages <- page:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if s.images != nil {
+		for _, img := range images {
+			select {
+			case s.images <- img:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (s *streams) close() {
+	if s.pages != nil {
+		close(s.pages)
+	}
+	if s.images != nil {
+		close(s.images)
+	}
+}
@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreams(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<img src="/a.png"><img src="/b.png"><a href="/next">next</a>`))
+		case "/next":
+			w.Write([]byte(`<img src="/b.png">`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL)
+	images, pages := c.Images(), c.Pages()
+	if c.Images() != images || c.Pages() != pages {
+		t.Error("asking again before the run gave a different stream")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.Run()
+		close(done)
+	}()
+
+	found, visited := []string{}, []string{}
+	for images != nil || pages != nil {
+		select {
+		case img, ok := <-images:
+			if !ok {
+				images = nil
+				continue
+			}
+			found = append(found, strings.TrimPrefix(img.URL, site.URL)+" "+strings.TrimPrefix(img.PageURL, site.URL))
+		case page, ok := <-pages:
+			if !ok {
+				pages = nil
+				continue
+			}
+			visited = append(visited, strings.TrimPrefix(page.URL, site.URL))
+		}
+	}
+	<-done
+
+	slices.Sort(found)
+	if want := []string{"/a.png ", "/b.png ", "/b.png /next"}; !slices.Equal(found, want) {
+		t.Errorf("images streamed = %q, want each on every page found %q", found, want)
+	}
+	slices.Sort(visited)
+	if want := []string{"", "/next"}; !slices.Equal(visited, want) {
+		t.Errorf("pages streamed = %q, want %q", visited, want)
+	}
+
+	// the next run gets streams of its own, closed when it ends
+	next := c.Images()
+	select {
+	case _, ok := <-next:
+		t.Errorf("stream for the next run received before it started, open %v", ok)
+	default:
+	}
+	c.Run()
+	if _, ok := <-next; ok {
+		t.Error("stream for the next run wasn't closed when it ended")
+	}
+}
+
+func TestStreamNotDrained(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		for i := range streamBuffer + 10 {
+			fmt.Fprintf(w, `<img src="/%d.png">`, i)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.DrainTimeout = 10 * time.Millisecond
+	c.Seed(site.URL)
+	images := c.Images()
+
+	// the consumer falling behind holds up the run until it's cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.RunContext(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("run ended with the stream full")
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run didn't end once cancelled")
+	}
+
+	n := 0
+	for range images {
+		n++
+	}
+	if n != streamBuffer {
+		t.Errorf("streamed %d images, want the %d buffered", n, streamBuffer)
+	}
+}
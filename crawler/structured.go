@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// structuredImageProps are the schema.org properties naming an image
+var structuredImageProps = []string{"image", "thumbnailUrl", "logo"}
+
+// isStructuredImageProp reports whether the property, or any of a
+// space-separated list of them, names an image
+func isStructuredImageProp(prop string) bool {
+	for _, p := range strings.Fields(prop) {
+		for _, want := range structuredImageProps {
+			if p == want || strings.HasSuffix(p, "/"+want) || strings.HasSuffix(p, ":"+want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isJSONLD reports whether the tag is a <script type="application/ld+json">
+func isJSONLD(tok *html.Token) bool {
+	isScript, typ := matchTag(tok, "script", "type")
+	return isScript && strings.EqualFold(strings.TrimSpace(typ), "application/ld+json")
+}
+
+// microdataImage returns the image URL a microdata item property holds,
+// if it's one of the image properties
+func microdataImage(tok *html.Token) (string, bool) {
+	if !isStructuredImageProp(getAttr(tok, "itemprop")) {
+		return "", false
+	}
+
+	// the attribute holding an element's value, per the microdata spec,
+	// properties held as text aren't URLs
+	attr := ""
+	switch tok.Data {
+	case "meta":
+		attr = "content"
+	case "img", "audio", "video", "source", "embed", "iframe", "track":
+		attr = "src"
+	case "a", "area", "link":
+		attr = "href"
+	case "object":
+		attr = "data"
+	}
+	if attr == "" {
+		return "", false
+	}
+
+	url := strings.TrimSpace(getAttr(tok, attr))
+	return url, url != ""
+}
+
+// jsonLDImages returns the image URLs a JSON-LD block declares, at any
+// depth, whether as URLs, ImageObjects or lists of either, properties in
+// name order. Malformed blocks yield none.
+func jsonLDImages(block string) []string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(block), &v); err != nil {
+		return nil
+	}
+
+	urls := []string{}
+	var walk func(v interface{}, isImage bool)
+	walk = func(v interface{}, isImage bool) {
+		switch v := v.(type) {
+		case string:
+			if isImage {
+				urls = append(urls, strings.TrimSpace(v))
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item, isImage)
+			}
+		case map[string]interface{}:
+			if isImage {
+				// an ImageObject, its URL is the image
+				for _, key := range []string{"url", "contentUrl"} {
+					if url, ok := v[key].(string); ok {
+						urls = append(urls, strings.TrimSpace(url))
+						break
+					}
+				}
+			}
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				if isImage && (key == "url" || key == "contentUrl") {
+					continue
+				}
+				walk(v[key], isStructuredImageProp(key))
+			}
+		}
+	}
+	walk(v, false)
+	return urls
+}
+
+// structuredImages are the images the document declares in JSON-LD and
+// microdata, less those it also has as <img>s
+func (d *document) structuredImages() []string {
+	declared := slices.Clone(d.microdata)
+	for _, block := range d.jsonLD {
+		declared = append(declared, jsonLDImages(block)...)
+	}
+
+	images := []string{}
+	for _, src := range declared {
+		if src != "" && !slices.Contains(d.imgSrcs, src) && !slices.Contains(images, src) {
+			images = append(images, src)
+		}
+	}
+	return images
+}
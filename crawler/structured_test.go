@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJSONLDImages(t *testing.T) {
+	tests := []struct {
+		block string
+		want  []string
+	}{
+		{`{"@type":"Product","url":"/product","image":"/a.jpg"}`, []string{"/a.jpg"}},
+		{`{"@type":"Product","image":["/a.jpg","/b.jpg"]}`, []string{"/a.jpg", "/b.jpg"}},
+		{`{"@type":"Article","image":{"@type":"ImageObject","url":"/a.jpg","width":800},"thumbnailUrl":"/t.jpg"}`, []string{"/a.jpg", "/t.jpg"}},
+		{`{"@graph":[{"@type":"Organization","logo":{"@type":"ImageObject","contentUrl":"/logo.png"}},{"@type":"WebPage","url":"/"}]}`, []string{"/logo.png"}},
+		{`[{"schema:image":"/a.jpg"}]`, []string{"/a.jpg"}},
+		{`{"image":`, nil},
+	}
+	for _, tt := range tests {
+		if got := jsonLDImages(tt.block); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: images = %v, want %v", tt.block, got, tt.want)
+		}
+	}
+}
+
+func TestExtractStructuredData(t *testing.T) {
+	body := `<script type="application/ld+json">{"@type":"Product","image":["/product.jpg","/shown.jpg"]}</script>
+	<div itemscope itemtype="https://schema.org/Organization">
+		<link itemprop="logo" href="/logo.png">
+		<meta itemprop="thumbnailUrl" content="/thumb.jpg">
+		<span itemprop="image">not a url</span>
+	</div>
+	<img src="/shown.jpg">`
+
+	c, _ := newTestCrawler(t)
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+	if want := []string{"https://example.com/shown.jpg"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("without StructuredData images = %v, want %v", page.imgSrcs, want)
+	}
+
+	c.StructuredData = true
+	page = c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+	want := []string{
+		"https://example.com/shown.jpg",
+		"https://example.com/logo.png",
+		"https://example.com/thumb.jpg",
+		"https://example.com/product.jpg",
+	}
+	if !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("images = %v, want %v", page.imgSrcs, want)
+	}
+}
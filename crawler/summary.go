@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	neturl "net/url"
+)
+
+// Summary is an overview of a crawl, built from the records of its pages
+// and the images found
+type Summary struct {
+	Pages int
+	// Failures counts the pages that failed by status code, e.g. "404", or
+	// "error" for those that failed to fetch at all
+	Failures map[string]int
+	Hosts    int // distinct hosts crawled
+	Images   int
+	// Extensions counts the images by their URL's file extension, e.g.
+	// "jpg", or "none" for those without one
+	Extensions map[string]int
+
+	// Started and Finished are when the first page was fetched and the last
+	// finished, Duration the time between them
+	Started  time.Time
+	Finished time.Time
+	Duration time.Duration
+	// AvgLatency is how long pages took to start arriving, on average
+	AvgLatency time.Duration
+}
+
+// Summary summarizes the crawl so far: the pages crawled and the failures
+// among them, the hosts crawled, the images found by extension, and how long
+// it took. Pages visited before their records were kept aren't counted.
+func (c *Crawler) Summary() (Summary, error) {
+	sum := Summary{Failures: map[string]int{}, Extensions: map[string]int{}}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	hosts := map[string]bool{}
+	var latency time.Duration
+	timed := 0
+	cursor := "0"
+	for {
+		pairs, next, err := scanPage(conn, "HSCAN", c.KeyPages, cursor, DefaultScanCount)
+		if err != nil {
+			return sum, err
+		}
+		cursor = next
+
+		for i := 0; i+1 < len(pairs); i += 2 {
+			rec := PageRecord{}
+			if json.Unmarshal([]byte(pairs[i+1]), &rec) != nil {
+				continue
+			}
+			sum.Pages++
+
+			switch {
+			case rec.Status >= 400:
+				sum.Failures[strconv.Itoa(rec.Status)]++
+			case rec.Error != "":
+				sum.Failures["error"]++
+			}
+
+			if u, err := neturl.Parse(rec.URL); err == nil && u.Hostname() != "" {
+				hosts[u.Hostname()] = true
+			}
+
+			if rec.FetchedAt.IsZero() {
+				continue
+			}
+			if sum.Started.IsZero() || rec.FetchedAt.Before(sum.Started) {
+				sum.Started = rec.FetchedAt
+			}
+			if end := rec.FetchedAt.Add(rec.Latency); end.After(sum.Finished) {
+				sum.Finished = end
+			}
+			if rec.Latency > 0 {
+				latency += rec.Latency
+				timed++
+			}
+		}
+
+		if cursor == "0" {
+			break
+		}
+	}
+
+	sum.Hosts = len(hosts)
+	sum.Duration = sum.Finished.Sub(sum.Started)
+	if timed > 0 {
+		sum.AvgLatency = latency / time.Duration(timed)
+	}
+
+	it := c.ImageIterator()
+	for it.Next() {
+		sum.Images++
+		sum.Extensions[imageExtension(it.Member())]++
+	}
+	return sum, it.Err()
+}
+
+// imageExtension is the lowercased file extension of an image URL, without
+// the dot, or "none"
+func imageExtension(url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil || u.Scheme == "data" {
+		return "none"
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+	if ext == "" {
+		return "none"
+	}
+	return ext
+}
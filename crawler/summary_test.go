@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">A</a><a href="/b">B</a><img src="/logo.PNG"><img src="/pixel">`)
+		case "/a":
+			fmt.Fprint(w, `<img src="/photo.jpg?w=100">`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	sum, err := c.Summary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Pages != 3 || sum.Hosts != 1 || sum.Images != 3 {
+		t.Errorf("summary = %+v, want 3 pages of 1 host and 3 images", sum)
+	}
+	if want := map[string]int{"404": 1}; !maps.Equal(sum.Failures, want) {
+		t.Errorf("failures = %v, want %v", sum.Failures, want)
+	}
+	if want := map[string]int{"png": 1, "jpg": 1, "none": 1}; !maps.Equal(sum.Extensions, want) {
+		t.Errorf("extensions = %v, want %v", sum.Extensions, want)
+	}
+	if sum.AvgLatency <= 0 || sum.Duration < sum.AvgLatency {
+		t.Errorf("duration %v, average latency %v, want both measured", sum.Duration, sum.AvgLatency)
+	}
+}
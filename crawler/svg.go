@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// svgType is the media type of SVG documents, which are parsed for the
+// images they embed along with the HTMLTypes
+const svgType = "image/svg+xml"
+
+// isSVG reports whether the content type is SVG
+func isSVG(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.EqualFold(mediaType, svgType)
+}
+
+// svgImage returns the URL an SVG <image> embeds, by its href or, from
+// SVG 1.1, xlink:href
+func svgImage(tok *html.Token) (string, bool) {
+	if tok.Data != "image" {
+		return "", false
+	}
+	href := getAttr(tok, "href")
+	if href == "" {
+		href = getAttr(tok, "xlink:href")
+	}
+	href = strings.TrimSpace(href)
+	return href, href != ""
+}
+
+// inlineSVGRefs are the synthetic references recorded for a page's inline
+// <svg> graphics, which have no URL of their own, e.g.
+// https://example.com/#svg-1
+func inlineSVGRefs(pageURL string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	refs := make([]string, n)
+	for i := range refs {
+		refs[i] = fmt.Sprintf("%s#svg-%d", pageURL, i+1)
+	}
+	return refs
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractSVG(t *testing.T) {
+	body := `<svg viewBox="0 0 10 10"><image href="/a.png"/><svg><image xlink:href="b.jpg"></image></svg></svg>
+	<p>between</p>
+	<svg><circle r="5"/></svg>
+	<image href="/outside.png">`
+
+	c, _ := newTestCrawler(t)
+	page := c.extract("https://example.com/", http.Header{}, strings.NewReader(body), c.Logger)
+	if want := []string{"https://example.com/a.png", "https://example.com/b.jpg"}; !slices.Equal(page.imgSrcs, want) {
+		t.Errorf("images = %v, want %v", page.imgSrcs, want)
+	}
+	if page.inlineSVGs != 2 {
+		t.Errorf("inline SVGs = %d, want 2", page.inlineSVGs)
+	}
+}
+
+func TestCrawlSVGDocument(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<svg><path d="M0 0"/></svg><a href="/diagram.svg">diagram</a>`))
+		case "/diagram.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image xlink:href="photo.jpg"/></svg>`))
+		}
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	rec, _, _ := c.LookupPage(site.URL + "/diagram.svg")
+	if want := []string{site.URL + "/photo.jpg"}; !slices.Equal(rec.Images, want) || len(rec.InlineSVGs) != 0 {
+		t.Errorf("SVG document images = %v inline = %v, want %v and none inline", rec.Images, rec.InlineSVGs, want)
+	}
+	rec, _, _ = c.LookupPage(site.URL + "/")
+	if want := []string{site.URL + "/#svg-1"}; !slices.Equal(rec.InlineSVGs, want) {
+		t.Errorf("inline SVGs = %v, want %v", rec.InlineSVGs, want)
+	}
+}
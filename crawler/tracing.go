@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the crawler's tracer, as its instrumentation scope
+const tracerName = "github.com/daveagill/go-imgcrawler/crawler"
+
+// traceContext carries spans' contexts in queue entries, as W3C
+// traceparent headers
+var traceContext = propagation.TraceContext{}
+
+// startSpan starts a span of a stage of crawling a page, a child of the
+// page's span in ctx
+func (c *Crawler) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	c.tracerOnce.Do(func() {
+		provider := c.TracerProvider
+		if provider == nil {
+			provider = otel.GetTracerProvider()
+		}
+		c.tracer = provider.Tracer(tracerName)
+	})
+	return c.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// startPageSpan starts the span of crawling the entry's page. Each page is
+// the root of a trace of its own, linked to the span of the page that
+// queued it, whichever worker crawled that, as a whole crawl is far too
+// large to be one trace.
+func (c *Crawler) startPageSpan(ctx context.Context, entry Entry) (context.Context, trace.Span) {
+	ctx, span := c.startSpan(ctx, "crawl page",
+		attribute.String("url.full", entry.URL),
+		attribute.Int("crawler.depth", entry.Depth),
+	)
+	if entry.Trace != "" {
+		parent := traceContext.Extract(context.Background(), propagation.MapCarrier{"traceparent": entry.Trace})
+		if sc := trace.SpanContextFromContext(parent); sc.IsValid() {
+			span.AddLink(trace.Link{SpanContext: sc})
+		}
+	}
+	return ctx, span
+}
+
+// endPageSpan ends the span of crawling a page, with how it went
+func endPageSpan(span trace.Span, page *scrapeResult) {
+	if page != nil {
+		if page.status != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", page.status))
+		}
+		if page.err != nil {
+			span.SetStatus(codes.Error, page.err.Error())
+		}
+	}
+	span.End()
+}
+
+// traceParent is the traceparent of the span in ctx, for the entries it
+// queues, or "" when not tracing
+func traceParent(ctx context.Context) string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	return carrier["traceparent"]
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<a href="/about">About</a><img src="/a.jpg">`))
+		}
+	}))
+	defer site.Close()
+
+	for _, concurrency := range []int{1, 4} {
+		recorder := tracetest.NewSpanRecorder()
+		c, _ := newTestCrawler(t)
+		c.FetchConcurrency = concurrency
+		c.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		c.Seed(site.URL + "/")
+		c.Run()
+
+		pages := map[string]sdktrace.ReadOnlySpan{}
+		stages := map[string][]string{}
+		spans := recorder.Ended()
+		for _, s := range spans {
+			if s.Name() == "crawl page" {
+				for _, attr := range s.Attributes() {
+					if attr.Key == "url.full" {
+						pages[attr.Value.AsString()] = s
+					}
+				}
+			}
+		}
+		for _, s := range spans {
+			for url, page := range pages {
+				if s.Parent().SpanID() == page.SpanContext().SpanID() {
+					stages[url] = append(stages[url], s.Name())
+				}
+			}
+		}
+
+		home, about := pages[site.URL+"/"], pages[site.URL+"/about"]
+		if home == nil || about == nil {
+			t.Fatalf("concurrency %d: page spans = %v, want / and /about", concurrency, pages)
+		}
+		for url, want := range map[string][]string{
+			site.URL + "/":      {"fetch", "parse", "resolve", "store"},
+			site.URL + "/about": {"fetch", "parse", "resolve", "store"},
+		} {
+			got := stages[url]
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("concurrency %d: %s stages = %v, want %v", concurrency, url, got, want)
+			}
+		}
+
+		// the linked page is traced apart, but linked to the page it was
+		// found on
+		if about.SpanContext().TraceID() == home.SpanContext().TraceID() {
+			t.Errorf("concurrency %d: /about traced in the same trace as /", concurrency)
+		}
+		if links := about.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != home.SpanContext().SpanID() {
+			t.Errorf("concurrency %d: /about links = %v, want the span of /", concurrency, links)
+		}
+	}
+}
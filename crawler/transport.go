@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ConnectionOptions tune how connections are reused across the crawl's
+// requests, so a large crawl keeps connections to each host open rather
+// than handshaking for every request
+type ConnectionOptions struct {
+	// MaxIdleConns caps the idle connections kept open across every host,
+	// and MaxIdleConnsPerHost to each host, 0 for no limit and net/http's
+	// default of 2 respectively
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open, 0 for
+	// ever
+	IdleConnTimeout time.Duration
+	// DisableHTTP2 keeps to HTTP/1.1, which is otherwise negotiated up to
+	// HTTP/2 with hosts supporting it, multiplexing requests over one
+	// connection
+	DisableHTTP2 bool
+	// TLSSessionCacheSize is how many TLS sessions are cached to resume,
+	// sparing new connections to a host a full handshake, 0 for none
+	TLSSessionCacheSize int
+}
+
+// DefaultConnectionOptions suit crawls of many pages per host
+var DefaultConnectionOptions = ConnectionOptions{
+	MaxIdleConns:        512,
+	MaxIdleConnsPerHost: 16,
+	IdleConnTimeout:     90 * time.Second,
+	TLSSessionCacheSize: 1024,
+}
+
+// newTransport is the transport the crawl's requests are made over, tuned
+// by the Connections options, and resolving hosts through the DNSCache if
+// it's set
+func (c *Crawler) newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	opts := c.Connections
+	t.MaxIdleConns = opts.MaxIdleConns
+	t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	t.IdleConnTimeout = opts.IdleConnTimeout
+	if c.DNSCache != nil {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		t.DialContext = newDNSCache(*c.DNSCache, c.metrics).dialContext(dialer)
+	}
+
+	if opts.TLSSessionCacheSize > 0 {
+		t.TLSClientConfig = &tls.Config{ClientSessionCache: tls.NewLRUClientSessionCache(opts.TLSSessionCacheSize)}
+	}
+
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(!opts.DisableHTTP2)
+	t.Protocols = protocols
+	t.ForceAttemptHTTP2 = !opts.DisableHTTP2
+	return t
+}
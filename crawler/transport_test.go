@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransportReusesConnections(t *testing.T) {
+	conns := atomic.Int32{}
+	site := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	site.EnableHTTP2 = true
+	site.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	site.StartTLS()
+	defer site.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(site.Certificate())
+
+	for _, disable := range []bool{false, true} {
+		conns.Store(0)
+		c, _ := newTestCrawler(t)
+		c.Connections.DisableHTTP2 = disable
+		transport := c.newTransport()
+		transport.TLSClientConfig.RootCAs = roots
+		client := &http.Client{Transport: transport}
+
+		want := "HTTP/2.0"
+		if disable {
+			want = "HTTP/1.1"
+		}
+		for i := 0; i < 5; i++ {
+			resp, err := client.Get(site.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			proto, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(proto) != want {
+				t.Errorf("DisableHTTP2 %v: fetched over %s, want %s", disable, proto, want)
+			}
+		}
+		if n := conns.Load(); n != 1 {
+			t.Errorf("DisableHTTP2 %v: opened %d connections for 5 sequential requests, want 1", disable, n)
+		}
+		transport.CloseIdleConnections()
+	}
+}
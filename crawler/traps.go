@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	neturl "net/url"
+)
+
+// TrapOptions guard against crawler traps, infinite spaces of URLs such as
+// calendars linking to the next month without end, or relative links
+// resolving to ever deeper paths. Links failing any guard aren't followed,
+// each guard is off at 0.
+type TrapOptions struct {
+	// MaxURLLength caps the length of the URLs followed, in bytes
+	MaxURLLength int
+	// MaxPathSegments caps how many segments their paths have, e.g. 3 for
+	// /a/b/c
+	MaxPathSegments int
+	// MaxQueryParams caps how many query parameters they have
+	MaxQueryParams int
+	// MaxRepeatedSegments caps how many times any one path segment may
+	// appear, e.g. 3 stops /a/a/a/a and /a/b/a/b/a/b/a
+	MaxRepeatedSegments int
+	// MaxYearsAhead and MaxYearsBack cap how far the dates in URLs, e.g.
+	// /2024/05/ or ?date=2024-05-01, may be from now, in years
+	MaxYearsAhead int
+	MaxYearsBack  int
+}
+
+// DefaultTrapOptions stop the common traps while leaving ordinary sites,
+// and their archives, alone
+var DefaultTrapOptions = TrapOptions{
+	MaxURLLength:        2048,
+	MaxPathSegments:     32,
+	MaxQueryParams:      16,
+	MaxRepeatedSegments: 3,
+	MaxYearsAhead:       2,
+	MaxYearsBack:        30,
+}
+
+// urlDate matches a year and month, and maybe a day, in a URL, e.g.
+// 2024/05, 2024-05-01
+var urlDate = regexp.MustCompile(`(?:^|[^0-9])((?:19|20)[0-9]{2})[-/](0?[1-9]|1[0-2])(?:[-/](0?[1-9]|[12][0-9]|3[01]))?(?:[^0-9]|$)`)
+
+// trap returns why the URL looks like a crawler trap, or "" if it doesn't
+func (t TrapOptions) trap(url string, now time.Time) string {
+	if t.MaxURLLength > 0 && len(url) > t.MaxURLLength {
+		return "url too long"
+	}
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.FieldsFunc(u.EscapedPath(), func(r rune) bool { return r == '/' })
+	if t.MaxPathSegments > 0 && len(segments) > t.MaxPathSegments {
+		return "too many path segments"
+	}
+	if t.MaxRepeatedSegments > 0 {
+		counts := map[string]int{}
+		for _, seg := range segments {
+			if counts[seg]++; counts[seg] > t.MaxRepeatedSegments {
+				return "repeated path segment"
+			}
+		}
+	}
+
+	query := u.Query()
+	if t.MaxQueryParams > 0 {
+		n := 0
+		for _, values := range query {
+			n += len(values)
+		}
+		if n > t.MaxQueryParams {
+			return "too many query parameters"
+		}
+	}
+
+	if t.MaxYearsAhead > 0 || t.MaxYearsBack > 0 {
+		years := []int{}
+		for _, m := range urlDate.FindAllStringSubmatch(u.Path+"?"+u.RawQuery, -1) {
+			year, _ := strconv.Atoi(m[1])
+			years = append(years, year)
+		}
+		// and calendars paging by ?year=2024&month=5
+		for name, values := range query {
+			if strings.EqualFold(name, "year") && len(values) > 0 {
+				if year, err := strconv.Atoi(values[0]); err == nil {
+					years = append(years, year)
+				}
+			}
+		}
+		for _, year := range years {
+			if t.MaxYearsAhead > 0 && year > now.Year()+t.MaxYearsAhead {
+				return "date too far ahead"
+			}
+			if t.MaxYearsBack > 0 && year < now.Year()-t.MaxYearsBack {
+				return "date too far back"
+			}
+		}
+	}
+	return ""
+}
+
+// filterTraps drops the page's links, and frames, that look like crawler
+// traps
+func (c *Crawler) filterTraps(url string, page *scrapeResult, logger *slog.Logger) {
+	if c.Traps == (TrapOptions{}) {
+		return
+	}
+	now := time.Now()
+
+	hrefs, anchors := []string{}, []LinkAnchor{}
+	for i, href := range page.hrefs {
+		if reason := c.Traps.trap(href, now); reason != "" {
+			logger.Debug("not following crawler trap", "url", url, "href", href, "reason", reason)
+			continue
+		}
+		hrefs = append(hrefs, href)
+		if i < len(page.anchors) {
+			anchors = append(anchors, page.anchors[i])
+		}
+	}
+	page.hrefs, page.anchors = hrefs, anchors
+
+	frames := []string{}
+	for _, src := range page.frames {
+		if reason := c.Traps.trap(src, now); reason != "" {
+			logger.Debug("not following crawler trap", "url", url, "frame", src, "reason", reason)
+			continue
+		}
+		frames = append(frames, src)
+	}
+	page.frames = frames
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTrap(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/blog/2024/05/post", ""},
+		{"https://example.com/blog/2010/05/01/post", ""},
+		{"https://example.com/img/1920-1080.jpg", ""},
+		{"https://example.com/" + strings.Repeat("x", 2048), "url too long"},
+		{"https://example.com/s/s/s/s", "repeated path segment"},
+		{"https://example.com" + strings.Repeat("/a/b/c/d/e/f/g/h", 5), "too many path segments"},
+		{"https://example.com/a/b/a/b/a/b/a", "repeated path segment"},
+		{"https://example.com/a/b/a/b/a/b", ""},
+		{"https://example.com/?" + strings.Repeat("p=1&", 17), "too many query parameters"},
+		{"https://example.com/calendar/2031/01", "date too far ahead"},
+		{"https://example.com/events?date=2026-12-01", ""},
+		{"https://example.com/events?date=2027-01-01", "date too far ahead"},
+		{"https://example.com/calendar?year=1990&month=1", "date too far back"},
+		{"https://example.com/archive/1993-02-01", "date too far back"},
+	}
+	for _, tt := range tests {
+		if got := DefaultTrapOptions.trap(tt.url, now); got != tt.want {
+			t.Errorf("trap(%.60q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	if got := (TrapOptions{}).trap(tests[3].url, now); got != "" {
+		t.Errorf("trap with no guards = %q, want none", got)
+	}
+}
+
+func TestTraps(t *testing.T) {
+	requests := atomic.Int32{}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		// a relative link to ever deeper paths, and a calendar without end
+		year := time.Now().Year()
+		if _, err := fmt.Sscanf(r.URL.Query().Get("year"), "%d", &year); err == nil {
+			year++
+		}
+		fmt.Fprintf(w, `<a href="loop/more">deeper</a><a href="/calendar?year=%d">next year</a>`, year)
+	}))
+	defer site.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	// /, /loop/more to /loop/loop/loop/more, and the calendar from this
+	// year to two ahead
+	if n := requests.Load(); n != 7 {
+		t.Errorf("crawled %d pages, want 7 with the traps cut short", n)
+	}
+}
@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// Traversals are the built-in crawl orders by name, as accepted by crawlsvc
+// -traversal, each a PriorityFunc for Crawler.Priority
+var Traversals = map[string]PriorityFunc{
+	"bfs":    BreadthFirst,
+	"dfs":    DepthFirst,
+	"random": RandomOrder,
+}
+
+// BreadthFirst crawls every page at one depth before any deeper, covering
+// the whole top of a site predictably, as for an audit
+func BreadthFirst(url string, depth int, source *Page) float64 {
+	return -float64(depth)
+}
+
+// DepthFirst crawls the deepest pages first, following each trail of links
+// as far as it goes before backtracking, as for mirroring an archive
+func DepthFirst(url string, depth int, source *Page) float64 {
+	return float64(depth)
+}
+
+// RandomOrder crawls pages in a random order, so a crawl cut short by
+// MaxPages samples the whole site evenly
+func RandomOrder(url string, depth int, source *Page) float64 {
+	return rand.Float64()
+}
+
+// ParseTraversal looks up a built-in crawl order by name
+func ParseTraversal(name string) (PriorityFunc, error) {
+	p, ok := Traversals[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		known := []string{}
+		for k := range Traversals {
+			known = append(known, k)
+		}
+		sort.Strings(known)
+		return nil, fmt.Errorf("unknown traversal %q, want one of %s", name, strings.Join(known, ", "))
+	}
+	return p, nil
+}
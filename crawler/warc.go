@@ -0,0 +1,305 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WARCOptions configure a WARCWriter
+type WARCOptions struct {
+	// Dir is where the WARC files are written
+	Dir string
+	// Prefix starts every file name, "crawl" by default
+	Prefix string
+	// MaxFileSize is the size, compressed, at which a file is closed and
+	// the next started, 1GB by default
+	MaxFileSize int64
+	// Images archives the images fetched, when hashed, probed or
+	// downloaded, as well as the pages
+	Images bool
+}
+
+// WARCWriter archives the responses fetched during a crawl as WARC 1.1
+// records, each request and response a pair, in gzipped files for replay in
+// pywb and other Wayback tooling. It's safe for concurrent use.
+type WARCWriter struct {
+	opts WARCOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	written int64
+	serial  int
+	infoID  string // the current file's warcinfo record
+}
+
+// NewWARCWriter creates the directory for a WARCWriter, the files themselves
+// are created as records are written
+func NewWARCWriter(opts WARCOptions) (*WARCWriter, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = "crawl"
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 1 << 30
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &WARCWriter{opts: opts}, nil
+}
+
+// Close closes the file being written
+func (w *WARCWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// warcRecord is a record to write, block being its content
+type warcRecord struct {
+	kind        string
+	id          string
+	uri         string
+	date        time.Time
+	contentType string
+	headers     [][2]string
+	block       []byte
+}
+
+// writeExchange writes the request and response records of one fetch,
+// body being as much of the response body as was read
+func (w *WARCWriter) writeExchange(req *http.Request, resp *http.Response, body []byte, truncated bool, date time.Time) error {
+	uri := req.URL.String()
+	respID, reqID := newRecordID(), newRecordID()
+
+	response := warcRecord{
+		kind:        "response",
+		id:          respID,
+		uri:         uri,
+		date:        date,
+		contentType: "application/http;msgtype=response",
+		headers:     [][2]string{{"WARC-Payload-Digest", warcDigest(body)}},
+		block:       append(httpResponseHead(resp), body...),
+	}
+	if truncated {
+		response.headers = append(response.headers, [2]string{"WARC-Truncated", "length"})
+	}
+	request := warcRecord{
+		kind:        "request",
+		id:          reqID,
+		uri:         uri,
+		date:        date,
+		contentType: "application/http;msgtype=request",
+		headers:     [][2]string{{"WARC-Concurrent-To", respID}},
+		block:       httpRequestHead(req),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.open(); err != nil {
+		return err
+	}
+	for _, r := range []warcRecord{response, request} {
+		if err := w.write(r); err != nil {
+			return err
+		}
+	}
+
+	// roll over once the file is full, records aren't split across files
+	if w.written >= w.opts.MaxFileSize {
+		err := w.file.Close()
+		w.file = nil
+		return err
+	}
+	return nil
+}
+
+// open starts the next file, with its warcinfo record, if none is open
+func (w *WARCWriter) open() error {
+	if w.file != nil {
+		return nil
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s-%05d-%s.warc.gz", w.opts.Prefix, time.Now().UTC().Format("20060102150405"), w.serial, hex.EncodeToString(suffix))
+	file, err := os.OpenFile(filepath.Join(w.opts.Dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file, w.written, w.infoID = file, 0, newRecordID()
+	w.serial++
+
+	return w.write(warcRecord{
+		kind:        "warcinfo",
+		id:          w.infoID,
+		date:        time.Now().UTC(),
+		contentType: "application/warc-fields",
+		headers:     [][2]string{{"WARC-Filename", name}},
+		block:       []byte("software: go-imgcrawler\r\nformat: WARC File Format 1.1\r\nconformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"),
+	})
+}
+
+// write appends the record to the file as a gzip member of its own, as
+// WARC readers expect of .warc.gz files
+func (w *WARCWriter) write(r warcRecord) error {
+	head := strings.Builder{}
+	head.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&head, "WARC-Type: %s\r\n", r.kind)
+	fmt.Fprintf(&head, "WARC-Record-ID: %s\r\n", r.id)
+	fmt.Fprintf(&head, "WARC-Date: %s\r\n", r.date.UTC().Format(time.RFC3339))
+	if r.uri != "" {
+		fmt.Fprintf(&head, "WARC-Target-URI: %s\r\n", r.uri)
+	}
+	if r.kind != "warcinfo" {
+		fmt.Fprintf(&head, "WARC-Warcinfo-ID: %s\r\n", w.infoID)
+	}
+	for _, h := range r.headers {
+		fmt.Fprintf(&head, "%s: %s\r\n", h[0], h[1])
+	}
+	fmt.Fprintf(&head, "WARC-Block-Digest: %s\r\n", warcDigest(r.block))
+	fmt.Fprintf(&head, "Content-Type: %s\r\n", r.contentType)
+	fmt.Fprintf(&head, "Content-Length: %d\r\n\r\n", len(r.block))
+
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(head.String()))
+	gz.Write(r.block)
+	gz.Write([]byte("\r\n\r\n"))
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	n, err := w.file.Write(buf.Bytes())
+	w.written += int64(n)
+	return err
+}
+
+// newRecordID is a random UUID URN, as WARC record IDs usually are
+func newRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// warcDigest is the base32 SHA-1 digest WARC tooling expects
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// warcRedactedHeaders are request headers whose values aren't archived
+var warcRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// httpRequestHead is the request line and headers as sent
+func httpRequestHead(req *http.Request) []byte {
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", req.URL.Host)
+
+	header := req.Header.Clone()
+	for _, h := range warcRedactedHeaders {
+		if header.Get(h) != "" {
+			header.Set(h, "[redacted]")
+		}
+	}
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// httpResponseHead is the status line and headers as received, less any
+// the transport undid, e.g. the Content-Encoding of a body it decompressed
+func httpResponseHead(resp *http.Response) []byte {
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	resp.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// warcTransport hands every exchange worth archiving to the WARCWriter once
+// its body is closed, the pages and redirects, and the images if asked to
+type warcTransport struct {
+	base http.RoundTripper
+	c    *Crawler
+}
+
+func (t *warcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !t.archived(req, resp) {
+		return resp, err
+	}
+
+	resp.Body = &warcBody{
+		ReadCloser: resp.Body,
+		date:       time.Now().UTC(),
+		finish: func(body []byte, truncated bool, date time.Time) {
+			if err := t.c.WARC.writeExchange(req, resp, body, truncated, date); err != nil {
+				t.c.Logger.Warn("failed to write WARC records", "url", req.URL.String(), "err", err)
+			}
+		},
+	}
+	return resp, nil
+}
+
+// archived reports whether the exchange belongs in the archive
+func (t *warcTransport) archived(req *http.Request, resp *http.Response) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return resp.StatusCode != http.StatusNotModified
+	}
+	ct := resp.Header.Get("Content-Type")
+	return t.c.parseable(ct) || (t.c.WARC.opts.Images && strings.HasPrefix(strings.ToLower(ct), "image/"))
+}
+
+// warcBody keeps a copy of the body as it's read, to archive once closed.
+// Bodies closed before being read to the end, e.g. as they were too large,
+// are archived as truncated.
+type warcBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	eof    bool
+	date   time.Time
+	once   sync.Once
+	finish func(body []byte, truncated bool, date time.Time)
+}
+
+func (b *warcBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *warcBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.finish(b.buf.Bytes(), !b.eof, b.date)
+	})
+	return err
+}
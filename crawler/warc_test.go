@@ -0,0 +1,155 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readWARC reads back the records of every WARC file in dir
+func readWARC(t *testing.T, dir string) (records []textproto.MIMEHeader, blocks []string, files int) {
+	t.Helper()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := bufio.NewReader(gz)
+		for {
+			tp := textproto.NewReader(r)
+			version, err := tp.ReadLine()
+			if err == io.EOF {
+				break
+			}
+			if err != nil || version != "WARC/1.1" {
+				t.Fatalf("%s: record starts %q, %v", path, version, err)
+			}
+			header, err := tp.ReadMIMEHeader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, _ := strconv.Atoi(header.Get("Content-Length"))
+			block := make([]byte, n+4)
+			if _, err := io.ReadFull(r, block); err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, header)
+			blocks = append(blocks, string(block[:n]))
+		}
+	}
+	return records, blocks, len(paths)
+}
+
+func TestWARC(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/a.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<img src="/a.png">`))
+		}
+	}))
+	defer site.Close()
+
+	dir := t.TempDir()
+	warc, err := NewWARCWriter(WARCOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestCrawler(t)
+	c.WARC = warc
+	c.Header = http.Header{"Authorization": {"Bearer secret"}}
+
+	page := c.scrape(t.Context(), site.URL+"/old", c.Logger)
+	if page.status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", page.status)
+	}
+	// images are only archived with Images set
+	resp, err := c.client().Get(site.URL + "/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	warc.Close()
+
+	records, blocks, _ := readWARC(t, dir)
+	kinds := []string{}
+	for _, r := range records {
+		kinds = append(kinds, r.Get("WARC-Type")+" "+strings.TrimPrefix(r.Get("WARC-Target-URI"), site.URL))
+	}
+	want := "warcinfo ,response /old,request /old,response /page,request /page"
+	if got := strings.Join(kinds, ","); got != want {
+		t.Fatalf("records = %s, want %s", got, want)
+	}
+
+	if !strings.HasPrefix(blocks[1], "HTTP/1.1 302 Found\r\n") {
+		t.Errorf("redirect response block = %q", blocks[1])
+	}
+	if !strings.HasSuffix(blocks[3], `<img src="/a.png">`) || records[3].Get("WARC-Truncated") != "" {
+		t.Errorf("page response block = %q, truncated %q", blocks[3], records[3].Get("WARC-Truncated"))
+	}
+	if records[4].Get("WARC-Concurrent-To") != records[3].Get("WARC-Record-ID") {
+		t.Errorf("request isn't concurrent to its response")
+	}
+	if records[3].Get("WARC-Warcinfo-ID") != records[0].Get("WARC-Record-ID") {
+		t.Errorf("response doesn't refer to the warcinfo record")
+	}
+	if strings.Contains(blocks[4], "secret") {
+		t.Errorf("request block has the Authorization header: %q", blocks[4])
+	}
+	if records[3].Get("WARC-Block-Digest") != warcDigest([]byte(blocks[3])) {
+		t.Errorf("block digest = %s, want %s", records[3].Get("WARC-Block-Digest"), warcDigest([]byte(blocks[3])))
+	}
+}
+
+func TestWARCRollsFiles(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	}))
+	defer site.Close()
+
+	dir := t.TempDir()
+	warc, err := NewWARCWriter(WARCOptions{Dir: dir, MaxFileSize: 1, Images: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestCrawler(t)
+	c.WARC = warc
+
+	for range 3 {
+		resp, err := c.client().Get(site.URL + "/a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	warc.Close()
+
+	// each file has its own warcinfo record ahead of the exchange
+	records, _, files := readWARC(t, dir)
+	if files != 3 || len(records) != 9 {
+		t.Errorf("got %d records in %d files, want 9 in 3", len(records), files)
+	}
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The events a Webhook is notified of
+const (
+	EventCrawlStarted   = "crawl.started"
+	EventCrawlCompleted = "crawl.completed"
+	EventCrawlStopped   = "crawl.stopped" // its context was cancelled
+	EventCrawlFailed    = "crawl.failed"
+	EventImagesFound    = "images.found"
+)
+
+// WebhookEvent is the JSON body POSTed to a Webhook
+type WebhookEvent struct {
+	Event string    `json:"event"`
+	Job   string    `json:"job,omitempty"`
+	Time  time.Time `json:"time"`
+	// Error is why the crawl failed
+	Error string `json:"error,omitempty"`
+	// the crawl's progress at the time, left out of images.found
+	Queued  int `json:"queued,omitempty"`
+	Visited int `json:"visited,omitempty"`
+	Images  int `json:"images,omitempty"`
+	// Records are the images newly found, for images.found
+	Records []ImageRecord `json:"records,omitempty"`
+}
+
+// Webhook POSTs a WebhookEvent to URL as each run of the crawl starts and
+// ends, when added to Crawler.Webhooks. Added to Crawler.Sinks as well, it
+// POSTs images.found with the images newly found, as they're found, at most
+// ImageBatch at a time.
+type Webhook struct {
+	URL string
+	// Events limits the lifecycle events sent, all of them by default
+	Events []string
+	// ImageBatch is the most images sent in one images.found, 100 by default
+	ImageBatch int
+	// Header is added to every request, e.g. for authentication
+	Header http.Header
+	// Client defaults to one with a 10s timeout
+	Client *http.Client
+}
+
+// webhookAttempts is how many times a lifecycle event is tried
+const webhookAttempts = 3
+
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Deliver implements Sink, POSTing the images in batches of ImageBatch
+func (h *Webhook) Deliver(ctx context.Context, records []ImageRecord) error {
+	size := h.ImageBatch
+	if size <= 0 {
+		size = sinkBatchSize
+	}
+	for len(records) > 0 {
+		n := min(size, len(records))
+		err := h.post(ctx, WebhookEvent{Event: EventImagesFound, Time: time.Now().UTC(), Records: records[:n]})
+		if err != nil {
+			return err
+		}
+		records = records[n:]
+	}
+	return nil
+}
+
+// wants reports whether the lifecycle event is to be sent
+func (h *Webhook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Webhook) post(ctx context.Context, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", h.URL, resp.Status)
+	}
+	return nil
+}
+
+// notify sends a lifecycle event to every webhook that wants it, retrying
+// each a few times before giving up, with the job's progress filled in
+func (c *Crawler) notify(ctx context.Context, event string, runErr error) {
+	if len(c.Webhooks) == 0 {
+		return
+	}
+
+	e := WebhookEvent{Event: event, Job: c.JobID, Time: time.Now().UTC()}
+	if runErr != nil {
+		e.Error = runErr.Error()
+	}
+	if info, err := c.Info(); err == nil {
+		e.Queued, e.Visited, e.Images = info.Queued, info.Visited, info.Images
+	}
+
+	for _, h := range c.Webhooks {
+		if !h.wants(event) {
+			continue
+		}
+
+		var err error
+		for attempt := range webhookAttempts {
+			if attempt > 0 {
+				select {
+				case <-time.After(time.Duration(attempt) * time.Second):
+				case <-ctx.Done():
+				}
+			}
+			if err = h.post(ctx, e); err == nil || ctx.Err() != nil {
+				break
+			}
+		}
+		if err != nil {
+			c.Logger.Warn("failed to notify webhook", "url", h.URL, "event", event, "err", err)
+			c.reportError(h.URL, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		for i := range 5 {
+			fmt.Fprintf(w, `<img src="/%d.png">`, i)
+		}
+	}))
+	defer site.Close()
+
+	var mu sync.Mutex
+	events := []WebhookEvent{}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("webhook sent without its header")
+		}
+		e := WebhookEvent{}
+		json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	c, _ := newTestCrawler(t)
+	c.JobID = "job1"
+	h := &Webhook{URL: hook.URL, ImageBatch: 2, Header: http.Header{"Authorization": {"Bearer token"}}}
+	c.Webhooks = []*Webhook{h}
+	c.Sinks = map[string]Sink{"hook": h}
+	c.Seed(site.URL + "/")
+	c.Run()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 5 {
+		t.Fatalf("got %d events, want started, three batches of images and completed: %+v", len(events), events)
+	}
+	if e := events[0]; e.Event != EventCrawlStarted || e.Job != "job1" || e.Queued != 1 {
+		t.Errorf("first event = %+v, want crawl.started with the seed queued", e)
+	}
+	images := 0
+	for _, e := range events[1:4] {
+		if e.Event != EventImagesFound || len(e.Records) > 2 {
+			t.Errorf("event = %+v, want images.found with at most 2 images", e)
+		}
+		images += len(e.Records)
+	}
+	if images != 5 {
+		t.Errorf("images.found sent %d images, want 5", images)
+	}
+	if e := events[4]; e.Event != EventCrawlCompleted || e.Visited != 1 || e.Images != 5 {
+		t.Errorf("last event = %+v, want crawl.completed with 1 page and 5 images", e)
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	var mu sync.Mutex
+	events := []string{}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := WebhookEvent{}
+		json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e.Event)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	c, _ := newTestCrawler(t)
+	c.Webhooks = []*Webhook{{URL: hook.URL, Events: []string{EventCrawlStopped}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.RunContext(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0] != EventCrawlStopped {
+		t.Errorf("events = %v, want just crawl.stopped", events)
+	}
+}
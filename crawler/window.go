@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a time of day during which crawling is allowed, as offsets from
+// midnight. A window ending before it starts runs over midnight.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses a window given as "HH:MM-HH:MM", e.g. "01:00-05:00",
+// or "22:00-06:00" for overnight
+func ParseWindow(s string) (Window, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q, want HH:MM-HH:MM", s)
+	}
+
+	w := Window{}
+	var err error
+	if w.Start, err = parseTimeOfDay(start); err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %v", s, err)
+	}
+	if w.End, err = parseTimeOfDay(end); err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %v", s, err)
+	}
+	if w.Start == w.End {
+		return Window{}, fmt.Errorf("invalid window %q, it starts when it ends", s)
+	}
+	return w, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a time of day", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w Window) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(w.Start) + "-" + format(w.End)
+}
+
+// contains reports whether the time of day, as an offset from midnight,
+// falls within the window
+func (w Window) contains(d time.Duration) bool {
+	if w.Start < w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// midnight is the start of t's day, in t's location
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// inCrawlWindow reports whether crawling is allowed at t
+func (c *Crawler) inCrawlWindow(t time.Time) bool {
+	if len(c.CrawlWindows) == 0 {
+		return true
+	}
+
+	t = t.In(c.windowLocation())
+	sinceMidnight := t.Sub(midnight(t))
+	for _, w := range c.CrawlWindows {
+		if w.contains(sinceMidnight) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextCrawlWindow is when the next window after t opens
+func (c *Crawler) nextCrawlWindow(t time.Time) time.Time {
+	t = t.In(c.windowLocation())
+
+	next := time.Time{}
+	for days := 0; days <= 1; days++ {
+		day := midnight(t).AddDate(0, 0, days)
+		for _, w := range c.CrawlWindows {
+			start := day.Add(w.Start)
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next
+}
+
+func (c *Crawler) windowLocation() *time.Location {
+	if c.WindowLocation == nil {
+		return time.Local
+	}
+	return c.WindowLocation
+}
+
+// waitForCrawlWindow blocks while outside the crawl windows, returning false
+// if ctx was cancelled in the meantime
+func (c *Crawler) waitForCrawlWindow(ctx context.Context, w *worker) bool {
+	logged := false
+	for !c.inCrawlWindow(time.Now()) {
+		next := c.nextCrawlWindow(time.Now())
+		if !logged {
+			w.logger.Info("outside the crawl windows, waiting", "until", next)
+			logged = true
+		}
+
+		// rechecked at least every minute in case the clock jumps
+		wait := min(time.Until(next), time.Minute)
+		select {
+		case <-time.After(max(wait, time.Second)):
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if logged {
+		w.logger.Info("crawl window open, resuming")
+	}
+	return true
+}
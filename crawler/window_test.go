@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrawlWindows(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	c.WindowLocation = tokyo
+	for _, s := range []string{"01:00-05:00", "22:30-00:30"} {
+		w, err := ParseWindow(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.CrawlWindows = append(c.CrawlWindows, w)
+	}
+
+	at := func(hhmm string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", "2026-03-10 "+hhmm, tokyo)
+		return t
+	}
+	tests := []struct {
+		at     time.Time
+		inside bool
+		next   time.Time
+	}{
+		{at("00:00"), true, at("01:00")},
+		{at("00:30"), false, at("01:00")},
+		{at("04:59"), true, at("22:30")},
+		{at("05:00"), false, at("22:30")},
+		{at("23:00"), true, at("01:00").AddDate(0, 0, 1)},
+	}
+	for _, tt := range tests {
+		// times in another zone are converted to the windows' zone
+		now := tt.at.UTC()
+		if got := c.inCrawlWindow(now); got != tt.inside {
+			t.Errorf("inCrawlWindow(%s) = %v, want %v", tt.at, got, tt.inside)
+		}
+		if got := c.nextCrawlWindow(now); !got.Equal(tt.next) {
+			t.Errorf("nextCrawlWindow(%s) = %s, want %s", tt.at, got, tt.next)
+		}
+	}
+}
+
+func TestParseWindowInvalid(t *testing.T) {
+	for _, s := range []string{"01:00", "1am-5am", "25:00-05:00", "03:00-03:00"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// workerLease is how long a worker stays counted as active without renewing
+// its lease, so that a worker which dies mid-page only holds up the end of
+// the crawl for a while rather than forever
+const workerLease = 30 * time.Second
+
+// claimScript is the completion barrier. It atomically either pops the entry
+// of highest priority and leases the worker as active, or, if the queue is
+// empty, drops the worker's lease and counts the leases still live, along
+// with the queue's epoch, which every write to the queue advances. So a
+// worker only sees no active workers when the queue is empty and nobody is
+// left to refill it, and by the epoch whether anything was queued since it
+// last saw so, see complete.
+//
+// The queue holds URLs, their entries kept in a hash, see enqueueScript.
+// Members queued by earlier versions are entries themselves, and returned
+// as they are. URLs deferred until later, see claimHost, are passed over,
+// keeping their places, and if nothing else is queued {3} returned, with
+// the milliseconds until the first may be claimed.
+//
+// If ARGV[4] is "mark" it also marks the entry's page as visited, saving a
+// round trip, and discards entries of pages visited already, up to 1000 at
+// a time, returning {2} if there may be more to claim.
+//
+// A worker with pages still in flight, ARGV[5] "1", keeps its lease even if
+// the queue is empty, as its pages may yet add to it.
+//
+//	KEYS[1] the crawl queue, KEYS[2] the active worker leases, KEYS[3] the
+//	pages visited, KEYS[4] the queue's epoch, KEYS[5] the queue's entries,
+//	KEYS[6] the deferred URLs
+//	ARGV[1] the worker, ARGV[2] now, ARGV[3] the lease in milliseconds,
+//	ARGV[4] whether to mark pages visited and ARGV[5] whether the worker
+//	has pages in flight
+var claimScript = redis.NewScript(6, `
+if redis.call("TYPE", KEYS[2]).ok == "string" then
+	-- the INCR/DECR counter of older versions
+	redis.call("DEL", KEYS[2])
+end
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ARGV[2])
+
+local skipped, held, claimed, wait = 0, {}, nil, nil
+while skipped + #held / 2 < 1000 do
+	local popped = redis.call("ZPOPMAX", KEYS[1])
+	local member, score = popped[1], popped[2]
+	if not member then
+		break
+	end
+
+	local notBefore = redis.call("ZSCORE", KEYS[6], member)
+	if notBefore and tonumber(notBefore) > tonumber(ARGV[2]) then
+		table.insert(held, score)
+		table.insert(held, member)
+		wait = math.min(wait or math.huge, tonumber(notBefore) - tonumber(ARGV[2]))
+	else
+		if notBefore then
+			redis.call("ZREM", KEYS[6], member)
+		end
+
+		-- 1 if marked visited, -1 if visited already and 0 if left to the worker
+		local entry, visited = redis.call("HGET", KEYS[5], member), 0
+		if entry then
+			redis.call("HDEL", KEYS[5], member)
+			if ARGV[4] == "mark" then
+				visited = redis.call("SADD", KEYS[3], member) == 1 and 1 or -1
+			end
+		else
+			entry = member
+		end
+
+		if visited >= 0 then
+			claimed = {1, entry, visited}
+			break
+		end
+		skipped = skipped + 1
+	end
+end
+for i = 1, #held, 2 do
+	redis.call("ZADD", KEYS[1], held[i], held[i + 1])
+end
+
+if claimed then
+	redis.call("ZADD", KEYS[2], tonumber(ARGV[2]) + tonumber(ARGV[3]), ARGV[1])
+	return claimed
+end
+if skipped > 0 and skipped + #held / 2 >= 1000 then
+	return {2, skipped}
+end
+if #held > 0 then
+	return {3, wait}
+end
+
+if ARGV[5] == "1" then
+	redis.call("ZADD", KEYS[2], tonumber(ARGV[2]) + tonumber(ARGV[3]), ARGV[1])
+else
+	redis.call("ZREM", KEYS[2], ARGV[1])
+end
+return {0, redis.call("ZCARD", KEYS[2]), redis.call("GET", KEYS[4]) or "0"}
+`)
+
+// claim pops the next entry for the worker, or if the queue is empty returns
+// a nil entry and how many workers are still active, noting the queue's
+// epoch. Entries are marked as visited in the same round trip, unless
+// VisitedBloom is set, and those visited already skipped. While only
+// deferred entries are queued it waits for them, or with pages in flight
+// returns a nil entry and 1 active worker.
+func (c *Crawler) claim(ctx context.Context, w *worker) (*Entry, int, error) {
+	mark := ""
+	if c.VisitedBloom == nil {
+		mark = "mark"
+	}
+
+	for {
+		reply, err := redis.Values(claimScript.Do(w.conn, c.KeyCrawlQ, c.KeyActiveWorkers, c.KeyVisitedHREFs, c.KeyEpoch, c.queueEntriesKey(), c.queueDeferredKey(), w.id, time.Now().UnixMilli(), workerLease.Milliseconds(), mark, w.inFlight > 0))
+		if err != nil {
+			if w.conn.Err() != nil && c.reconnect(ctx, w) {
+				continue
+			}
+			return nil, 0, err
+		}
+
+		if len(reply) < 2 {
+			return nil, 0, fmt.Errorf("unexpected claim reply %v", reply)
+		}
+		claimed, _ := redis.Int(reply[0], nil)
+		w.busy.Store(claimed == 1 || w.inFlight > 0)
+		switch claimed {
+		case 0:
+			if len(reply) > 2 {
+				w.epoch, _ = redis.Int64(reply[2], nil)
+			}
+			active, err := redis.Int(reply[1], nil)
+			return nil, active, err
+		case 2:
+			continue // skipped a run of pages visited already
+		case 3:
+			// only pages deferred until later are queued, wait for the first
+			// unless there are pages in flight to see to meanwhile
+			if w.inFlight > 0 {
+				return nil, 1, nil
+			}
+			wait, _ := redis.Int64(reply[1], nil)
+			select {
+			case <-time.After(time.Duration(wait) * time.Millisecond):
+				continue
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			}
+		}
+		w.quiet = false
+
+		data, err := redis.Bytes(reply[1], nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		entry := Entry{}
+		if err := c.Codec.Unmarshal(data, &entry); err != nil {
+			w.logger.Error("failed to decode crawl queue entry", "err", err)
+			continue
+		}
+		if len(reply) > 2 {
+			visited, _ := redis.Int(reply[2], nil)
+			entry.visited = visited == 1
+		}
+		if entry.Version > EntryVersion {
+			w.logger.Warn("crawl queue entry is from a newer version, ignoring what it added", "url", entry.URL, "version", entry.Version)
+		}
+		return &entry, 0, nil
+	}
+}
+
+// complete reports whether the crawl is complete, once claim has twice found
+// the queue empty and no worker active, TerminationGrace apart, with nothing
+// queued in between. A single look isn't enough as a worker whose lease
+// lapsed, e.g. while Redis was unreachable, may yet queue the links it found.
+func (c *Crawler) complete(w *worker, active int) bool {
+	if active > 0 {
+		w.quiet = false
+		return false
+	}
+	if w.quiet && w.epoch == w.quietEpoch {
+		return true
+	}
+	w.quiet, w.quietEpoch = true, w.epoch
+	return false
+}
+
+// waitToClaim waits before claiming again from an empty queue, for
+// TerminationGrace if the crawl looked complete, returning false if ctx is
+// cancelled meanwhile
+func (c *Crawler) waitToClaim(ctx context.Context, active int) bool {
+	wait := 1 * time.Second
+	if active == 0 {
+		wait = c.TerminationGrace
+	}
+	select {
+	case <-time.After(wait):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release drops the worker's lease as it exits, if this fails the lease
+// just expires
+func (c *Crawler) release(w *worker) {
+	w.busy.Store(false)
+	if _, err := w.conn.Do("ZREM", c.KeyActiveWorkers, w.id); err != nil {
+		w.logger.Warn("failed to release worker lease", "err", err)
+	}
+}
+
+// heartbeatTTL is how long the heartbeat of a worker that stopped without
+// clearing it, e.g. as its process crashed, is listed as stale before it's
+// forgotten
+const heartbeatTTL = time.Hour
+
+// WorkerHeartbeat is the last sign of life of a worker, in any process
+// sharing the crawl
+type WorkerHeartbeat struct {
+	ID      string    `json:"-"`
+	Host    string    `json:"host"` // the hostname of the worker's process
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Seen    time.Time `json:"seen"`
+	Busy    bool      `json:"busy"` // held a lease when last seen
+	// Stale is set if the worker hasn't been seen for longer than a lease,
+	// so has most likely died, along with its process, without saying so
+	Stale bool `json:"-"`
+}
+
+// heartbeat records every worker's heartbeat and renews the leases of the
+// busy workers until stop is closed, so pages that take longer than the
+// lease to crawl don't let it lapse. The heartbeats are cleared once stop is
+// closed, so only those of workers that died are left behind.
+func (c *Crawler) heartbeat(workers []*worker, stop <-chan struct{}) <-chan struct{} {
+	done := make(chan struct{})
+
+	host, _ := os.Hostname()
+	started := time.Now().UTC()
+
+	go func() {
+		defer close(done)
+
+		conn := c.RedisPool.Get()
+		defer func() { conn.Close() }()
+
+		for {
+			if conn.Err() != nil {
+				conn.Close()
+				conn = c.RedisPool.Get()
+			}
+
+			now := time.Now()
+			expiry := now.Add(workerLease).UnixMilli()
+			args := redis.Args{c.KeyHeartbeats}
+			for _, w := range workers {
+				busy := w.busy.Load()
+				if busy {
+					// XX so a worker that has since gone idle isn't revived
+					conn.Send("ZADD", c.KeyActiveWorkers, "XX", expiry, w.id)
+				}
+				data, _ := json.Marshal(WorkerHeartbeat{Host: host, PID: os.Getpid(), Started: started, Seen: now.UTC(), Busy: busy})
+				args = args.Add(w.id, data)
+			}
+			if len(workers) > 0 {
+				conn.Send("HSET", args...)
+			}
+			if _, err := conn.Do(""); err != nil {
+				c.Logger.Warn("failed to renew worker leases", "err", err)
+			}
+
+			select {
+			case <-time.After(workerLease / 3):
+			case <-stop:
+				c.clearHeartbeats(workers)
+				return
+			}
+		}
+	}()
+
+	return done
+}
+
+// clearHeartbeats removes the heartbeats of workers that have exited
+func (c *Crawler) clearHeartbeats(workers []*worker) {
+	if len(workers) == 0 {
+		return
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	args := redis.Args{c.KeyHeartbeats}
+	for _, w := range workers {
+		args = args.Add(w.id)
+	}
+	if _, err := conn.Do("HDEL", args...); err != nil {
+		c.Logger.Warn("failed to clear worker heartbeats", "err", err)
+	}
+}
+
+// Workers lists the heartbeats of the workers crawling, or that were until
+// they died, across every process, marking those not seen within a lease as
+// stale. Heartbeats stale for over an hour are forgotten.
+func (c *Crawler) Workers() ([]WorkerHeartbeat, error) {
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+
+	beats, err := redis.StringMap(conn.Do("HGETALL", c.KeyHeartbeats))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	workers := []WorkerHeartbeat{}
+	forgotten := redis.Args{c.KeyHeartbeats}
+	for id, data := range beats {
+		hb := WorkerHeartbeat{}
+		if json.Unmarshal([]byte(data), &hb) != nil || now.Sub(hb.Seen) > heartbeatTTL {
+			forgotten = forgotten.Add(id)
+			continue
+		}
+		hb.ID = id
+		hb.Stale = now.Sub(hb.Seen) > workerLease
+		workers = append(workers, hb)
+	}
+	if len(forgotten) > 1 {
+		if _, err := conn.Do("HDEL", forgotten...); err != nil {
+			return workers, err
+		}
+	}
+
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
+// activeWorkers counts the workers holding live leases
+func (c *Crawler) activeWorkers(conn redis.Conn) (int, error) {
+	return redis.Int(conn.Do("ZCOUNT", c.KeyActiveWorkers, time.Now().UnixMilli(), "+inf"))
+}
@@ -0,0 +1,208 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestClaimBarrier(t *testing.T) {
+	c, _ := newTestCrawler(t)
+	c.Seed("https://example.com/")
+
+	state := newRunState(nil)
+	a, b := c.newWorker(0, state), c.newWorker(1, state)
+	for _, w := range []*worker{a, b} {
+		w.conn = c.RedisPool.Get()
+		defer w.conn.Close()
+	}
+
+	entry, _, err := c.claim(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.URL != "https://example.com/" {
+		t.Fatalf("claimed %v, want the seed", entry)
+	}
+
+	// the queue is empty but a may still enqueue links, so b mustn't finish
+	entry, active, err := c.claim(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil || active != 1 {
+		t.Errorf("claim = %v, %d active, want nothing with a active", entry, active)
+	}
+	if b.busy.Load() {
+		t.Errorf("b holds a lease without an entry")
+	}
+
+	// once a finds the queue empty too, nobody is left to refill it
+	entry, active, err = c.claim(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil || active != 0 {
+		t.Errorf("claim = %v, %d active, want nothing with nobody active", entry, active)
+	}
+}
+
+func TestClaimLeaseExpires(t *testing.T) {
+	c, mr := newTestCrawler(t)
+
+	// the lease of a worker that died mid-page, long lapsed
+	mr.ZAdd(c.KeyActiveWorkers, 1, "dead:0")
+
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+
+	entry, active, err := c.claim(context.Background(), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil || active != 0 {
+		t.Errorf("claim = %v, %d active, want the dead worker's lease to have lapsed", entry, active)
+	}
+}
+
+func TestClaimUpgradesCounter(t *testing.T) {
+	c, mr := newTestCrawler(t)
+
+	// the INCR/DECR counter left by an older version
+	mr.Set(c.KeyActiveWorkers, "3")
+	c.Seed("https://example.com/")
+
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+
+	entry, _, err := c.claim(context.Background(), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatalf("claimed nothing, want the seed")
+	}
+	if n, err := c.activeWorkers(w.conn); err != nil || n != 1 {
+		t.Errorf("activeWorkers = %d, %v, want 1", n, err)
+	}
+}
+
+func TestClaimMarksVisited(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	// the same page found twice, queued once
+	c.push(conn,
+		Entry{URL: "https://example.com/a", Parent: "https://example.com/", Priority: 2},
+		Entry{URL: "https://example.com/a", Parent: "https://example.com/other", Priority: 1},
+		Entry{URL: "https://example.com/b"},
+	)
+
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+
+	claimed := []string{}
+	for {
+		entry, _, err := c.claim(context.Background(), w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil {
+			break
+		}
+		if !entry.visited {
+			t.Errorf("claimed %s without marking it visited", entry.URL)
+		}
+		claimed = append(claimed, entry.URL)
+	}
+
+	if len(claimed) != 2 || claimed[0] != "https://example.com/a" || claimed[1] != "https://example.com/b" {
+		t.Errorf("claimed %v, want a once then b", claimed)
+	}
+	if visited, _ := mr.Members(c.KeyVisitedHREFs); len(visited) != 2 {
+		t.Errorf("visited = %v, want a and b", visited)
+	}
+}
+
+func TestWorkersStale(t *testing.T) {
+	c, mr := newTestCrawler(t)
+
+	// a worker whose process died a minute ago, and one long forgotten
+	dead, _ := json.Marshal(WorkerHeartbeat{Host: "gone", PID: 7, Seen: time.Now().Add(-time.Minute)})
+	forgotten, _ := json.Marshal(WorkerHeartbeat{Seen: time.Now().Add(-2 * heartbeatTTL)})
+	mr.HSet(c.KeyHeartbeats, "dead:0", string(dead), "old:0", string(forgotten))
+
+	w := c.newWorker(0, newRunState(nil))
+	stop := make(chan struct{})
+	done := c.heartbeat([]*worker{w}, stop)
+	defer func() {
+		close(stop)
+		<-done
+	}()
+	// the first heartbeat is recorded straight away
+	for !mr.Exists(c.KeyHeartbeats) || mr.HGet(c.KeyHeartbeats, w.id) == "" {
+		time.Sleep(time.Millisecond)
+	}
+
+	workers, err := c.Workers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := map[string]bool{}
+	for _, hb := range workers {
+		stale[hb.ID] = hb.Stale
+	}
+	if len(stale) != 2 || !stale["dead:0"] || stale[w.id] {
+		t.Errorf("workers = %+v, want the dead worker stale and the live one not", workers)
+	}
+	if mr.HGet(c.KeyHeartbeats, "old:0") != "" {
+		t.Errorf("the long dead worker's heartbeat wasn't forgotten")
+	}
+}
+
+func TestHeartbeatsCleared(t *testing.T) {
+	c, mr := newTestCrawler(t)
+	c.Seed("https://example.com/")
+	c.BeforeFetch = func(ctx context.Context, url string) error { return ErrSkipPage }
+	c.RunN(2)
+
+	if keys, _ := mr.HKeys(c.KeyHeartbeats); len(keys) != 0 {
+		t.Errorf("heartbeats left behind %v, want them cleared", keys)
+	}
+}
+
+func TestCompleteWaitsForQuietEpoch(t *testing.T) {
+	c, _ := newTestCrawler(t)
+
+	w := c.newWorker(0, newRunState(nil))
+	w.conn = c.RedisPool.Get()
+	defer w.conn.Close()
+
+	claimEmpty := func() int {
+		entry, active, err := c.claim(context.Background(), w)
+		if err != nil || entry != nil {
+			t.Fatalf("claim = %v, %v, want an empty queue", entry, err)
+		}
+		return active
+	}
+
+	if c.complete(w, claimEmpty()) {
+		t.Fatalf("complete at the first look")
+	}
+
+	// a worker whose lease lapsed queues a link, which another claims and
+	// crawls, all between two looks
+	c.push(w.conn, Entry{URL: "https://example.com/late"})
+	w.conn.Do("ZPOPMAX", c.KeyCrawlQ)
+	if c.complete(w, claimEmpty()) {
+		t.Errorf("complete though a page was queued since the last look")
+	}
+
+	if !c.complete(w, claimEmpty()) {
+		t.Errorf("not complete after two quiet looks")
+	}
+}